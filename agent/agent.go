@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/log"
+
+	"github.com/yuyang0/resource-hostdir/agent/quota"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// Source provides the bindings and cleanups of a node, it is implemented by hostdir.Plugin
+type Source interface {
+	GetNodeBindings(ctx context.Context, nodename string) (types.VolumeBindings, error)
+	GetCleanupTasks(ctx context.Context, nodename string) ([]*types.CleanupTask, error)
+	FinishCleanupTasks(ctx context.Context, nodename string, sources []string) error
+}
+
+// Agent runs on a node, it creates the sources of bindings and limits their size
+type Agent struct {
+	nodename string
+	source   Source
+	backend  quota.Backend
+	config   types.AgentConfig
+	// applied maps source to the size limit set by backend
+	applied map[string]int64
+}
+
+// New .
+func New(nodename string, source Source, backend quota.Backend, config types.AgentConfig) *Agent {
+	return &Agent{
+		nodename: nodename,
+		source:   source,
+		backend:  backend,
+		config:   config,
+		applied:  map[string]int64{},
+	}
+}
+
+// Run reconciles bindings every interval until ctx is done
+func (a *Agent) Run(ctx context.Context) error {
+	logger := log.WithFunc("agent.Run").WithField("node", a.nodename)
+	interval := a.config.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := a.Reconcile(ctx); err != nil {
+			logger.Error(ctx, err, "failed to reconcile bindings")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile provisions the current bindings once, limits of removed bindings are released.
+// Errors of one binding don't stop the others, they are combined.
+func (a *Agent) Reconcile(ctx context.Context) error {
+	bindings, err := a.source.GetNodeBindings(ctx, a.nodename)
+	if err != nil {
+		return err
+	}
+	mode, err := a.config.FileMode()
+	if err != nil {
+		return err
+	}
+
+	// bindings of the same source are merged, sizes are summed
+	sources := map[string]*types.VolumeBinding{}
+	for _, vb := range bindings {
+		if source, ok := sources[vb.Source]; ok {
+			source.SizeInBytes += vb.SizeInBytes
+			source.MergeAttrs(vb)
+			continue
+		}
+		sources[vb.Source] = vb.DeepCopy()
+	}
+
+	var errs error
+	for dir, vb := range sources {
+		if err := a.ensureDir(vb, os.FileMode(mode)); err != nil {
+			errs = errors.CombineErrors(errs, err)
+			continue
+		}
+		if applied, ok := a.applied[dir]; ok && applied == vb.SizeInBytes {
+			continue
+		}
+		if err := a.backend.Apply(ctx, dir, vb.SizeInBytes); err != nil {
+			errs = errors.CombineErrors(errs, errors.Wrapf(err, "failed to limit %s to %d", dir, vb.SizeInBytes))
+			continue
+		}
+		a.applied[dir] = vb.SizeInBytes
+	}
+	for dir := range a.applied {
+		if _, ok := sources[dir]; ok {
+			continue
+		}
+		if err := a.backend.Release(ctx, dir); err != nil {
+			errs = errors.CombineErrors(errs, errors.Wrapf(err, "failed to release %s", dir))
+			continue
+		}
+		delete(a.applied, dir)
+	}
+	return errs
+}
+
+// ensureDir creates the source and sets owner and mode, they are set every time to fix manual changes.
+// Owner, group and mode of the binding override the ones in config.
+func (a *Agent) ensureDir(vb *types.VolumeBinding, mode os.FileMode) error {
+	if m, ok := vb.FileMode(); ok {
+		mode = os.FileMode(m)
+	}
+	uid, gid := a.config.UID, a.config.GID
+	if vb.UID != nil {
+		uid = *vb.UID
+	}
+	if vb.GID != nil {
+		gid = *vb.GID
+	}
+	if err := os.MkdirAll(vb.Source, mode); err != nil {
+		return err
+	}
+	// MkdirAll is affected by umask
+	if err := os.Chmod(vb.Source, mode); err != nil {
+		return err
+	}
+	return os.Chown(vb.Source, uid, gid)
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/agent/quota"
+	"github.com/yuyang0/resource-hostdir/hostdir"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	p, err := hostdir.NewPlugin(ctx, coretypes.Config{}, types.Config{
+		Store: types.StoreConfig{Type: types.StoreMemory},
+	})
+	assert.NoError(t, err)
+	defer p.Close()
+	_, err = p.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{"roots": []string{root + ":100GiB"}}, nil)
+	assert.NoError(t, err)
+
+	img0, img1 := filepath.Join(root, "img0"), filepath.Join(root, "img1")
+	workloads := []plugintypes.WorkloadResource{
+		{"volumes": []string{img0 + ":/dir0:1GiB"}},
+		{"volumes": []string{img1 + ":/dir1:2GiB:mode=0700,uid=" + strconv.Itoa(os.Getuid())}},
+	}
+	_, err = p.SetNodeResourceUsage(ctx, "node0", nil, nil, workloads, true, true)
+	assert.NoError(t, err)
+
+	backend := quota.NewFake()
+	a := New("node0", p, backend, types.AgentConfig{UID: os.Getuid(), GID: os.Getgid(), Mode: "0750"})
+	assert.NoError(t, a.Reconcile(ctx))
+	// mode of binding overrides the one in config
+	for dir, mode := range map[string]os.FileMode{img0: 0750, img1: 0700} {
+		info, err := os.Stat(dir)
+		assert.NoError(t, err)
+		assert.True(t, info.IsDir())
+		assert.Equal(t, mode, info.Mode().Perm())
+	}
+	size, ok := backend.Get(img0)
+	assert.True(t, ok)
+	assert.EqualValues(t, 1<<30, size)
+
+	// realloc grows img0
+	delta := plugintypes.WorkloadResource{"volumes": []string{img0 + ":/dir0:1GiB"}}
+	_, err = p.SetNodeResourceUsage(ctx, "node0", nil, nil, []plugintypes.WorkloadResource{delta}, true, true)
+	assert.NoError(t, err)
+	assert.NoError(t, a.Reconcile(ctx))
+	size, _ = backend.Get(img0)
+	assert.EqualValues(t, 2<<30, size)
+
+	// workload of img1 is removed, its limit is released but the content is kept
+	_, err = p.SetNodeResourceUsage(ctx, "node0", nil, nil, workloads[1:], true, false)
+	assert.NoError(t, err)
+	assert.NoError(t, a.Reconcile(ctx))
+	_, ok = backend.Get(img1)
+	assert.False(t, ok)
+	_, err = os.Stat(img1)
+	assert.NoError(t, err)
+
+	// unknown node
+	a = New("node1", p, backend, types.AgentConfig{})
+	assert.ErrorIs(t, a.Reconcile(ctx), coretypes.ErrNodeNotExists)
+}
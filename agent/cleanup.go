@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/log"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// Orphan is the source of a removed binding, it takes disk until cleaned up
+type Orphan struct {
+	*types.CleanupTask
+	DueAt     *time.Time `json:"due_at,omitempty"` // nil if the source is kept
+	Due       bool       `json:"due"`
+	Exists    bool       `json:"exists"`
+	DiskUsage int64      `json:"disk_usage"`
+}
+
+// Orphans returns the sources waiting for cleanup, with their disk usage on this node
+func (a *Agent) Orphans(ctx context.Context, now time.Time) ([]*Orphan, error) {
+	tasks, err := a.source.GetCleanupTasks(ctx, a.nodename)
+	if err != nil {
+		return nil, err
+	}
+	ans := []*Orphan{}
+	for _, task := range tasks {
+		orphan := &Orphan{CleanupTask: task, Due: task.IsDue(now)}
+		if dueAt, ok := task.DueAt(); ok {
+			orphan.DueAt = &dueAt
+		}
+		if orphan.DiskUsage, err = diskUsage(task.Source); err == nil {
+			orphan.Exists = true
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+		ans = append(ans, orphan)
+	}
+	return ans, nil
+}
+
+// Cleanup deletes or archives the sources whose retention is due, they are returned.
+// Sources already gone are treated as cleaned up. With dryRun, nothing is touched.
+func (a *Agent) Cleanup(ctx context.Context, now time.Time, dryRun bool) ([]*types.CleanupTask, error) {
+	logger := log.WithFunc("agent.Cleanup").WithField("node", a.nodename)
+	tasks, err := a.source.GetCleanupTasks(ctx, a.nodename)
+	if err != nil {
+		return nil, err
+	}
+	done := []*types.CleanupTask{}
+	var errs error
+	for _, task := range tasks {
+		if !task.IsDue(now) {
+			continue
+		}
+		if !dryRun {
+			if err := a.cleanup(ctx, task, now); err != nil {
+				errs = errors.CombineErrors(errs, errors.Wrapf(err, "failed to clean up %s", task.Source))
+				continue
+			}
+			logger.Infof(ctx, "%s is cleaned up, retention %s", task.Source, task.Retention)
+		}
+		done = append(done, task)
+	}
+	if dryRun || len(done) == 0 {
+		return done, errs
+	}
+	sources := []string{}
+	for _, task := range done {
+		sources = append(sources, task.Source)
+	}
+	if err := a.source.FinishCleanupTasks(ctx, a.nodename, sources); err != nil {
+		return nil, errors.CombineErrors(errs, err)
+	}
+	return done, errs
+}
+
+func (a *Agent) cleanup(ctx context.Context, task *types.CleanupTask, now time.Time) error {
+	if _, err := os.Lstat(task.Source); os.IsNotExist(err) {
+		return a.backend.Purge(ctx, task.Source)
+	}
+	retention, err := types.ParseRetention(task.Retention)
+	if err != nil {
+		return err
+	}
+	if retention.Mode == types.RetentionArchiveAfter {
+		if err := a.archive(task.Source, now); err != nil {
+			return err
+		}
+	}
+	if err := a.backend.Purge(ctx, task.Source); err != nil {
+		return err
+	}
+	return os.RemoveAll(task.Source)
+}
+
+// archive writes dir into a tar.gz file under ArchiveDir
+func (a *Agent) archive(dir string, now time.Time) (err error) {
+	if a.config.ArchiveDir == "" {
+		return errors.Wrap(types.ErrInvalidConfig, "archive dir of agent must be provided")
+	}
+	if err := os.MkdirAll(a.config.ArchiveDir, 0700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d.tar.gz", strings.ReplaceAll(strings.Trim(filepath.Clean(dir), "/"), "/", "_"), now.Unix())
+	path := filepath.Join(a.config.ArchiveDir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	if err := writeTar(tw, dir); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func writeTar(tw *tar.Writer, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+}
+
+// diskUsage sums the size of regular files under dir
+func diskUsage(dir string) (int64, error) {
+	ans := int64(0)
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		ans += info.Size()
+		return nil
+	})
+	return ans, err
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/agent/quota"
+	"github.com/yuyang0/resource-hostdir/hostdir"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestCleanup(t *testing.T) {
+	ctx := context.Background()
+	root, archiveDir := t.TempDir(), t.TempDir()
+	p, err := hostdir.NewPlugin(ctx, coretypes.Config{}, types.Config{
+		Retention: "keep",
+		Store:     types.StoreConfig{Type: types.StoreMemory},
+	})
+	assert.NoError(t, err)
+	defer p.Close()
+	_, err = p.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{"roots": []string{root + ":100GiB"}}, nil)
+	assert.NoError(t, err)
+
+	img0, img1, img2 := filepath.Join(root, "img0"), filepath.Join(root, "img1"), filepath.Join(root, "img2")
+	workloads := []plugintypes.WorkloadResource{
+		{"volumes": []string{img0 + ":/dir0:1GiB:retention=delete"}},
+		{"volumes": []string{img1 + ":/dir0:1GiB:retention=archive-after-7d"}},
+		{"volumes": []string{img2 + ":/dir0:1GiB"}},
+	}
+	_, err = p.SetNodeResourceUsage(ctx, "node0", nil, nil, workloads, true, true)
+	assert.NoError(t, err)
+
+	backend := quota.NewFake()
+	a := New("node0", p, backend, types.AgentConfig{UID: os.Getuid(), GID: os.Getgid(), ArchiveDir: archiveDir})
+	assert.NoError(t, a.Reconcile(ctx))
+	for _, dir := range []string{img0, img1, img2} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "data"), []byte("hello"), 0600))
+	}
+
+	_, err = p.SetNodeResourceUsage(ctx, "node0", nil, nil, workloads, true, false)
+	assert.NoError(t, err)
+	assert.NoError(t, a.Reconcile(ctx))
+
+	now := time.Now()
+	orphans, err := a.Orphans(ctx, now)
+	assert.NoError(t, err)
+	assert.Len(t, orphans, 3)
+	for _, orphan := range orphans {
+		assert.True(t, orphan.Exists)
+		assert.EqualValues(t, 5, orphan.DiskUsage)
+		assert.Equal(t, orphan.Source == img0, orphan.Due)
+	}
+
+	// dry run touches nothing
+	done, err := a.Cleanup(ctx, now, true)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	_, err = os.Stat(img0)
+	assert.NoError(t, err)
+
+	done, err = a.Cleanup(ctx, now, false)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	_, err = os.Stat(img0)
+	assert.True(t, os.IsNotExist(err))
+
+	// img1 is archived after 7 days, img2 is kept forever
+	done, err = a.Cleanup(ctx, now.AddDate(0, 0, 7), false)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.Equal(t, img1, done[0].Source)
+	_, err = os.Stat(img1)
+	assert.True(t, os.IsNotExist(err))
+	archives, err := filepath.Glob(filepath.Join(archiveDir, "*.tar.gz"))
+	assert.NoError(t, err)
+	assert.Len(t, archives, 1)
+
+	orphans, err = a.Orphans(ctx, now.AddDate(1, 0, 0))
+	assert.NoError(t, err)
+	assert.Len(t, orphans, 1)
+	assert.Equal(t, img2, orphans[0].Source)
+	assert.False(t, orphans[0].Due)
+}
@@ -0,0 +1,46 @@
+package quota
+
+import (
+	"context"
+	"sync"
+)
+
+// Fake records limits in memory, it is used for testing without special filesystems
+type Fake struct {
+	sync.Mutex
+	Limits map[string]int64
+}
+
+// NewFake .
+func NewFake() *Fake {
+	return &Fake{Limits: map[string]int64{}}
+}
+
+// Apply .
+func (q *Fake) Apply(_ context.Context, dir string, size int64) error {
+	q.Lock()
+	defer q.Unlock()
+	q.Limits[dir] = size
+	return nil
+}
+
+// Release .
+func (q *Fake) Release(_ context.Context, dir string) error {
+	q.Lock()
+	defer q.Unlock()
+	delete(q.Limits, dir)
+	return nil
+}
+
+// Purge .
+func (q *Fake) Purge(ctx context.Context, dir string) error {
+	return q.Release(ctx, dir)
+}
+
+// Get returns the limit of dir
+func (q *Fake) Get(dir string) (int64, bool) {
+	q.Lock()
+	defer q.Unlock()
+	size, ok := q.Limits[dir]
+	return size, ok
+}
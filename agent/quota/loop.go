@@ -0,0 +1,143 @@
+package quota
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// LoopQuota backs each directory by an ext4 image file of the limited size, mounted by loop device.
+// Images stay mounted after release, they are removed when the directory is cleaned up.
+type LoopQuota struct {
+	imageDir string
+	projects *Projects
+}
+
+// NewLoop .
+func NewLoop(imageDir string, projects *Projects) *LoopQuota {
+	return &LoopQuota{imageDir: imageDir, projects: projects}
+}
+
+// Apply creates the image on first call and grows it later, images are never shrunk
+func (q *LoopQuota) Apply(ctx context.Context, dir string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	if err := os.MkdirAll(q.imageDir, 0700); err != nil {
+		return err
+	}
+	image, err := q.image(dir)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(image)
+	switch {
+	case os.IsNotExist(err):
+		if err := q.truncate(image, size); err != nil {
+			return err
+		}
+		if err := runner(ctx, "mkfs.ext4", "-q", "-F", image); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case info.Size() < size:
+		if err := q.truncate(image, size); err != nil {
+			return err
+		}
+		if mounted, err := isMounted(dir); err != nil {
+			return err
+		} else if mounted {
+			// online resize needs the loop device to pick up the new size first
+			device, err := output(ctx, "findmnt", "-n", "-o", "SOURCE", dir)
+			if err != nil {
+				return err
+			}
+			if err := runner(ctx, "losetup", "-c", device); err != nil {
+				return err
+			}
+			return runner(ctx, "resize2fs", device)
+		}
+		if err := runner(ctx, "e2fsck", "-f", "-y", image); err != nil {
+			return err
+		}
+		if err := runner(ctx, "resize2fs", image); err != nil {
+			return err
+		}
+	}
+	if mounted, err := isMounted(dir); err != nil || mounted {
+		return err
+	}
+	return mount(ctx, image, dir)
+}
+
+// mount mounts image over dir, the root of image takes the owner and mode of dir,
+// which are set by the agent before Apply, otherwise it stays root:0755 of mkfs
+func mount(ctx context.Context, image, dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if err := runner(ctx, "mount", "-o", "loop", image, dir); err != nil {
+		return err
+	}
+	if err := os.Chmod(dir, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return err
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return os.Chown(dir, int(stat.Uid), int(stat.Gid))
+	}
+	return nil
+}
+
+// Release keeps the image mounted, the limit can't be removed without hiding the content of dir
+func (q *LoopQuota) Release(context.Context, string) error {
+	return nil
+}
+
+// Purge unmounts and removes the image
+func (q *LoopQuota) Purge(ctx context.Context, dir string) error {
+	if mounted, err := isMounted(dir); err != nil {
+		return err
+	} else if mounted {
+		if err := runner(ctx, "umount", dir); err != nil {
+			return err
+		}
+	}
+	image, err := q.image(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(image); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return q.projects.Remove(dir)
+}
+
+// image returns the image file of dir, it is named by the project id of dir
+func (q *LoopQuota) image(dir string) (string, error) {
+	id, err := q.projects.ID(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(q.imageDir, strconv.FormatUint(uint64(id), 10)+".img"), nil
+}
+
+func (q *LoopQuota) truncate(image string, size int64) error {
+	f, err := os.OpenFile(image, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Truncate(size)
+}
+
+func isMounted(dir string) (bool, error) {
+	mnt, err := mountPoint(dir)
+	if err != nil {
+		return false, err
+	}
+	return mnt == filepath.Clean(dir), nil
+}
@@ -0,0 +1,105 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// XFSQuota uses xfs_quota to set project quota, the filesystem must be mounted with prjquota
+type XFSQuota struct {
+	projects *Projects
+}
+
+// NewXFS .
+func NewXFS(projects *Projects) *XFSQuota {
+	return &XFSQuota{projects: projects}
+}
+
+// Apply .
+func (q *XFSQuota) Apply(ctx context.Context, dir string, size int64) error {
+	mnt, err := mountPoint(dir)
+	if err != nil {
+		return err
+	}
+	id, err := q.projects.ID(dir)
+	if err != nil {
+		return err
+	}
+	if err := runner(ctx, "xfs_quota", "-x", "-c", fmt.Sprintf("project -s -p %s %d", dir, id), mnt); err != nil {
+		return err
+	}
+	return runner(ctx, "xfs_quota", "-x", "-c", fmt.Sprintf("limit -p bhard=%d %d", size, id), mnt)
+}
+
+// Release .
+func (q *XFSQuota) Release(ctx context.Context, dir string) error {
+	mnt, err := mountPoint(dir)
+	if err != nil {
+		return err
+	}
+	id, err := q.projects.ID(dir)
+	if err != nil {
+		return err
+	}
+	return runner(ctx, "xfs_quota", "-x", "-c", fmt.Sprintf("limit -p bhard=0 %d", id), mnt)
+}
+
+// Purge frees the project id of dir
+func (q *XFSQuota) Purge(_ context.Context, dir string) error {
+	return q.projects.Remove(dir)
+}
+
+// Ext4Quota uses chattr and setquota to set project quota, the filesystem must be mounted with prjquota
+type Ext4Quota struct {
+	projects *Projects
+}
+
+// NewExt4 .
+func NewExt4(projects *Projects) *Ext4Quota {
+	return &Ext4Quota{projects: projects}
+}
+
+// Apply .
+func (q *Ext4Quota) Apply(ctx context.Context, dir string, size int64) error {
+	mnt, err := mountPoint(dir)
+	if err != nil {
+		return err
+	}
+	id, err := q.id(dir)
+	if err != nil {
+		return err
+	}
+	// +P makes new files inherit the project id of dir
+	if err := runner(ctx, "chattr", "-R", "+P", "-p", id, dir); err != nil {
+		return err
+	}
+	// setquota takes limits in KiB
+	return runner(ctx, "setquota", "-P", id, "0", strconv.FormatInt((size+1023)/1024, 10), "0", "0", mnt)
+}
+
+// Release .
+func (q *Ext4Quota) Release(ctx context.Context, dir string) error {
+	mnt, err := mountPoint(dir)
+	if err != nil {
+		return err
+	}
+	id, err := q.id(dir)
+	if err != nil {
+		return err
+	}
+	return runner(ctx, "setquota", "-P", id, "0", "0", "0", "0", mnt)
+}
+
+// Purge frees the project id of dir
+func (q *Ext4Quota) Purge(_ context.Context, dir string) error {
+	return q.projects.Remove(dir)
+}
+
+func (q *Ext4Quota) id(dir string) (string, error) {
+	id, err := q.projects.ID(dir)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(uint64(id), 10), nil
+}
@@ -0,0 +1,109 @@
+package quota
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// maxProjectID is the max project id, ids are 31 bits as some quota tools take them as signed
+const maxProjectID = 0x7fffffff
+
+// Projects assigns project ids of quota to directories and keeps them in a file,
+// so ids stay the same across restarts and no two directories share one project.
+// The id of a new directory is derived from its path, the next free one is taken if it is used by another directory.
+type Projects struct {
+	sync.Mutex
+	path string
+	ids  map[string]uint32
+}
+
+// LoadProjects reads the assigned ids from path, there are none if it doesn't exist
+func LoadProjects(path string) (*Projects, error) {
+	p := &Projects{path: path, ids: map[string]uint32{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &p.ids); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode project ids in %s", path)
+	}
+	return p, nil
+}
+
+// ID returns the project id of dir, a new one is assigned and saved if dir has none
+func (p *Projects) ID(dir string) (uint32, error) {
+	p.Lock()
+	defer p.Unlock()
+	dir = filepath.Clean(dir)
+	if id, ok := p.ids[dir]; ok {
+		return id, nil
+	}
+	used := map[uint32]bool{}
+	for _, id := range p.ids {
+		used[id] = true
+	}
+	if len(used) >= maxProjectID {
+		return 0, errors.New("no free project id")
+	}
+	id := projectID(dir)
+	for used[id] {
+		id = id%maxProjectID + 1
+	}
+	p.ids[dir] = id
+	if err := p.save(); err != nil {
+		delete(p.ids, dir)
+		return 0, err
+	}
+	return id, nil
+}
+
+// Remove frees the project id of dir
+func (p *Projects) Remove(dir string) error {
+	p.Lock()
+	defer p.Unlock()
+	dir = filepath.Clean(dir)
+	id, ok := p.ids[dir]
+	if !ok {
+		return nil
+	}
+	delete(p.ids, dir)
+	if err := p.save(); err != nil {
+		p.ids[dir] = id
+		return err
+	}
+	return nil
+}
+
+// save writes the ids to a temporary file and renames it, so the file is never half written
+func (p *Projects) save() error {
+	data, err := json.Marshal(p.ids)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0700); err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
+
+// projectID derives the preferred project id from dir, 0 is reserved by quota tools
+func projectID(dir string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(filepath.Clean(dir)))
+	if id := h.Sum32() & maxProjectID; id != 0 {
+		return id
+	}
+	return 1
+}
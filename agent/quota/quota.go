@@ -0,0 +1,98 @@
+package quota
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// Backend limits the size of a directory
+type Backend interface {
+	// Apply limits dir to size bytes, it is called again when size changes.
+	// dir is created before Apply, size 0 means no limit.
+	Apply(ctx context.Context, dir string, size int64) error
+	// Release removes the limit of dir, the content of dir is kept
+	Release(ctx context.Context, dir string) error
+	// Purge removes the data held by backend for dir, it is called when dir is cleaned up
+	Purge(ctx context.Context, dir string) error
+}
+
+// New returns the backend selected by config
+func New(cfg types.AgentConfig) (Backend, error) {
+	switch cfg.Quota {
+	case "", types.QuotaNone:
+		return none{}, nil
+	case types.QuotaXFS, types.QuotaExt4, types.QuotaLoop:
+	default:
+		return nil, errors.Wrapf(types.ErrInvalidConfig, "unknown quota backend: %s", cfg.Quota)
+	}
+	projects, err := LoadProjects(cfg.ProjectFile)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Quota {
+	case types.QuotaXFS:
+		return NewXFS(projects), nil
+	case types.QuotaExt4:
+		return NewExt4(projects), nil
+	default:
+		return NewLoop(cfg.ImageDir, projects), nil
+	}
+}
+
+type none struct{}
+
+func (none) Apply(context.Context, string, int64) error { return nil }
+func (none) Release(context.Context, string) error      { return nil }
+func (none) Purge(context.Context, string) error        { return nil }
+
+// output runs external commands and returns the trimmed output, it is replaced in tests to record the commands
+var output = func(ctx context.Context, name string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s %s: %s", name, strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func runner(ctx context.Context, name string, args ...string) error {
+	_, err := output(ctx, name, args...)
+	return err
+}
+
+// mountsFile lists the mounted filesystems, it is replaced in tests
+var mountsFile = "/proc/self/mounts"
+
+// mountPoint returns the mount point of the filesystem holding dir
+func mountPoint(dir string) (string, error) {
+	f, err := os.Open(mountsFile)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	dir = filepath.Clean(dir)
+	ans := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		mnt := fields[1]
+		if (dir == mnt || strings.HasPrefix(dir, mnt+"/") || mnt == "/") && len(mnt) > len(ans) {
+			ans = mnt
+		}
+	}
+	if ans == "" {
+		return "", errors.Errorf("no mount point for %s", dir)
+	}
+	return ans, scanner.Err()
+}
@@ -0,0 +1,208 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-units"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCommands replaces output to record the commands, mount resets the mode of its mountpoint as mkfs does
+func fakeCommands(t *testing.T) *[]string {
+	commands := &[]string{}
+	origin := output
+	output = func(_ context.Context, name string, args ...string) (string, error) {
+		*commands = append(*commands, strings.Join(append([]string{name}, args...), " "))
+		switch name {
+		case "mount":
+			return "", os.Chmod(args[len(args)-1], 0755)
+		case "findmnt":
+			return "/dev/loop0", nil
+		}
+		return "", nil
+	}
+	t.Cleanup(func() { output = origin })
+	return commands
+}
+
+// fakeMounts replaces the mounted filesystems by mountpoints, the first one is the root
+func fakeMounts(t *testing.T, mountpoints ...string) {
+	lines := []string{}
+	for i, mnt := range mountpoints {
+		lines = append(lines, fmt.Sprintf("/dev/sd%c %s ext4 rw 0 0", 'a'+i, mnt))
+	}
+	path := filepath.Join(t.TempDir(), "mounts")
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0600))
+	origin := mountsFile
+	mountsFile = path
+	t.Cleanup(func() { mountsFile = origin })
+}
+
+func TestBackends(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	mnt := filepath.Join(root, "data")
+	dir := filepath.Join(mnt, "img0")
+	images := filepath.Join(root, "images")
+	assert.NoError(t, os.MkdirAll(dir, 0700))
+	id := projectID(dir)
+	image := filepath.Join(images, fmt.Sprintf("%d.img", id))
+
+	for _, c := range []struct {
+		name    string
+		backend func(*Projects) Backend
+		// mounted tells whether dir is a mountpoint before f
+		mounted  bool
+		f        func(Backend) error
+		commands []string
+	}{
+		{
+			name:    "xfs apply",
+			backend: func(p *Projects) Backend { return NewXFS(p) },
+			f:       func(b Backend) error { return b.Apply(ctx, dir, 10*units.GiB) },
+			commands: []string{
+				fmt.Sprintf("xfs_quota -x -c project -s -p %s %d %s", dir, id, mnt),
+				fmt.Sprintf("xfs_quota -x -c limit -p bhard=10737418240 %d %s", id, mnt),
+			},
+		},
+		{
+			name:     "xfs release",
+			backend:  func(p *Projects) Backend { return NewXFS(p) },
+			f:        func(b Backend) error { return b.Release(ctx, dir) },
+			commands: []string{fmt.Sprintf("xfs_quota -x -c limit -p bhard=0 %d %s", id, mnt)},
+		},
+		{
+			name:    "ext4 apply",
+			backend: func(p *Projects) Backend { return NewExt4(p) },
+			f:       func(b Backend) error { return b.Apply(ctx, dir, 10*units.GiB+1) },
+			commands: []string{
+				fmt.Sprintf("chattr -R +P -p %d %s", id, dir),
+				fmt.Sprintf("setquota -P %d 0 10485761 0 0 %s", id, mnt),
+			},
+		},
+		{
+			name:     "ext4 release",
+			backend:  func(p *Projects) Backend { return NewExt4(p) },
+			f:        func(b Backend) error { return b.Release(ctx, dir) },
+			commands: []string{fmt.Sprintf("setquota -P %d 0 0 0 0 %s", id, mnt)},
+		},
+		{
+			name:    "loop apply",
+			backend: func(p *Projects) Backend { return NewLoop(images, p) },
+			f:       func(b Backend) error { return b.Apply(ctx, dir, units.MiB) },
+			commands: []string{
+				fmt.Sprintf("mkfs.ext4 -q -F %s", image),
+				fmt.Sprintf("mount -o loop %s %s", image, dir),
+			},
+		},
+		{
+			name:    "loop grow unmounted",
+			backend: func(p *Projects) Backend { return NewLoop(images, p) },
+			f:       func(b Backend) error { return b.Apply(ctx, dir, 2*units.MiB) },
+			commands: []string{
+				fmt.Sprintf("e2fsck -f -y %s", image),
+				fmt.Sprintf("resize2fs %s", image),
+				fmt.Sprintf("mount -o loop %s %s", image, dir),
+			},
+		},
+		{
+			name:    "loop grow mounted",
+			backend: func(p *Projects) Backend { return NewLoop(images, p) },
+			mounted: true,
+			f:       func(b Backend) error { return b.Apply(ctx, dir, 3*units.MiB) },
+			commands: []string{
+				fmt.Sprintf("findmnt -n -o SOURCE %s", dir),
+				"losetup -c /dev/loop0",
+				"resize2fs /dev/loop0",
+			},
+		},
+		{
+			name:     "loop release",
+			backend:  func(p *Projects) Backend { return NewLoop(images, p) },
+			mounted:  true,
+			f:        func(b Backend) error { return b.Release(ctx, dir) },
+			commands: []string{},
+		},
+		{
+			name:     "loop purge",
+			backend:  func(p *Projects) Backend { return NewLoop(images, p) },
+			mounted:  true,
+			f:        func(b Backend) error { return b.Purge(ctx, dir) },
+			commands: []string{fmt.Sprintf("umount %s", dir)},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			commands := fakeCommands(t)
+			if c.mounted {
+				fakeMounts(t, "/", mnt, dir)
+			} else {
+				fakeMounts(t, "/", mnt)
+			}
+			projects, err := LoadProjects(filepath.Join(root, "projects.json"))
+			assert.NoError(t, err)
+			assert.NoError(t, c.f(c.backend(projects)))
+			assert.Equal(t, c.commands, *commands)
+		})
+	}
+
+	// the image is removed by purge
+	_, err := os.Stat(image)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLoopMountMode(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	dir := filepath.Join(root, "img0")
+	assert.NoError(t, os.Mkdir(dir, 0700))
+	assert.NoError(t, os.Chmod(dir, 0700|os.ModeSticky))
+	fakeCommands(t)
+	fakeMounts(t, "/")
+
+	projects, err := LoadProjects(filepath.Join(root, "projects.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, NewLoop(filepath.Join(root, "images"), projects).Apply(ctx, dir, units.MiB))
+	// the root of mounted image takes the mode of dir set by agent
+	info, err := os.Stat(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0700|os.ModeSticky|os.ModeDir, info.Mode())
+}
+
+func TestProjects(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "projects.json")
+	projects, err := LoadProjects(path)
+	assert.NoError(t, err)
+
+	dir0, dir1 := "/data/img0", "/data/img1"
+	id0, err := projects.ID(dir0)
+	assert.NoError(t, err)
+	assert.Equal(t, projectID(dir0), id0)
+	id, err := projects.ID(dir0 + "/")
+	assert.NoError(t, err)
+	assert.Equal(t, id0, id)
+
+	// dir1 would collide with the id taken by another directory
+	projects.ids["/data/other"] = projectID(dir1)
+	id1, err := projects.ID(dir1)
+	assert.NoError(t, err)
+	assert.Equal(t, projectID(dir1)%maxProjectID+1, id1)
+
+	// ids are kept across restarts
+	projects, err = LoadProjects(path)
+	assert.NoError(t, err)
+	id, err = projects.ID(dir1)
+	assert.NoError(t, err)
+	assert.Equal(t, id1, id)
+
+	// removed ids are free again
+	assert.NoError(t, projects.Remove(dir0))
+	projects, err = LoadProjects(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, projects.ids, dir0)
+	assert.Len(t, projects.ids, 2)
+}
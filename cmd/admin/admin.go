@@ -0,0 +1,361 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	"github.com/urfave/cli/v2"
+
+	"github.com/yuyang0/resource-hostdir/cmd"
+	"github.com/yuyang0/resource-hostdir/hostdir"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// Admin inspects and edits node state in the store directly, for ops
+func Admin() *cli.Command {
+	return &cli.Command{
+		Name:  "admin",
+		Usage: "inspect and edit hostdir state of nodes in the store",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "nodes",
+				Usage:  "list nodes with their capacity and usage",
+				Flags:  []cli.Flag{outputFlag()},
+				Action: withPlugin(listNodes),
+			},
+			{
+				Name:      "roots",
+				Usage:     "show roots of node with capacity, used and free",
+				ArgsUsage: "NODE",
+				Flags:     []cli.Flag{outputFlag()},
+				Action:    withPlugin(showRoots),
+			},
+			{
+				Name:      "bindings",
+				Usage:     "list bindings of node and the workloads using them",
+				ArgsUsage: "NODE",
+				Flags:     []cli.Flag{outputFlag()},
+				Action:    withPlugin(listBindings),
+			},
+			{
+				Name:      "set-capacity",
+				Usage:     "set capacity of roots of node",
+				ArgsUsage: "NODE",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:     "root",
+						Usage:    "root of node, path:size[:overcommit[:reserved]], can be repeated",
+						Required: true,
+					},
+					&cli.BoolFlag{Name: "incr", Usage: "add size to the current capacity"},
+					&cli.BoolFlag{Name: "decr", Usage: "subtract size from the current capacity"},
+					outputFlag(),
+				},
+				Action: withPlugin(setCapacity),
+			},
+			{
+				Name:  "migrate",
+				Usage: "rewrite node records of old schema versions in the current one",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "dry-run", Usage: "only print the records to migrate"},
+					outputFlag(),
+				},
+				Action: withPlugin(migrate),
+			},
+			{
+				Name:   "tenants",
+				Usage:  "show consumption of tenants across nodes against their quotas",
+				Flags:  []cli.Flag{outputFlag()},
+				Action: withPlugin(listTenants),
+			},
+			{
+				Name:  "journal",
+				Usage: "query the audit journal of mutating calls",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "node", Usage: "only entries of node"},
+					&cli.StringFlag{Name: "since", Usage: "only entries at or after, RFC3339 time or duration ago, e.g. 24h"},
+					&cli.StringFlag{Name: "until", Usage: "only entries before, RFC3339 time or duration ago"},
+					&cli.IntFlag{Name: "limit", Usage: "only the latest entries, 0 means all"},
+					outputFlag(),
+				},
+				Action: withPlugin(queryJournal),
+			},
+			{
+				Name:      "revert",
+				Usage:     "restore node of journal entry to its record before the entry, the node must be unchanged since",
+				ArgsUsage: "ID",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "dry-run", Usage: "only preview the changes"},
+					outputFlag(),
+				},
+				Action: withPlugin(revert),
+			},
+		},
+	}
+}
+
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: "table", Usage: "table or json"}
+}
+
+func withPlugin(f func(c *cli.Context, p *hostdir.Plugin) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		p, err := cmd.NewPlugin(c)
+		if err != nil {
+			return cli.Exit(err, 128)
+		}
+		defer p.Close()
+		if err := f(c, p); err != nil {
+			return cli.Exit(err, 128)
+		}
+		return nil
+	}
+}
+
+func nodename(c *cli.Context) (string, error) {
+	if c.NArg() != 1 {
+		return "", fmt.Errorf("exactly one node must be provided")
+	}
+	return c.Args().First(), nil
+}
+
+func listNodes(c *cli.Context, p *hostdir.Plugin) error {
+	nodes, err := p.ListNodes(c.Context)
+	if err != nil {
+		return err
+	}
+	if c.String("output") == "json" {
+		return json.NewEncoder(os.Stdout).Encode(nodes)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tROOTS\tBINDINGS\tCAPACITY\tUSED\tFREE")
+	for _, node := range nodes {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n", node.Nodename, node.Roots, node.Bindings, size(node.Capacity), size(node.Used), size(node.Free))
+	}
+	return w.Flush()
+}
+
+func showRoots(c *cli.Context, p *hostdir.Plugin) error {
+	node, err := nodename(c)
+	if err != nil {
+		return err
+	}
+	roots, err := p.GetNodeRoots(c.Context, node)
+	if err != nil {
+		return err
+	}
+	return printRoots(c, roots)
+}
+
+func printRoots(c *cli.Context, roots []*types.RootUsage) error {
+	if c.String("output") == "json" {
+		return json.NewEncoder(os.Stdout).Encode(roots)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ROOT\tPOOL\tDOMAIN\tSIZE\tOVERCOMMIT\tRESERVED\tCAPACITY\tUSED\tFREE")
+	for _, root := range roots {
+		pool, overcommit, reserved := "-", "-", "-"
+		if root.Pool != "" {
+			pool = root.Pool
+		}
+		if root.Overcommit > 0 {
+			overcommit = fmt.Sprint(root.Overcommit)
+		}
+		if root.Reserved != "" {
+			reserved = root.Reserved
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", root.Path, pool, root.Domain, size(root.Size), overcommit, reserved, size(root.Capacity), size(root.Used), size(root.Free))
+	}
+	return w.Flush()
+}
+
+func listBindings(c *cli.Context, p *hostdir.Plugin) error {
+	node, err := nodename(c)
+	if err != nil {
+		return err
+	}
+	bindings, err := p.ListNodeBindings(c.Context, node)
+	if err != nil {
+		return err
+	}
+	if c.String("output") == "json" {
+		return json.NewEncoder(os.Stdout).Encode(bindings)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tDESTINATION\tSIZE\tWORKLOADS")
+	for _, binding := range bindings {
+		workloads := "-"
+		if len(binding.Workloads) > 0 {
+			workloads = strings.Join(binding.Workloads, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", binding.Source, binding.Destination, size(binding.SizeInBytes), workloads)
+	}
+	return w.Flush()
+}
+
+func setCapacity(c *cli.Context, p *hostdir.Plugin) error {
+	node, err := nodename(c)
+	if err != nil {
+		return err
+	}
+	incr, decr := c.Bool("incr"), c.Bool("decr")
+	if incr && decr {
+		return fmt.Errorf("--incr and --decr can't be used together")
+	}
+	req := plugintypes.NodeResourceRequest{"roots": c.StringSlice("root")}
+	if _, err := p.SetNodeResourceCapacity(c.Context, node, nil, req, incr || decr, incr); err != nil {
+		return err
+	}
+	roots, err := p.GetNodeRoots(c.Context, node)
+	if err != nil {
+		return err
+	}
+	return printRoots(c, roots)
+}
+
+func migrate(c *cli.Context, p *hostdir.Plugin) error {
+	migrations, err := p.MigrateNodes(c.Context, c.Bool("dry-run"))
+	if err != nil {
+		return err
+	}
+	if c.String("output") == "json" {
+		return json.NewEncoder(os.Stdout).Encode(migrations)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tFROM\tTO")
+	for _, m := range migrations {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", m.Nodename, m.From, m.To)
+	}
+	return w.Flush()
+}
+
+func listTenants(c *cli.Context, p *hostdir.Plugin) error {
+	reports, err := p.GetTenantReports(c.Context)
+	if err != nil {
+		return err
+	}
+	if c.String("output") == "json" {
+		return json.NewEncoder(os.Stdout).Encode(reports)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TENANT\tNODES\tUSED\tQUOTA\tUSAGE")
+	for _, report := range reports {
+		quota, usage := "unlimited", "-"
+		if report.Quota > 0 {
+			quota = size(report.Quota)
+			usage = fmt.Sprintf("%.1f%%", float64(report.Used)*100/float64(report.Quota))
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", report.Tenant, report.Nodes, size(report.Used), quota, usage)
+	}
+	return w.Flush()
+}
+
+func queryJournal(c *cli.Context, p *hostdir.Plugin) error {
+	now := time.Now()
+	since, err := parseTime(c.String("since"), now)
+	if err != nil {
+		return err
+	}
+	until, err := parseTime(c.String("until"), now)
+	if err != nil {
+		return err
+	}
+	entries, err := p.QueryJournal(c.Context, &types.JournalFilter{
+		Node:  c.String("node"),
+		Since: since,
+		Until: until,
+		Limit: c.Int("limit"),
+	})
+	if err != nil {
+		return err
+	}
+	if c.String("output") == "json" {
+		return json.NewEncoder(os.Stdout).Encode(entries)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tCOMMAND\tNODE\tCHANGE")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", entry.ID, entry.Time.Local().Format(time.RFC3339), entry.Command, entry.Node, change(entry))
+	}
+	return w.Flush()
+}
+
+func revert(c *cli.Context, p *hostdir.Plugin) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("exactly one journal entry must be provided")
+	}
+	id, err := strconv.ParseInt(c.Args().First(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid journal entry %s", c.Args().First())
+	}
+	revert, err := p.RevertJournal(c.Context, id, c.Bool("dry-run"))
+	if err != nil {
+		return err
+	}
+	if c.String("output") == "json" {
+		return json.NewEncoder(os.Stdout).Encode(revert)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ITEM\tFROM\tTO")
+	for _, change := range revert.Changes {
+		from, to := "-", "-"
+		if change.From != "" {
+			from = change.From
+		}
+		if change.To != "" {
+			to = change.To
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", change.Item, from, to)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	entry := revert.Entry
+	if !revert.Applied {
+		fmt.Printf("dry run, %s of node %s at %s is not reverted\n", entry.Command, entry.Node, entry.Time.Local().Format(time.RFC3339))
+		return nil
+	}
+	fmt.Printf("reverted %s of node %s at %s\n", entry.Command, entry.Node, entry.Time.Local().Format(time.RFC3339))
+	return nil
+}
+
+// parseTime parses RFC3339 time or duration before now, empty means zero time
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %s, RFC3339 time or duration is expected", s)
+	}
+	return t, nil
+}
+
+func change(entry *types.JournalEntry) string {
+	switch {
+	case isNull(entry.Before):
+		return "created"
+	case isNull(entry.After):
+		return "removed"
+	default:
+		return "updated"
+	}
+}
+
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(data) == "null"
+}
+
+func size(n int64) string {
+	return units.BytesSize(float64(n))
+}
@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/yuyang0/resource-hostdir/agent"
+	"github.com/yuyang0/resource-hostdir/agent/quota"
+	"github.com/yuyang0/resource-hostdir/cmd"
+)
+
+// Agent provisions the sources of bindings on this node
+func Agent() *cli.Command {
+	return &cli.Command{
+		Name:  "agent",
+		Usage: "run on a node, create the sources of bindings and limit their size, see hostdir.agent in config",
+		Flags: []cli.Flag{
+			nodenameFlag(),
+			&cli.BoolFlag{
+				Name:  "once",
+				Usage: "reconcile once and exit",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			a, closer, err := newAgent(c)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			defer closer()
+			if c.Bool("once") {
+				if err := a.Reconcile(c.Context); err != nil {
+					return cli.Exit(err, 128)
+				}
+				return nil
+			}
+
+			ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+			return a.Run(ctx)
+		},
+	}
+}
+
+func nodenameFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:     "nodename",
+		Usage:    "name of this node in eru",
+		Required: true,
+		EnvVars:  []string{"ERU_NODE_NAME"},
+	}
+}
+
+func newAgent(c *cli.Context) (*agent.Agent, func(), error) {
+	p, err := cmd.NewPlugin(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg := p.Config().Agent
+	backend, err := quota.New(cfg)
+	if err != nil {
+		_ = p.Close()
+		return nil, nil, err
+	}
+	return agent.New(c.String("nodename"), p, backend, cfg), func() { _ = p.Close() }, nil
+}
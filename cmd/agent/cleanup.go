@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/urfave/cli/v2"
+)
+
+// Cleanup lists and executes the cleanups of sources of removed bindings on this node
+func Cleanup() *cli.Command {
+	return &cli.Command{
+		Name:  "cleanup",
+		Usage: "list and execute the cleanups of sources of removed bindings, see retention in config",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list orphaned sources with their retention and disk usage",
+				Flags: []cli.Flag{
+					nodenameFlag(),
+					&cli.BoolFlag{Name: "due", Usage: "only list the due ones"},
+					&cli.StringFlag{Name: "output", Value: "table", Usage: "table or json"},
+				},
+				Action: func(c *cli.Context) error {
+					a, closer, err := newAgent(c)
+					if err != nil {
+						return cli.Exit(err, 128)
+					}
+					defer closer()
+
+					orphans, err := a.Orphans(c.Context, time.Now())
+					if err != nil {
+						return cli.Exit(err, 128)
+					}
+					if c.Bool("due") {
+						due := orphans[:0]
+						for _, orphan := range orphans {
+							if orphan.Due {
+								due = append(due, orphan)
+							}
+						}
+						orphans = due
+					}
+					if c.String("output") == "json" {
+						return json.NewEncoder(os.Stdout).Encode(orphans)
+					}
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(w, "SOURCE\tRETENTION\tREMOVED AT\tDUE AT\tDISK USAGE")
+					for _, orphan := range orphans {
+						dueAt, usage := "never", "missing"
+						if orphan.DueAt != nil {
+							dueAt = orphan.DueAt.Format(time.RFC3339)
+						}
+						if orphan.Exists {
+							usage = units.BytesSize(float64(orphan.DiskUsage))
+						}
+						fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", orphan.Source, orphan.Retention, orphan.RemovedAt.Format(time.RFC3339), dueAt, usage)
+					}
+					return w.Flush()
+				},
+			},
+			{
+				Name:  "run",
+				Usage: "delete or archive the due sources",
+				Flags: []cli.Flag{
+					nodenameFlag(),
+					&cli.BoolFlag{Name: "dry-run", Usage: "only print the due sources"},
+				},
+				Action: func(c *cli.Context) error {
+					a, closer, err := newAgent(c)
+					if err != nil {
+						return cli.Exit(err, 128)
+					}
+					defer closer()
+
+					done, err := a.Cleanup(c.Context, time.Now(), c.Bool("dry-run"))
+					for _, task := range done {
+						fmt.Printf("%s\t%s\n", task.Source, task.Retention)
+					}
+					if err != nil {
+						return cli.Exit(err, 128)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
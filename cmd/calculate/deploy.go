@@ -1,31 +1,30 @@
 package calculate
 
 import (
+	"context"
+
 	"github.com/projecteru2/core/resource/plugins/binary"
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	"github.com/projecteru2/core/types"
 	"github.com/urfave/cli/v2"
 	"github.com/yuyang0/resource-hostdir/cmd"
 	"github.com/yuyang0/resource-hostdir/hostdir"
 )
 
-func CalculateDeploy() *cli.Command { //nolint
-	return &cli.Command{
-		Name:   binary.CalculateDeployCommand,
-		Usage:  "calculate deploy plan",
-		Action: calculateDeploy,
-	}
+// CalculateDeployInput is the input of calculate deploy plan
+type CalculateDeployInput struct {
+	Nodename                string                              `json:"nodename" description:"name of node"`
+	DeployCount             int                                 `json:"deploy_count" description:"number of workloads to deploy"`
+	WorkloadResourceRequest plugintypes.WorkloadResourceRequest `json:"workload_resource_request" description:"resource request of each workload, e.g. {\"volumes\": [\"/eru/img0:/data:10GiB\"]}"`
 }
 
-func calculateDeploy(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
-		deployCount := in.Int("deploy_count")
+func CalculateDeploy() *cli.Command { //nolint
+	return cmd.NewCommand(binary.CalculateDeployCommand, "calculate deploy plan", calculateDeploy)
+}
 
-		workloadResourceRequest := in.RawParams("workload_resource_request")
-		return s.CalculateDeploy(c.Context, nodename, deployCount, workloadResourceRequest)
-	})
+func calculateDeploy(ctx context.Context, s *hostdir.Plugin, in *CalculateDeployInput) (*plugintypes.CalculateDeployResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
+	}
+	return s.CalculateDeploy(ctx, in.Nodename, in.DeployCount, in.WorkloadResourceRequest)
 }
@@ -1,32 +1,30 @@
 package calculate
 
 import (
+	"context"
+
 	"github.com/projecteru2/core/resource/plugins/binary"
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	"github.com/projecteru2/core/types"
 	"github.com/urfave/cli/v2"
 	"github.com/yuyang0/resource-hostdir/cmd"
 	"github.com/yuyang0/resource-hostdir/hostdir"
 )
 
-func CalculateRealloc() *cli.Command { //nolint
-	return &cli.Command{
-		Name:   binary.CalculateReallocCommand,
-		Usage:  "calculate realloc plan",
-		Action: calculateRealloc,
-	}
+// CalculateReallocInput is the input of calculate realloc plan
+type CalculateReallocInput struct {
+	Nodename                string                              `json:"nodename" description:"name of node"`
+	WorkloadResource        plugintypes.WorkloadResource        `json:"workload_resource" description:"current resource of the workload"`
+	WorkloadResourceRequest plugintypes.WorkloadResourceRequest `json:"workload_resource_request" description:"change of resource request, sizes of volumes are added to the current ones"`
 }
 
-func calculateRealloc(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
-
-		workloadResource := in.RawParams("workload_resource")
-		workloadResourceRequest := in.RawParams("workload_resource_request")
+func CalculateRealloc() *cli.Command { //nolint
+	return cmd.NewCommand(binary.CalculateReallocCommand, "calculate realloc plan", calculateRealloc)
+}
 
-		return s.CalculateRealloc(c.Context, nodename, workloadResource, workloadResourceRequest)
-	})
+func calculateRealloc(ctx context.Context, s *hostdir.Plugin, in *CalculateReallocInput) (*plugintypes.CalculateReallocResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
+	}
+	return s.CalculateRealloc(ctx, in.Nodename, in.WorkloadResource, in.WorkloadResourceRequest)
 }
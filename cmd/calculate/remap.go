@@ -1,36 +1,30 @@
 package calculate
 
 import (
-	"github.com/mitchellh/mapstructure"
+	"context"
+
 	"github.com/projecteru2/core/resource/plugins/binary"
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	"github.com/projecteru2/core/types"
 	"github.com/urfave/cli/v2"
 	"github.com/yuyang0/resource-hostdir/cmd"
 	"github.com/yuyang0/resource-hostdir/hostdir"
 )
 
-func CalculateRemap() *cli.Command { //nolint
-	return &cli.Command{
-		Name:   binary.CalculateRemapCommand,
-		Usage:  "remap resource",
-		Action: calculateRemap,
-	}
+// CalculateRemapInput is the input of remap resource
+type CalculateRemapInput struct {
+	Nodename          string                                  `json:"nodename" description:"name of node"`
+	WorkloadsResource map[string]plugintypes.WorkloadResource `json:"workloads_resource,omitempty" description:"resource of workloads on node by their IDs"`
 }
 
-func calculateRemap(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
+func CalculateRemap() *cli.Command { //nolint
+	return cmd.NewCommand(binary.CalculateRemapCommand, "remap resource", calculateRemap)
+}
 
-		workloadsResource := map[string]resourcetypes.RawParams{}
-		for ID, data := range in.RawParams("workloads_resource") {
-			workloadsResource[ID] = resourcetypes.RawParams{}
-			_ = mapstructure.Decode(data, workloadsResource[ID])
-		}
-		// NO NEED REMAP hostdir
-		return s.CalculateRemap(c.Context, nodename, workloadsResource)
-	})
+func calculateRemap(ctx context.Context, s *hostdir.Plugin, in *CalculateRemapInput) (*plugintypes.CalculateRemapResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
+	}
+	// NO NEED REMAP hostdir
+	return s.CalculateRemap(ctx, in.Nodename, in.WorkloadsResource)
 }
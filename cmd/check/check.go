@@ -0,0 +1,138 @@
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/urfave/cli/v2"
+
+	"github.com/yuyang0/resource-hostdir/cmd"
+	"github.com/yuyang0/resource-hostdir/hostdir"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+	"github.com/yuyang0/resource-hostdir/version"
+)
+
+// Check reports whether the plugin is ready to be registered in eru-core
+func Check() *cli.Command {
+	return &cli.Command{
+		Name:  "check",
+		Usage: "check config, settings, store and stored records of plugin, exit with the code of the first failure",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{Name: "timeout", Value: 10 * time.Second, Usage: "timeout of store checks"},
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: "table", Usage: "table or json"},
+		},
+		Action: func(c *cli.Context) error {
+			ctx, cancel := context.WithTimeout(c.Context, c.Duration("timeout"))
+			defer cancel()
+			report, exitCode := check(ctx)
+			if err := printReport(c, report); err != nil {
+				return cli.Exit(err, 128)
+			}
+			if exitCode != 0 {
+				return cli.Exit("", exitCode)
+			}
+			return nil
+		},
+	}
+}
+
+// checker runs the checks in order, a check is skipped once one fails
+type checker struct {
+	report   *types.CheckReport
+	exitCode int
+}
+
+// run fills the result of check by f, the error of f fails it with the code of cmd.Error
+func (c *checker) run(name string, f func(result *types.CheckResult) error) {
+	result := &types.CheckResult{Name: name, Status: types.CheckOK}
+	c.report.Checks = append(c.report.Checks, result)
+	if !c.report.OK {
+		result.Status = types.CheckSkipped
+		return
+	}
+	if err := f(result); err != nil {
+		e := cmd.NewError(err)
+		result.Status, result.Code, result.Message = types.CheckFailed, e.Code, e.Message
+		c.report.OK, c.exitCode = false, e.ExitCode()
+	}
+}
+
+// check runs all checks, the exit code is of the first failure, 0 if none
+func check(ctx context.Context) (*types.CheckReport, int) {
+	c := &checker{report: &types.CheckReport{Name: hostdir.PluginName, Version: version.VERSION, Revision: version.REVISION, OK: true}}
+	var (
+		cfg        coretypes.Config
+		hostdirCfg types.Config
+		p          *hostdir.Plugin
+	)
+	c.run(types.CheckConfig, func(result *types.CheckResult) (err error) {
+		result.Details = map[string]any{"path": cmd.ConfigPath}
+		if cfg, hostdirCfg, err = cmd.LoadConfig(); err != nil {
+			// errors of the config file are of configor and eru-core, they aren't typed
+			return errors.Mark(err, types.ErrInvalidConfig)
+		}
+		return nil
+	})
+	c.run(types.CheckSettings, func(result *types.CheckResult) error {
+		result.Details = map[string]any{"roots": len(hostdirCfg.Roots), "journal": hostdirCfg.Journal.Type}
+		return hostdirCfg.Validate()
+	})
+	c.run(types.CheckStore, func(result *types.CheckResult) (err error) {
+		result.Details = map[string]any{"type": hostdirCfg.Store.Type}
+		switch hostdirCfg.Store.Type {
+		case "", types.StoreETCD:
+			result.Details["type"] = types.StoreETCD
+			result.Details["machines"] = cfg.Etcd.Machines
+			result.Details["prefix"] = cfg.Etcd.Prefix
+		case types.StoreBolt:
+			result.Details["data_dir"] = hostdirCfg.Store.DataDir
+		}
+		if p, err = hostdir.NewPlugin(ctx, cfg, hostdirCfg); err != nil {
+			return err
+		}
+		return p.CheckStore(ctx)
+	})
+	if p != nil {
+		defer p.Close()
+	}
+	c.run(types.CheckRecords, func(result *types.CheckResult) error {
+		records, err := p.CheckRecords(ctx)
+		if err != nil {
+			return err
+		}
+		result.Details = map[string]any{"nodes": records.Nodes, "tenants": records.Tenants}
+		if len(records.Outdated) > 0 {
+			result.Details["outdated"] = records.Outdated
+			result.Message = fmt.Sprintf("%d nodes of old schema versions, migrate them by `admin migrate`", len(records.Outdated))
+		}
+		if len(records.Invalid) > 0 {
+			result.Details["invalid"] = records.Invalid
+			return errors.Wrapf(types.ErrInvalidSchema, "%d records can't be decoded", len(records.Invalid))
+		}
+		return nil
+	})
+	return c.report, c.exitCode
+}
+
+func printReport(c *cli.Context, report *types.CheckReport) error {
+	if c.String("output") == "json" {
+		return json.NewEncoder(os.Stdout).Encode(report)
+	}
+	fmt.Printf("%s %s (%s)\n", report.Name, report.Version, report.Revision)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tSTATUS\tMESSAGE")
+	for _, result := range report.Checks {
+		message := "-"
+		if result.Message != "" {
+			message = result.Message
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", result.Name, result.Status, message)
+	}
+	return w.Flush()
+}
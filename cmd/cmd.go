@@ -1,56 +1,197 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"testing"
+	"reflect"
+	"sort"
 
+	"github.com/cockroachdb/errors"
+	"github.com/jinzhu/configor"
 	resourcetypes "github.com/projecteru2/core/resource/types"
+	coretypes "github.com/projecteru2/core/types"
 	"github.com/projecteru2/core/utils"
 	"github.com/urfave/cli/v2"
 	"github.com/yuyang0/resource-hostdir/hostdir"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
 )
 
 var (
 	ConfigPath      string
 	EmbeddedStorage bool
+	DataDir         string
+	Socket          string
+
+	commands = map[string]*Command{}
 )
 
-func Serve(c *cli.Context, f func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error)) error {
-	cfg, err := utils.LoadConfig(ConfigPath)
+// Handler handles one command of eru binary plugin protocol
+type Handler func(ctx context.Context, s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error)
+
+// Command is a command of eru binary plugin protocol with the schemas of its input and output.
+// Handler decodes the input into the typed one, validate it by Input first.
+type Command struct {
+	Name    string
+	Usage   string
+	Input   *Schema
+	Output  *Schema
+	Handler Handler
+}
+
+// Validate checks the input against the input schema, so unknown keys and values of wrong types are rejected
+func (c *Command) Validate(in resourcetypes.RawParams) error {
+	if in == nil {
+		in = resourcetypes.RawParams{}
+	}
+	if err := c.Input.Validate(in); err != nil {
+		return &Error{Code: CodeInvalidInput, Message: fmt.Sprintf("invalid input of %s: %s", c.Name, err)}
+	}
+	return nil
+}
+
+// NewCommand returns a cli command serving the handler, In and Out are the typed input and output of it,
+// their schemas are shown by `schema`. The command is also registered so that it can be called in server mode.
+func NewCommand[In any, Out any](name, usage string, handler func(ctx context.Context, s *hostdir.Plugin, in *In) (Out, error)) *cli.Command {
+	command := &Command{
+		Name:   name,
+		Usage:  usage,
+		Input:  SchemaOf(reflect.TypeOf((*In)(nil)).Elem()),
+		Output: SchemaOf(reflect.TypeOf((*Out)(nil)).Elem()),
+		Handler: func(ctx context.Context, s *hostdir.Plugin, params resourcetypes.RawParams) (interface{}, error) {
+			in := new(In)
+			data, err := json.Marshal(params)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(data, in); err != nil {
+				return nil, &Error{Code: CodeInvalidInput, Message: fmt.Sprintf("invalid input of %s: %s", name, err)}
+			}
+			return handler(ctx, s, in)
+		},
+	}
+	commands[name] = command
+	return &cli.Command{
+		Name:  name,
+		Usage: usage,
+		Action: func(c *cli.Context) error {
+			return Serve(c, command)
+		},
+	}
+}
+
+// GetCommand returns the command registered by NewCommand
+func GetCommand(name string) (*Command, bool) {
+	c, ok := commands[name]
+	return c, ok
+}
+
+// Commands returns all commands registered by NewCommand, sorted by name
+func Commands() []*Command {
+	ans := []*Command{}
+	for _, c := range commands {
+		ans = append(ans, c)
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Name < ans[j].Name })
+	return ans
+}
+
+// NewPlugin loads config and creates the plugin.
+// With EmbeddedStorage, node records are kept in a bolt file under DataDir instead of etcd.
+func NewPlugin(c *cli.Context) (*hostdir.Plugin, error) {
+	cfg, hostdirCfg, err := LoadConfig()
 	if err != nil {
-		return cli.Exit(err, 128)
+		return nil, err
 	}
+	return hostdir.NewPlugin(c.Context, cfg, hostdirCfg)
+}
 
-	var t *testing.T
-	if EmbeddedStorage {
-		t = &testing.T{}
+// LoadConfig reads the config of eru-core and hostdir from ConfigPath, the storage flags override the hostdir one.
+// The hostdir config isn't validated here, it is by hostdir.NewPlugin.
+func LoadConfig() (coretypes.Config, types.Config, error) {
+	hostdirCfg, err := LoadHostdirConfig()
+	if err != nil {
+		return coretypes.Config{}, types.Config{}, err
 	}
+	cfg, err := loadCoreConfig(hostdirCfg.Store.Type)
+	return cfg, hostdirCfg, err
+}
 
-	s, err := hostdir.NewPlugin(c.Context, cfg, t)
+// LoadHostdirConfig reads the hostdir config from ConfigPath, the storage flags override it
+func LoadHostdirConfig() (types.Config, error) {
+	hostdirCfg, err := types.ReadConfig(ConfigPath)
 	if err != nil {
-		return cli.Exit(err, 128)
+		return types.Config{}, err
 	}
+	if EmbeddedStorage {
+		hostdirCfg.Store.Type = types.StoreBolt
+	}
+	if DataDir != "" {
+		hostdirCfg.Store.DataDir = DataDir
+	}
+	return hostdirCfg, nil
+}
 
+func loadCoreConfig(storeType string) (coretypes.Config, error) {
+	if storeType == "" || storeType == types.StoreETCD {
+		return utils.LoadConfig(ConfigPath)
+	}
+	// etcd machines are required by eru-core config, but not used by other stores
+	cfg := coretypes.Config{Etcd: coretypes.EtcdConfig{Machines: []string{""}}}
+	return cfg, configor.Load(&cfg, ConfigPath)
+}
+
+// Serve reads input from stdin and writes output to stdout, the input is validated before dispatched.
+// If Socket is set, the call is forwarded to the server listening on it.
+// On failure the Error of it is written to stderr in JSON, and the exit code tells its category, see Error.ExitCode.
+func Serve(c *cli.Context, command *Command) error {
 	in := resourcetypes.RawParams{}
 	if err := json.NewDecoder(os.Stdin).Decode(&in); err != nil {
-		fmt.Fprintf(os.Stderr, "HOSTDIR: failed decode input json: %s\n", err)
-		fmt.Fprintf(os.Stderr, "HOSTDIR: input: %v\n", in)
-		return cli.Exit(err, 128)
-	}
-
-	if r, err := f(s, in); err != nil {
-		fmt.Fprintf(os.Stderr, "HOSTDIR: failed call function: %s\n", err)
-		fmt.Fprintf(os.Stderr, "HOSTDIR: input: %v\n", in)
-		return cli.Exit(err, 128)
-	} else if o, err := json.Marshal(r); err != nil {
-		fmt.Fprintf(os.Stderr, "HOSTDIR: failed encode return object: %s\n", err)
-		fmt.Fprintf(os.Stderr, "HOSTDIR: input: %v\n", in)
-		fmt.Fprintf(os.Stderr, "HOSTDIR: output: %v\n", o)
-		return cli.Exit(err, 128)
-	} else { //nolint
+		return fail(&Error{Code: CodeInvalidInput, Message: fmt.Sprintf("failed to decode input json: %s", err)}, nil)
+	}
+	if err := command.Validate(in); err != nil {
+		return fail(err, in)
+	}
+
+	if Socket != "" {
+		o, err := Call(c.Context, Socket, c.Command.Name, in)
+		if err != nil {
+			return fail(err, in)
+		}
 		fmt.Print(string(o))
+		return nil
+	}
+
+	s, err := NewPlugin(c)
+	if err != nil {
+		return fail(err, in)
+	}
+	defer s.Close()
+
+	r, err := command.Handler(c.Context, s, in)
+	if err != nil {
+		return fail(err, in)
 	}
+	o, err := json.Marshal(r)
+	if err != nil {
+		return fail(errors.Wrap(err, "failed to encode output"), in)
+	}
+	fmt.Print(string(o))
 	return nil
 }
+
+// fail writes the Error of err with input to stderr, the returned error only carries the exit code
+func fail(err error, in resourcetypes.RawParams) error {
+	e := NewError(err)
+	if in != nil {
+		if e.Details == nil {
+			e.Details = map[string]any{}
+		}
+		e.Details["input"] = in
+	}
+	if err := json.NewEncoder(os.Stderr).Encode(e); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", e.Code, e.Message)
+	}
+	return cli.Exit("", e.ExitCode())
+}
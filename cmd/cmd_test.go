@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestLoadHostdirConfig(t *testing.T) {
+	ConfigPath = filepath.Join(t.TempDir(), "hostdir.yaml")
+	defer func() { ConfigPath, EmbeddedStorage, DataDir = "", false, "" }()
+	assert.NoError(t, os.WriteFile(ConfigPath, []byte("hostdir:\n  overcommit: 1.5\n  store:\n    type: etcd\n"), 0600))
+
+	hostdirCfg, err := LoadHostdirConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, hostdirCfg.Overcommit)
+	assert.Equal(t, types.StoreETCD, hostdirCfg.Store.Type)
+
+	// the storage flags override the config
+	EmbeddedStorage, DataDir = true, "/tmp/hostdir"
+	hostdirCfg, err = LoadHostdirConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, types.StoreBolt, hostdirCfg.Store.Type)
+	assert.Equal(t, "/tmp/hostdir", hostdirCfg.Store.DataDir)
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	coretypes "github.com/projecteru2/core/types"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// Codes of Error, each belongs to a category which decides the exit code of Serve
+const (
+	CodeInvalidInput     = "invalid_input"
+	CodeUnknownCommand   = "unknown_command"
+	CodeInvalidCapacity  = "invalid_capacity"
+	CodeInvalidVolume    = "invalid_volume"
+	CodeInvalidStorage   = "invalid_storage"
+	CodeInvalidVolumes   = "invalid_volumes"
+	CodeInvalidParams    = "invalid_params"
+	CodeInvalidConfig    = "invalid_config"
+	CodeInvalidRoot      = "invalid_root"
+	CodeRootNotFound     = "root_not_found"
+	CodeInvalidRetention = "invalid_retention"
+	CodeInvalidSnapshot  = "invalid_snapshot"
+	CodeInvalidSchema    = "invalid_schema"
+	CodeEmptyNodeName    = "empty_node_name"
+
+	CodeInsufficientCapacity = "insufficient_capacity"
+	CodeQuotaExceeded        = "quota_exceeded"
+
+	CodeNodeNotFound    = "node_not_found"
+	CodeJournalNotFound = "journal_not_found"
+
+	CodeNodeExists = "node_exists"
+	CodeConflict   = "conflict"
+
+	CodeStoreUnavailable  = "store_unavailable"
+	CodeServerUnavailable = "server_unavailable"
+
+	CodeInternal = "internal"
+)
+
+// Categories of Error.Code, the exit code of Serve and the grpc code of rpc are derived from them
+const (
+	CategoryInvalid     = "invalid"     // the input, the request or the config is invalid
+	CategoryExhausted   = "exhausted"   // capacity or quota is not enough
+	CategoryNotFound    = "not_found"   // the node or the record doesn't exist
+	CategoryConflict    = "conflict"    // the node exists or is changed by others
+	CategoryUnavailable = "unavailable" // the store or the server can't be reached, try again later
+	CategoryInternal    = "internal"
+)
+
+// Exit codes of Serve by category of Error.Code
+const (
+	ExitInvalid     = 10
+	ExitExhausted   = 11
+	ExitNotFound    = 12
+	ExitConflict    = 13
+	ExitUnavailable = 14
+	ExitInternal    = 128
+)
+
+// categories maps codes to their categories, codes not in it are internal
+var categories = map[string]string{
+	CodeInvalidInput:         CategoryInvalid,
+	CodeUnknownCommand:       CategoryInvalid,
+	CodeInvalidCapacity:      CategoryInvalid,
+	CodeInvalidVolume:        CategoryInvalid,
+	CodeInvalidStorage:       CategoryInvalid,
+	CodeInvalidVolumes:       CategoryInvalid,
+	CodeInvalidParams:        CategoryInvalid,
+	CodeInvalidConfig:        CategoryInvalid,
+	CodeInvalidRoot:          CategoryInvalid,
+	CodeRootNotFound:         CategoryInvalid,
+	CodeInvalidRetention:     CategoryInvalid,
+	CodeInvalidSnapshot:      CategoryInvalid,
+	CodeInvalidSchema:        CategoryInvalid,
+	CodeEmptyNodeName:        CategoryInvalid,
+	CodeInsufficientCapacity: CategoryExhausted,
+	CodeQuotaExceeded:        CategoryExhausted,
+	CodeNodeNotFound:         CategoryNotFound,
+	CodeJournalNotFound:      CategoryNotFound,
+	CodeNodeExists:           CategoryConflict,
+	CodeConflict:             CategoryConflict,
+	CodeStoreUnavailable:     CategoryUnavailable,
+	CodeServerUnavailable:    CategoryUnavailable,
+}
+
+var exitCodes = map[string]int{
+	CategoryInvalid:     ExitInvalid,
+	CategoryExhausted:   ExitExhausted,
+	CategoryNotFound:    ExitNotFound,
+	CategoryConflict:    ExitConflict,
+	CategoryUnavailable: ExitUnavailable,
+	CategoryInternal:    ExitInternal,
+}
+
+// codes maps errors of plugin to their codes, the first matched one wins
+var codes = []struct {
+	err  error
+	code string
+}{
+	{types.ErrInvalidCapacity, CodeInvalidCapacity},
+	{types.ErrInvalidVolume, CodeInvalidVolume},
+	{types.ErrInvalidStorage, CodeInvalidStorage},
+	{types.ErrInvalidVolumes, CodeInvalidVolumes},
+	{types.ErrInvalidParams, CodeInvalidParams},
+	{types.ErrInvalidConfig, CodeInvalidConfig},
+	{coretypes.ErrConfigInvaild, CodeInvalidConfig},
+	{types.ErrInvalidRoot, CodeInvalidRoot},
+	{types.ErrRootNotFound, CodeRootNotFound},
+	{types.ErrInvalidRetention, CodeInvalidRetention},
+	{types.ErrInvalidSnapshot, CodeInvalidSnapshot},
+	{types.ErrInvalidSchema, CodeInvalidSchema},
+	{coretypes.ErrEmptyNodeName, CodeEmptyNodeName},
+	{types.ErrInsufficientCapacity, CodeInsufficientCapacity},
+	{types.ErrQuotaExceeded, CodeQuotaExceeded},
+	{coretypes.ErrNodeNotExists, CodeNodeNotFound},
+	{types.ErrJournalNotFound, CodeJournalNotFound},
+	{coretypes.ErrNodeExists, CodeNodeExists},
+	{types.ErrConflict, CodeConflict},
+	{store.ErrConflict, CodeConflict},
+	{store.ErrUnavailable, CodeStoreUnavailable},
+	{context.DeadlineExceeded, CodeStoreUnavailable},
+}
+
+// Error is the structured error of commands, Serve writes it to stderr in JSON.
+// Details are the fields of typed errors, e.g. types.InsufficientCapacityError, and the input of command.
+type Error struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Error .
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Category returns the category of code
+func (e *Error) Category() string {
+	if category, ok := categories[e.Code]; ok {
+		return category
+	}
+	return CategoryInternal
+}
+
+// ExitCode returns the exit code of the category of code
+func (e *Error) ExitCode() int {
+	return exitCodes[e.Category()]
+}
+
+// NewError returns the structured error of err, errors of unknown types are internal
+func NewError(err error) *Error {
+	if e := (*Error)(nil); errors.As(err, &e) {
+		ans := &Error{Code: e.Code, Message: e.Message, Details: map[string]any{}}
+		for k, v := range e.Details {
+			ans.Details[k] = v
+		}
+		return ans
+	}
+	ans := &Error{Code: CodeInternal, Message: err.Error(), Details: map[string]any{}}
+	for _, c := range codes {
+		if errors.Is(err, c.err) {
+			ans.Code = c.code
+			break
+		}
+	}
+	if e := (*types.InsufficientCapacityError)(nil); errors.As(err, &e) {
+		ans.Details["node"] = e.Node
+		ans.Details["root"] = e.Root
+		ans.Details["binding"] = e.Binding
+		ans.Details["need"] = e.Need
+		ans.Details["available"] = e.Available
+	}
+	if e := (*types.PolicyError)(nil); errors.As(err, &e) {
+		ans.Details["rule"] = e.Rule
+		ans.Details["from"] = e.From
+		if e.Binding != "" {
+			ans.Details["binding"] = e.Binding
+		}
+		ans.Details["limit"] = e.Limit
+		ans.Details["actual"] = e.Actual
+	}
+	return ans
+}
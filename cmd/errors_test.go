@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestNewError(t *testing.T) {
+	for _, c := range []struct {
+		err  error
+		code string
+		exit int
+	}{
+		{errors.Wrap(types.ErrInvalidVolume, "/eru/img0"), CodeInvalidVolume, ExitInvalid},
+		{types.ErrInvalidConfig, CodeInvalidConfig, ExitInvalid},
+		{coretypes.ErrNodeNotExists, CodeNodeNotFound, ExitNotFound},
+		{coretypes.ErrNodeExists, CodeNodeExists, ExitConflict},
+		{errors.Wrap(types.ErrConflict, "node node0"), CodeConflict, ExitConflict},
+		{errors.Mark(errors.New("connection refused"), store.ErrUnavailable), CodeStoreUnavailable, ExitUnavailable},
+		{errors.Wrap(context.DeadlineExceeded, "get node0"), CodeStoreUnavailable, ExitUnavailable},
+		{errors.New("boom"), CodeInternal, ExitInternal},
+		{&Error{Code: CodeUnknownCommand, Message: "unknown command: foo"}, CodeUnknownCommand, ExitInvalid},
+	} {
+		e := NewError(c.err)
+		assert.Equal(t, c.code, e.Code, c.err.Error())
+		assert.Equal(t, c.err.Error(), e.Message)
+		assert.Equal(t, c.exit, e.ExitCode())
+	}
+
+	// every error of plugin is of a category other than internal
+	for _, c := range codes {
+		assert.NotEqual(t, CategoryInternal, NewError(c.err).Category(), c.code)
+	}
+
+	e := NewError(errors.Wrap(&types.InsufficientCapacityError{Node: "node0", Root: "/eru", Binding: "/eru/img0:/data:1GiB", Need: 10, Available: 1}, "deploy"))
+	assert.Equal(t, CodeInsufficientCapacity, e.Code)
+	assert.Equal(t, ExitExhausted, e.ExitCode())
+	assert.Equal(t, map[string]any{"node": "node0", "root": "/eru", "binding": "/eru/img0:/data:1GiB", "need": int64(10), "available": int64(1)}, e.Details)
+
+	e = NewError(&types.PolicyError{Rule: types.RuleMaxTotal, From: "app mysql", Limit: "1TiB", Actual: "2TiB"})
+	assert.Equal(t, CodeQuotaExceeded, e.Code)
+	assert.Equal(t, ExitExhausted, e.ExitCode())
+	assert.Equal(t, map[string]any{"rule": types.RuleMaxTotal, "from": "app mysql", "limit": "1TiB", "actual": "2TiB"}, e.Details)
+}
@@ -1,22 +1,20 @@
 package hostdir
 
 import (
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	"context"
+
 	"github.com/urfave/cli/v2"
 	"github.com/yuyang0/resource-hostdir/cmd"
 	"github.com/yuyang0/resource-hostdir/hostdir"
 )
 
+// NameInput is the input of show name, it has no keys
+type NameInput struct{}
+
 func Name() *cli.Command {
-	return &cli.Command{
-		Name:   "name",
-		Usage:  "show name",
-		Action: name,
-	}
+	return cmd.NewCommand("name", "show name", name)
 }
 
-func name(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, _ resourcetypes.RawParams) (interface{}, error) {
-		return s.Name(), nil
-	})
+func name(_ context.Context, s *hostdir.Plugin, _ *NameInput) (string, error) {
+	return s.Name(), nil
 }
@@ -0,0 +1,56 @@
+package hostdir
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+	"github.com/yuyang0/resource-hostdir/cmd"
+)
+
+// commandSchema is the schemas of input and output of a command
+type commandSchema struct {
+	Input  *cmd.Schema `json:"input"`
+	Output *cmd.Schema `json:"output"`
+}
+
+// Schema prints JSON Schemas of the input and output of commands
+func Schema() *cli.Command {
+	return &cli.Command{
+		Name:      "schema",
+		Usage:     "print JSON Schemas of the input and output of commands, all commands if none is provided",
+		ArgsUsage: "[COMMAND]",
+		Action: func(c *cli.Context) error {
+			schemas := map[string]*commandSchema{}
+			for _, command := range cmd.Commands() {
+				schemas[command.Name] = &commandSchema{
+					Input:  titled(command.Input, command.Name+" input", command.Usage),
+					Output: titled(command.Output, command.Name+" output", ""),
+				}
+			}
+			var v any = schemas
+			if c.NArg() > 0 {
+				schema, ok := schemas[c.Args().First()]
+				if !ok {
+					return cli.Exit(fmt.Sprintf("unknown command: %s", c.Args().First()), 128)
+				}
+				v = schema
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(v)
+		},
+	}
+}
+
+// titled returns a copy of top level schema with its dialect and title
+func titled(schema *cmd.Schema, title, description string) *cmd.Schema {
+	s := *schema
+	s.Schema = cmd.SchemaVersion
+	s.Title = title
+	if description != "" {
+		s.Description = description
+	}
+	return &s
+}
@@ -1,24 +1,23 @@
 package metrics
 
 import (
+	"context"
+
 	"github.com/yuyang0/resource-hostdir/cmd"
 	"github.com/yuyang0/resource-hostdir/hostdir"
 
 	"github.com/projecteru2/core/resource/plugins/binary"
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	"github.com/urfave/cli/v2"
 )
 
+// DescriptionInput is the input of show metrics descriptions, it has no keys
+type DescriptionInput struct{}
+
 func Description() *cli.Command {
-	return &cli.Command{
-		Name:   binary.GetMetricsDescriptionCommand,
-		Usage:  "show metrics descriptions",
-		Action: description,
-	}
+	return cmd.NewCommand(binary.GetMetricsDescriptionCommand, "show metrics descriptions", description)
 }
 
-func description(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, _ resourcetypes.RawParams) (interface{}, error) {
-		return s.GetMetricsDescription(c.Context)
-	})
+func description(ctx context.Context, s *hostdir.Plugin, _ *DescriptionInput) (*plugintypes.GetMetricsDescriptionResponse, error) {
+	return s.GetMetricsDescription(ctx)
 }
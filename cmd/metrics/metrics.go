@@ -1,26 +1,26 @@
 package metrics
 
 import (
+	"context"
+
 	"github.com/yuyang0/resource-hostdir/cmd"
 	"github.com/yuyang0/resource-hostdir/hostdir"
 
 	"github.com/projecteru2/core/resource/plugins/binary"
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	"github.com/urfave/cli/v2"
 )
 
+// GetMetricsInput is the input of show metrics
+type GetMetricsInput struct {
+	Podname  string `json:"podname,omitempty" description:"pod of node, a label of metrics"`
+	Nodename string `json:"nodename" description:"name of node"`
+}
+
 func GetMetrics() *cli.Command {
-	return &cli.Command{
-		Name:   binary.GetMetricsCommand,
-		Usage:  "show metrics",
-		Action: metric,
-	}
+	return cmd.NewCommand(binary.GetMetricsCommand, "show metrics", metric)
 }
 
-func metric(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		podname := in.String("podname")
-		nodename := in.String("nodename")
-		return s.GetMetrics(c.Context, podname, nodename)
-	})
+func metric(ctx context.Context, s *hostdir.Plugin, in *GetMetricsInput) (*plugintypes.GetMetricsResponse, error) {
+	return s.GetMetrics(ctx, in.Podname, in.Nodename)
 }
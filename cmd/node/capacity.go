@@ -1,53 +1,49 @@
 package node
 
 import (
+	"context"
+
 	"github.com/projecteru2/core/resource/plugins/binary"
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	"github.com/projecteru2/core/types"
 	"github.com/urfave/cli/v2"
 	"github.com/yuyang0/resource-hostdir/cmd"
 	"github.com/yuyang0/resource-hostdir/hostdir"
 )
 
-func GetNodesDeployCapacity() *cli.Command {
-	return &cli.Command{
-		Name:   binary.GetNodesDeployCapacityCommand,
-		Usage:  "get deploy capacity",
-		Action: getNodesDeployCapacity,
-	}
+// GetNodesDeployCapacityInput is the input of get deploy capacity
+type GetNodesDeployCapacityInput struct {
+	Nodenames        []string                            `json:"nodenames" description:"names of nodes"`
+	WorkloadResource plugintypes.WorkloadResourceRequest `json:"workload_resource,omitempty" description:"resource request of a workload, e.g. {\"volumes\": [\"/eru/img0:/data:10GiB\"]}"`
+}
+
+// SetNodeResourceCapacityInput is the input of set node capacity
+type SetNodeResourceCapacityInput struct {
+	Nodename        string                          `json:"nodename" description:"name of node"`
+	Resource        plugintypes.NodeResource        `json:"resource,omitempty" description:"capacity in the form of node resource, used if resource_request isn't set"`
+	ResourceRequest plugintypes.NodeResourceRequest `json:"resource_request,omitempty" description:"roots of node, e.g. {\"roots\": [\"/eru:1TiB\"]}"`
+	Delta           bool                            `json:"delta,omitempty" description:"the capacity is added to or subtracted from the current one"`
+	Incr            bool                            `json:"incr,omitempty" description:"with delta, add rather than subtract"`
 }
 
-func getNodesDeployCapacity(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodenames := in.StringSlice("nodenames")
-		if len(nodenames) == 0 {
-			return nil, types.ErrEmptyNodeName
-		}
+func GetNodesDeployCapacity() *cli.Command {
+	return cmd.NewCommand(binary.GetNodesDeployCapacityCommand, "get deploy capacity", getNodesDeployCapacity)
+}
 
-		workloadResource := in.RawParams("workload_resource")
-		return s.GetNodesDeployCapacity(c.Context, nodenames, workloadResource)
-	})
+func getNodesDeployCapacity(ctx context.Context, s *hostdir.Plugin, in *GetNodesDeployCapacityInput) (*plugintypes.GetNodesDeployCapacityResponse, error) {
+	if len(in.Nodenames) == 0 {
+		return nil, types.ErrEmptyNodeName
+	}
+	return s.GetNodesDeployCapacity(ctx, in.Nodenames, in.WorkloadResource)
 }
 
 func SetNodeResourceCapacity() *cli.Command {
-	return &cli.Command{
-		Name:   binary.SetNodeResourceCapacityCommand,
-		Usage:  "set node capacity",
-		Action: setNodeResourceCapacity,
-	}
+	return cmd.NewCommand(binary.SetNodeResourceCapacityCommand, "set node capacity", setNodeResourceCapacity)
 }
 
-func setNodeResourceCapacity(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
-
-		incr := in.Bool("incr")
-		delta := in.Bool("delta")
-		resource := in.RawParams("resource")
-		resourceRequest := in.RawParams("resource_request")
-		return s.SetNodeResourceCapacity(c.Context, nodename, resource, resourceRequest, delta, incr)
-	})
+func setNodeResourceCapacity(ctx context.Context, s *hostdir.Plugin, in *SetNodeResourceCapacityInput) (*plugintypes.SetNodeResourceCapacityResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
+	}
+	return s.SetNodeResourceCapacity(ctx, in.Nodename, in.Resource, in.ResourceRequest, in.Delta, in.Incr)
 }
@@ -1,29 +1,28 @@
 package node
 
 import (
+	"context"
+
 	"github.com/projecteru2/core/resource/plugins/binary"
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	"github.com/projecteru2/core/types"
 	"github.com/urfave/cli/v2"
 	"github.com/yuyang0/resource-hostdir/cmd"
 	"github.com/yuyang0/resource-hostdir/hostdir"
 )
 
-func GetMostIdleNode() *cli.Command {
-	return &cli.Command{
-		Name:   binary.GetMostIdleNodeCommand,
-		Usage:  "get most idle node",
-		Action: getMostIdleNode,
-	}
+// GetMostIdleNodeInput is the input of get most idle node
+type GetMostIdleNodeInput struct {
+	Nodenames []string `json:"nodenames" description:"names of nodes"`
 }
 
-func getMostIdleNode(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodenames := in.StringSlice("nodenames")
-		if len(nodenames) == 0 {
-			return nil, types.ErrEmptyNodeName
-		}
+func GetMostIdleNode() *cli.Command {
+	return cmd.NewCommand(binary.GetMostIdleNodeCommand, "get most idle node", getMostIdleNode)
+}
 
-		return s.GetMostIdleNode(c.Context, nodenames)
-	})
+func getMostIdleNode(ctx context.Context, s *hostdir.Plugin, in *GetMostIdleNodeInput) (*plugintypes.GetMostIdleNodeResponse, error) {
+	if len(in.Nodenames) == 0 {
+		return nil, types.ErrEmptyNodeName
+	}
+	return s.GetMostIdleNode(ctx, in.Nodenames)
 }
@@ -1,9 +1,11 @@
 package node
 
 import (
+	"context"
+
 	"github.com/cockroachdb/errors"
 	"github.com/projecteru2/core/resource/plugins/binary"
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	"github.com/projecteru2/core/types"
 	coretypes "github.com/projecteru2/core/types"
 	"github.com/urfave/cli/v2"
@@ -11,69 +13,54 @@ import (
 	"github.com/yuyang0/resource-hostdir/hostdir"
 )
 
-func GetNodeResourceInfo() *cli.Command {
-	return &cli.Command{
-		Name:   binary.GetNodeResourceInfoCommand,
-		Usage:  "get node resource info",
-		Action: getNodeResourceInfo,
-	}
+// GetNodeResourceInfoInput is the input of get node resource info and fix node resource
+type GetNodeResourceInfoInput struct {
+	Nodename          string                         `json:"nodename" description:"name of node"`
+	WorkloadsResource []plugintypes.WorkloadResource `json:"workloads_resource,omitempty" description:"resource of all workloads on node, the usage is checked against them"`
 }
 
-func getNodeResourceInfo(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
-
-		workloadsResource := in.SliceRawParams("workloads_resource")
-		r, err := s.GetNodeResourceInfo(c.Context, nodename, workloadsResource)
-		// when ETCD key doesn't exist, then return an empty NodeResourceInfo value
-		if err == nil || errors.Is(err, coretypes.ErrNodeNotExists) {
-			return r, nil
-		}
-		return r, err
-	})
+// SetNodeResourceInfoInput is the input of set node resource info
+type SetNodeResourceInfoInput struct {
+	Nodename string                   `json:"nodename" description:"name of node"`
+	Capacity plugintypes.NodeResource `json:"capacity,omitempty" description:"capacity of node, e.g. {\"roots\": {\"/eru\": {\"size\": 1099511627776}}}"`
+	Usage    plugintypes.NodeResource `json:"usage,omitempty" description:"usage of node, in the form of capacity"`
 }
 
-func SetNodeResourceInfo() *cli.Command {
-	return &cli.Command{
-		Name:   binary.SetNodeResourceInfoCommand,
-		Usage:  "set node resource info",
-		Action: setNodeResourceInfo,
-	}
+func GetNodeResourceInfo() *cli.Command {
+	return cmd.NewCommand(binary.GetNodeResourceInfoCommand, "get node resource info", getNodeResourceInfo)
 }
 
-func setNodeResourceInfo(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
+func getNodeResourceInfo(ctx context.Context, s *hostdir.Plugin, in *GetNodeResourceInfoInput) (*plugintypes.GetNodeResourceInfoResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
+	}
 
-		capacity := in.RawParams("capacity")
-		usage := in.RawParams("usage")
+	r, err := s.GetNodeResourceInfo(ctx, in.Nodename, in.WorkloadsResource)
+	// when ETCD key doesn't exist, then return an empty NodeResourceInfo value
+	if err == nil || errors.Is(err, coretypes.ErrNodeNotExists) {
+		return r, nil
+	}
+	return r, err
+}
 
-		return s.SetNodeResourceInfo(c.Context, nodename, capacity, usage)
-	})
+func SetNodeResourceInfo() *cli.Command {
+	return cmd.NewCommand(binary.SetNodeResourceInfoCommand, "set node resource info", setNodeResourceInfo)
 }
 
-func FixNodeResource() *cli.Command {
-	return &cli.Command{
-		Name:   binary.FixNodeResourceCommand,
-		Usage:  "fix node resource",
-		Action: fixNodeResource,
+func setNodeResourceInfo(ctx context.Context, s *hostdir.Plugin, in *SetNodeResourceInfoInput) (*plugintypes.SetNodeResourceInfoResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
 	}
+	return s.SetNodeResourceInfo(ctx, in.Nodename, in.Capacity, in.Usage)
 }
 
-func fixNodeResource(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
+func FixNodeResource() *cli.Command {
+	return cmd.NewCommand(binary.FixNodeResourceCommand, "fix node resource", fixNodeResource)
+}
 
-		workloadsResource := in.SliceRawParams("workloads_resource")
-		return s.FixNodeResource(c.Context, nodename, workloadsResource)
-	})
+func fixNodeResource(ctx context.Context, s *hostdir.Plugin, in *GetNodeResourceInfoInput) (*plugintypes.GetNodeResourceInfoResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
+	}
+	return s.FixNodeResource(ctx, in.Nodename, in.WorkloadsResource)
 }
@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/yuyang0/resource-hostdir/cmd"
@@ -8,53 +9,50 @@ import (
 
 	enginetypes "github.com/projecteru2/core/engine/types"
 	"github.com/projecteru2/core/resource/plugins/binary"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	resourcetypes "github.com/projecteru2/core/resource/types"
 	"github.com/projecteru2/core/types"
 	"github.com/urfave/cli/v2"
 )
 
+// AddNodeInput is the input of add node
+type AddNodeInput struct {
+	Nodename string                          `json:"nodename" description:"name of node"`
+	Resource plugintypes.NodeResourceRequest `json:"resource,omitempty" description:"roots of node, e.g. {\"roots\": [\"/eru:1TiB\"]}, default roots of config if not set"`
+	Info     resourcetypes.RawParams         `json:"info,omitempty" description:"engine info of node"`
+}
+
+// RemoveNodeInput is the input of remove node
+type RemoveNodeInput struct {
+	Nodename string `json:"nodename" description:"name of node"`
+}
+
 func AddNode() *cli.Command {
-	return &cli.Command{
-		Name:   binary.AddNodeCommand,
-		Usage:  "add node",
-		Action: addNode,
-	}
+	return cmd.NewCommand(binary.AddNodeCommand, "add node", addNode)
 }
 
 func RemoveNode() *cli.Command {
-	return &cli.Command{
-		Name:   binary.RemoveNodeCommand,
-		Usage:  "remove node",
-		Action: removeNode,
-	}
+	return cmd.NewCommand(binary.RemoveNodeCommand, "remove node", removeNode)
 }
 
-func addNode(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
-		engineInfo := in.RawParams("info")
-		eInfoBytes, err := json.Marshal(engineInfo)
-		if err != nil {
-			return nil, err
-		}
-		resource := in.RawParams("resource")
-		info := &enginetypes.Info{}
-		if err := json.Unmarshal(eInfoBytes, info); err != nil {
-			return nil, err
-		}
-		return s.AddNode(c.Context, nodename, resource, info)
-	})
+func addNode(ctx context.Context, s *hostdir.Plugin, in *AddNodeInput) (*plugintypes.AddNodeResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
+	}
+	eInfoBytes, err := json.Marshal(in.Info)
+	if err != nil {
+		return nil, err
+	}
+	info := &enginetypes.Info{}
+	if err := json.Unmarshal(eInfoBytes, info); err != nil {
+		return nil, err
+	}
+	return s.AddNode(ctx, in.Nodename, in.Resource, info)
 }
 
-func removeNode(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
-		return s.RemoveNode(c.Context, nodename)
-	})
+func removeNode(ctx context.Context, s *hostdir.Plugin, in *RemoveNodeInput) (*plugintypes.RemoveNodeResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
+	}
+	return s.RemoveNode(ctx, in.Nodename)
 }
@@ -1,34 +1,33 @@
 package node
 
 import (
+	"context"
+
 	"github.com/projecteru2/core/resource/plugins/binary"
-	resourcetypes "github.com/projecteru2/core/resource/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	"github.com/projecteru2/core/types"
 	"github.com/urfave/cli/v2"
 	"github.com/yuyang0/resource-hostdir/cmd"
 	"github.com/yuyang0/resource-hostdir/hostdir"
 )
 
-func SetNodeResourceUsage() *cli.Command {
-	return &cli.Command{
-		Name:   binary.SetNodeResourceUsageCommand,
-		Usage:  "set node usage",
-		Action: setNodeResourceUsage,
-	}
+// SetNodeResourceUsageInput is the input of set node usage
+type SetNodeResourceUsageInput struct {
+	Nodename          string                          `json:"nodename" description:"name of node"`
+	WorkloadsResource []plugintypes.WorkloadResource  `json:"workloads_resource,omitempty" description:"resource of workloads, used if neither resource nor resource_request is set"`
+	Resource          plugintypes.NodeResource        `json:"resource,omitempty" description:"usage in the form of node resource"`
+	ResourceRequest   plugintypes.NodeResourceRequest `json:"resource_request,omitempty" description:"usage in the form of node resource request"`
+	Delta             bool                            `json:"delta,omitempty" description:"the usage is added to or subtracted from the current one"`
+	Incr              bool                            `json:"incr,omitempty" description:"with delta, add rather than subtract"`
 }
 
-func setNodeResourceUsage(c *cli.Context) error {
-	return cmd.Serve(c, func(s *hostdir.Plugin, in resourcetypes.RawParams) (interface{}, error) {
-		nodename := in.String("nodename")
-		if nodename == "" {
-			return nil, types.ErrEmptyNodeName
-		}
+func SetNodeResourceUsage() *cli.Command {
+	return cmd.NewCommand(binary.SetNodeResourceUsageCommand, "set node usage", setNodeResourceUsage)
+}
 
-		incr := in.Bool("incr")
-		delta := in.Bool("delta")
-		resource := in.RawParams("resource")
-		resourceRequest := in.RawParams("resource_request")
-		workloadsResource := in.SliceRawParams("workloads_resource")
-		return s.SetNodeResourceUsage(c.Context, nodename, resource, resourceRequest, workloadsResource, delta, incr)
-	})
+func setNodeResourceUsage(ctx context.Context, s *hostdir.Plugin, in *SetNodeResourceUsageInput) (*plugintypes.SetNodeResourceUsageResponse, error) {
+	if in.Nodename == "" {
+		return nil, types.ErrEmptyNodeName
+	}
+	return s.SetNodeResourceUsage(ctx, in.Nodename, in.Resource, in.ResourceRequest, in.WorkloadsResource, in.Delta, in.Incr)
 }
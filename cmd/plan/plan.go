@@ -0,0 +1,135 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	"github.com/urfave/cli/v2"
+
+	"github.com/yuyang0/resource-hostdir/cmd"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// Plan prints the placement of a deploy across nodes without touching the stored state
+func Plan() *cli.Command {
+	return &cli.Command{
+		Name:      "plan",
+		Usage:     "dry run a deploy across nodes and print the placement",
+		ArgsUsage: "NODE [NODE...]",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "volume",
+				Usage: "volume of workload resource request, e.g. /eru/img0:/data:10GiB, can be repeated",
+			},
+			&cli.StringFlag{
+				Name:  "request",
+				Usage: "workload resource request in json, - for stdin, merged with --volume",
+			},
+			&cli.IntFlag{
+				Name:     "count",
+				Usage:    "number of workloads to deploy",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "output",
+				Value: "table",
+				Usage: "table or json",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			nodenames := c.Args().Slice()
+			if len(nodenames) == 0 {
+				return cli.Exit("at least one node must be provided", 128)
+			}
+			resource, err := parseRequest(c)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			p, err := cmd.NewPlugin(c)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			defer p.Close()
+
+			plan, err := p.Plan(c.Context, nodenames, resource, c.Int("count"))
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			if c.String("output") == "json" {
+				err = json.NewEncoder(os.Stdout).Encode(plan)
+			} else {
+				err = printPlan(plan)
+			}
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			if plan.Unplaced > 0 {
+				return cli.Exit("", 1)
+			}
+			return nil
+		},
+	}
+}
+
+func parseRequest(c *cli.Context) (plugintypes.WorkloadResourceRequest, error) {
+	resource := plugintypes.WorkloadResourceRequest{}
+	switch path := c.String("request"); path {
+	case "":
+	case "-":
+		if err := json.NewDecoder(os.Stdin).Decode(&resource); err != nil {
+			return nil, err
+		}
+	default:
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(body, &resource); err != nil {
+			return nil, err
+		}
+	}
+	if volumes := c.StringSlice("volume"); len(volumes) > 0 {
+		resource["volumes"] = append(resource.OneOfStringSlice("volumes"), volumes...)
+	}
+	return resource, nil
+}
+
+func printPlan(plan *types.DeployPlan) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tCAPACITY\tDEPLOY\tREMAINING\tFREE")
+	for _, nodePlan := range plan.Nodes {
+		paths := []string{}
+		for path := range nodePlan.Free {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		free := []string{}
+		for _, path := range paths {
+			free = append(free, fmt.Sprintf("%s=%s", path, units.BytesSize(float64(nodePlan.Free[path]))))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", nodePlan.Nodename, count(nodePlan.Capacity), nodePlan.Deploy, count(nodePlan.Remaining), strings.Join(free, ","))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("placed %d of %d", plan.Placed, plan.Count)
+	if plan.Unplaced > 0 {
+		fmt.Printf(", %d can't be placed", plan.Unplaced)
+	}
+	fmt.Println()
+	return nil
+}
+
+func count(n int) string {
+	if n == math.MaxInt {
+		return "unlimited"
+	}
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaVersion is the dialect of JSON Schema of commands
+const SchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is the subset of JSON Schema describing inputs and outputs of commands, see SchemaOf.
+// AdditionalProperties is false for objects of structs, or the schema of values for maps.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 SchemaType         `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// SchemaType is the type of Schema, it is a list if the value is nullable, e.g. ["object", "null"]
+type SchemaType []string
+
+// MarshalJSON writes single type as a string
+func (t SchemaType) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// SchemaOf returns the schema of values of t in JSON.
+// Fields are required unless tagged omitempty, the description tag of field is its description.
+// Maps, slices, pointers and interfaces are nullable, interfaces are of any type.
+func SchemaOf(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: SchemaType{"boolean"}}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: SchemaType{"integer"}}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: SchemaType{"number"}}
+	case reflect.String:
+		return &Schema{Type: SchemaType{"string"}}
+	case reflect.Ptr:
+		s := SchemaOf(t.Elem())
+		s.Type = nullable(s.Type)
+		return s
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: SchemaType{"array", "null"}, Items: SchemaOf(t.Elem())}
+	case reflect.Map:
+		s := &Schema{Type: SchemaType{"object", "null"}}
+		// values of any type are left unchecked
+		if t.Elem().Kind() != reflect.Interface {
+			s.AdditionalProperties = SchemaOf(t.Elem())
+		}
+		return s
+	case reflect.Struct:
+		s := &Schema{Type: SchemaType{"object"}, Properties: map[string]*Schema{}, AdditionalProperties: false}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, omitempty, ok := jsonName(field)
+			if !ok {
+				continue
+			}
+			property := SchemaOf(field.Type)
+			property.Description = field.Tag.Get("description")
+			s.Properties[name] = property
+			if !omitempty {
+				s.Required = append(s.Required, name)
+			}
+		}
+		sort.Strings(s.Required)
+		return s
+	default:
+		return &Schema{}
+	}
+}
+
+func nullable(t SchemaType) SchemaType {
+	for _, typ := range t {
+		if typ == "null" {
+			return t
+		}
+	}
+	if len(t) == 0 {
+		return t
+	}
+	return append(t, "null")
+}
+
+// jsonName returns the key of field in JSON, false if it is skipped
+func jsonName(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	omitempty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, true
+}
+
+// Validate checks whether v in JSON matches the schema, the error tells the first mismatch by its path, e.g. /nodenames/0
+func (s *Schema) Validate(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return s.validate("", value)
+}
+
+func (s *Schema) validate(path string, v any) error {
+	if len(s.Type) > 0 && !s.match(v) {
+		return fmt.Errorf("%s: %s is expected, got %s", pathOf(path), strings.Join(s.Type, " or "), typeOf(v))
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				return fmt.Errorf("%s: missing required key %s", pathOf(path), key)
+			}
+		}
+		keys := []string{}
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			property, ok := s.Properties[key]
+			if !ok {
+				switch additional := s.AdditionalProperties.(type) {
+				case bool:
+					if !additional {
+						return fmt.Errorf("%s: unknown key %s", pathOf(path), key)
+					}
+					continue
+				case *Schema:
+					property = additional
+				default:
+					continue
+				}
+			}
+			if err := property.validate(path+"/"+key, v[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range v {
+			if err := s.Items.validate(fmt.Sprintf("%s/%d", path, i), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) match(v any) bool {
+	actual := typeOf(v)
+	for _, typ := range s.Type {
+		if typ == actual || (typ == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the type of value decoded from JSON in terms of JSON Schema
+func typeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func pathOf(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
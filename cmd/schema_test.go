@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	resourcetypes "github.com/projecteru2/core/resource/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type testInput struct {
+	Nodename  string                  `json:"nodename" description:"name of node"`
+	Nodenames []string                `json:"nodenames,omitempty"`
+	Count     int                     `json:"count,omitempty"`
+	Incr      bool                    `json:"incr,omitempty"`
+	Resource  resourcetypes.RawParams `json:"resource,omitempty"`
+	Sizes     map[string]int64        `json:"sizes,omitempty"`
+	Skipped   string                  `json:"-"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(reflect.TypeOf(testInput{}))
+	data, err := json.Marshal(s)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"nodename": {"type": "string", "description": "name of node"},
+			"nodenames": {"type": ["array", "null"], "items": {"type": "string"}},
+			"count": {"type": "integer"},
+			"incr": {"type": "boolean"},
+			"resource": {"type": ["object", "null"]},
+			"sizes": {"type": ["object", "null"], "additionalProperties": {"type": "integer"}}
+		},
+		"required": ["nodename"],
+		"additionalProperties": false
+	}`, string(data))
+}
+
+func TestSchemaValidate(t *testing.T) {
+	s := SchemaOf(reflect.TypeOf(testInput{}))
+	for _, in := range []resourcetypes.RawParams{
+		{"nodename": "node0"},
+		{"nodename": "node0", "nodenames": nil, "resource": nil, "sizes": nil},
+		{"nodename": "node0", "nodenames": []string{"node1"}, "count": 2, "incr": true},
+		{"nodename": "node0", "resource": resourcetypes.RawParams{"roots": []string{"/eru:1TiB"}}, "sizes": map[string]int64{"/eru": 1}},
+	} {
+		assert.NoError(t, s.Validate(in))
+	}
+	for in, msg := range map[string]string{
+		`{}`: "/: missing required key nodename",
+		`{"nodename": "node0", "podname": "pod0"}`:  "/: unknown key podname",
+		`{"nodename": 1}`:                           "/nodename: string is expected, got integer",
+		`{"nodename": "node0", "count": 1.5}`:       "/count: integer is expected, got number",
+		`{"nodename": "node0", "incr": "true"}`:     "/incr: boolean is expected, got string",
+		`{"nodename": "node0", "nodenames": [1]}`:   "/nodenames/0: string is expected, got integer",
+		`{"nodename": "node0", "resource": []}`:     "/resource: object or null is expected, got array",
+		`{"nodename": "node0", "sizes": {"a": ""}}`: "/sizes/a: integer is expected, got string",
+	} {
+		v := resourcetypes.RawParams{}
+		assert.NoError(t, json.Unmarshal([]byte(in), &v))
+		assert.EqualError(t, s.Validate(v), msg, in)
+	}
+}
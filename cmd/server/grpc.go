@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net"
+	"os/signal"
+	"syscall"
+
+	"github.com/projecteru2/core/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/yuyang0/resource-hostdir/cmd"
+	"github.com/yuyang0/resource-hostdir/rpc"
+)
+
+// GRPC keeps one plugin alive and serves it over grpc
+func GRPC() *cli.Command {
+	return &cli.Command{
+		Name:  "grpc",
+		Usage: "serve the plugin interface over grpc, see hostdir.grpc in config",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "bind",
+				Usage: "address to listen on, overrides hostdir.grpc.bind in config",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			p, err := cmd.NewPlugin(c)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			defer p.Close()
+
+			cfg := p.Config().GRPC
+			if bind := c.String("bind"); bind != "" {
+				cfg.Bind = bind
+			}
+			opts, err := rpc.ServerOptions(cfg)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			l, err := net.Listen("tcp", cfg.Bind)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			s := rpc.NewGRPCServer(p, opts...)
+
+			ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+			go func() {
+				<-ctx.Done()
+				s.GracefulStop()
+			}()
+			log.WithFunc("server.GRPC").Infof(ctx, "grpc server listening on %s", cfg.Bind)
+			if err := s.Serve(l); err != nil {
+				return cli.Exit(err, 128)
+			}
+			return nil
+		},
+	}
+}
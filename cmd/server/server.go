@@ -0,0 +1,159 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/log"
+	"github.com/urfave/cli/v2"
+	"github.com/yuyang0/resource-hostdir/cmd"
+	"github.com/yuyang0/resource-hostdir/hostdir"
+)
+
+const (
+	requestTimeout = time.Minute
+	// accept errors are retried with backoff from minAcceptDelay doubling to maxAcceptDelay, as net/http does
+	minAcceptDelay = 5 * time.Millisecond
+	maxAcceptDelay = time.Second
+)
+
+// Serve keeps one plugin alive and answers commands over unix socket
+func Serve() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "serve commands over unix socket, use --socket in other commands to call it",
+		Action: func(c *cli.Context) error {
+			if cmd.Socket == "" {
+				return cli.Exit("socket must be provided", 128)
+			}
+			p, err := cmd.NewPlugin(c)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			defer p.Close()
+
+			ctx, cancel := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+			if err := New(p).ListenAndServe(ctx, cmd.Socket); err != nil {
+				return cli.Exit(err, 128)
+			}
+			return nil
+		},
+	}
+}
+
+// Server .
+type Server struct {
+	plugin *hostdir.Plugin
+}
+
+// New .
+func New(p *hostdir.Plugin) *Server {
+	return &Server{plugin: p}
+}
+
+// ListenAndServe listens on socket until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context, socket string) error {
+	// remove stale socket left by a crashed server
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socket)
+	if err := os.Chmod(socket, 0660); err != nil {
+		_ = l.Close()
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve accepts connections on l until ctx is done, l is closed when returned
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	logger := log.WithFunc("server.Serve")
+	go func() {
+		<-ctx.Done()
+		_ = l.Close()
+	}()
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	delay := time.Duration(0)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			// errors like EMFILE last for a while, retrying at once only spins
+			if delay = 2 * delay; delay == 0 {
+				delay = minAcceptDelay
+			} else if delay > maxAcceptDelay {
+				delay = maxAcceptDelay
+			}
+			logger.Warnf(ctx, "failed to accept: %s, retrying in %s", err, delay)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(delay):
+			}
+			continue
+		}
+		delay = 0
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			s.handle(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) handle(ctx context.Context, conn net.Conn) {
+	logger := log.WithFunc("server.handle")
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+	req := &cmd.Request{}
+	resp := &cmd.Response{}
+	if err := json.NewDecoder(conn).Decode(req); err != nil {
+		resp.Error, resp.Code = errors.Wrap(err, "failed decode request").Error(), cmd.CodeInvalidInput
+	} else if result, err := s.Call(ctx, req); err != nil {
+		e := cmd.NewError(err)
+		resp.Error, resp.Code, resp.Details = e.Message, e.Code, e.Details
+	} else {
+		resp.Result = result
+	}
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		logger.Warnf(ctx, "failed to write response of %s: %s", req.Command, err)
+	}
+}
+
+// Call dispatches the request to the handler of command
+func (s *Server) Call(ctx context.Context, req *cmd.Request) (json.RawMessage, error) {
+	command, ok := cmd.GetCommand(req.Command)
+	if !ok {
+		return nil, &cmd.Error{Code: cmd.CodeUnknownCommand, Message: fmt.Sprintf("unknown command: %s", req.Command)}
+	}
+	if err := command.Validate(req.Params); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	r, err := command.Handler(ctx, s.plugin, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(r)
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	resourcetypes "github.com/projecteru2/core/resource/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/yuyang0/resource-hostdir/cmd"
+	"github.com/yuyang0/resource-hostdir/hostdir"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p, err := hostdir.NewPlugin(ctx, coretypes.Config{}, types.Config{
+		Roots: []types.RootConfig{{Path: "/eru", Size: "1TiB"}},
+		Store: types.StoreConfig{Type: types.StoreMemory},
+	})
+	assert.NoError(t, err)
+
+	type addNodeInput struct {
+		Nodename string `json:"nodename"`
+	}
+	cmd.NewCommand("test-add-node", "", func(ctx context.Context, s *hostdir.Plugin, in *addNodeInput) (*plugintypes.AddNodeResponse, error) {
+		return s.AddNode(ctx, in.Nodename, nil, nil)
+	})
+
+	socket := filepath.Join(t.TempDir(), "hostdir.sock")
+	done := make(chan error)
+	go func() {
+		done <- New(p).ListenAndServe(ctx, socket)
+	}()
+	assert.Eventually(t, func() bool {
+		_, err := cmd.Call(ctx, socket, "unknown", nil)
+		return err != nil && err.Error() == "unknown command: unknown"
+	}, time.Second, 10*time.Millisecond)
+
+	o, err := cmd.Call(ctx, socket, "test-add-node", resourcetypes.RawParams{"nodename": "node0"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"capacity":{"roots":{"/eru":{"size":1099511627776}}},"usage":{"roots":{"/eru":{"size":0}}}}`, string(o))
+
+	// the input is validated against the schema
+	_, err = cmd.Call(ctx, socket, "test-add-node", resourcetypes.RawParams{"nodename": "node1", "podname": "pod0"})
+	assert.ErrorContains(t, err, "unknown key podname")
+
+	// the state is kept between calls
+	_, err = cmd.Call(ctx, socket, "test-add-node", resourcetypes.RawParams{"nodename": "node0"})
+	assert.ErrorContains(t, err, coretypes.ErrNodeExists.Error())
+	// the code of error is kept over socket
+	e := &cmd.Error{}
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, cmd.CodeNodeExists, e.Code)
+
+	cancel()
+	assert.NoError(t, <-done)
+	_, err = cmd.Call(context.Background(), socket, "test-add-node", nil)
+	assert.ErrorAs(t, err, &e)
+	assert.Equal(t, cmd.CodeServerUnavailable, e.Code)
+}
+
+// failingListener fails every accept, like a process out of file descriptors
+type failingListener struct {
+	net.Listener
+	accepts atomic.Int32
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	l.accepts.Add(1)
+	return nil, syscall.EMFILE
+}
+
+func (l *failingListener) Close() error { return nil }
+
+func TestServeBackoff(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	l := &failingListener{}
+	assert.NoError(t, New(nil).Serve(ctx, l))
+	// 5ms, 10ms, 20ms... rather than spinning
+	assert.Less(t, l.accepts.Load(), int32(10))
+}
@@ -0,0 +1,117 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/yuyang0/resource-hostdir/cmd"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// Export dumps the state of all nodes to a versioned json file
+func Export() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "dump the state of all nodes to a versioned json file, for backup and migration",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "file", Aliases: []string{"f"}, Value: "-", Usage: "file to write, - for stdout"},
+		},
+		Action: func(c *cli.Context) error {
+			p, err := cmd.NewPlugin(c)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			defer p.Close()
+
+			snapshot, err := p.Export(c.Context)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			w := io.Writer(os.Stdout)
+			if path := c.String("file"); path != "-" {
+				f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+				if err != nil {
+					return cli.Exit(err, 128)
+				}
+				defer f.Close()
+				w = f
+			}
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(snapshot); err != nil {
+				return cli.Exit(err, 128)
+			}
+			return nil
+		},
+	}
+}
+
+// Import restores the state of nodes from a file written by export
+func Import() *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "restore the state of nodes from a file written by export",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "file", Aliases: []string{"f"}, Value: "-", Usage: "file to read, - for stdin"},
+			&cli.StringFlag{
+				Name:  "mode",
+				Value: types.ImportMerge,
+				Usage: "merge keeps the nodes not in the file, replace removes them",
+			},
+			&cli.BoolFlag{Name: "dry-run", Usage: "only print the changes"},
+			&cli.StringFlag{Name: "output", Value: "table", Usage: "table or json"},
+		},
+		Action: func(c *cli.Context) error {
+			r := io.Reader(os.Stdin)
+			if path := c.String("file"); path != "-" {
+				f, err := os.Open(path)
+				if err != nil {
+					return cli.Exit(err, 128)
+				}
+				defer f.Close()
+				r = f
+			}
+			snapshot := &types.Snapshot{}
+			if err := json.NewDecoder(r).Decode(snapshot); err != nil {
+				return cli.Exit(errors.Wrap(types.ErrInvalidSnapshot, err.Error()), 128)
+			}
+
+			p, err := cmd.NewPlugin(c)
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			defer p.Close()
+
+			changes, err := p.Import(c.Context, snapshot, c.String("mode"), c.Bool("dry-run"))
+			if err != nil {
+				return cli.Exit(err, 128)
+			}
+			if c.String("output") == "json" {
+				if err := json.NewEncoder(os.Stdout).Encode(changes); err != nil {
+					return cli.Exit(err, 128)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NODE\tACTION\tDIFFS")
+			for _, change := range changes {
+				diffs := "-"
+				if len(change.Diffs) > 0 {
+					diffs = strings.Join(change.Diffs, "; ")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", change.Nodename, change.Action, diffs)
+			}
+			if err := w.Flush(); err != nil {
+				return cli.Exit(err, 128)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+
+	resourcetypes "github.com/projecteru2/core/resource/types"
+)
+
+// Request is sent to the server over unix socket, one request per connection
+type Request struct {
+	Command string                  `json:"command"`
+	Params  resourcetypes.RawParams `json:"params"`
+}
+
+// Response is sent back by the server, Result is the same as the output of the command.
+// Error is the message of failure, Code and Details are of its Error.
+type Response struct {
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Code    string          `json:"code,omitempty"`
+	Details map[string]any  `json:"details,omitempty"`
+}
+
+// Call sends the command to the server listening on socket and returns the output.
+// Failures of the command are returned as Error.
+func Call(ctx context.Context, socket, command string, in resourcetypes.RawParams) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", socket)
+	if err != nil {
+		return nil, &Error{Code: CodeServerUnavailable, Message: err.Error()}
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := json.NewEncoder(conn).Encode(&Request{Command: command, Params: in}); err != nil {
+		return nil, err
+	}
+	resp := &Response{}
+	if err := json.NewDecoder(conn).Decode(resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		code := resp.Code
+		if code == "" {
+			code = CodeInternal
+		}
+		return nil, &Error{Code: code, Message: resp.Error, Details: resp.Details}
+	}
+	return resp.Result, nil
+}
@@ -5,11 +5,17 @@ go 1.20
 require (
 	github.com/cockroachdb/errors v1.9.1
 	github.com/docker/go-units v0.5.0
+	github.com/jinzhu/configor v1.2.1
 	github.com/mitchellh/mapstructure v1.5.0
 	github.com/projecteru2/core v0.0.0-20231019042116-435f703768f4
 	github.com/sanity-io/litter v1.5.5
 	github.com/stretchr/testify v1.8.2
 	github.com/urfave/cli/v2 v2.25.1
+	go.etcd.io/bbolt v1.3.7
+	go.etcd.io/etcd/client/pkg/v3 v3.5.8
+	go.etcd.io/etcd/client/v3 v3.5.8
+	google.golang.org/grpc v1.54.1
+	google.golang.org/protobuf v1.30.0
 )
 
 require (
@@ -40,7 +46,6 @@ require (
 	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0 // indirect
 	github.com/grpc-ecosystem/grpc-gateway v1.16.0 // indirect
 	github.com/grpc-ecosystem/grpc-gateway/v2 v2.15.2 // indirect
-	github.com/jinzhu/configor v1.2.1 // indirect
 	github.com/jonboulle/clockwork v0.4.0 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
@@ -67,11 +72,8 @@ require (
 	github.com/tmc/grpc-websocket-proxy v0.0.0-20220101234140-673ab2c3ae75 // indirect
 	github.com/xiang90/probing v0.0.0-20221125231312-a49e3df8f510 // indirect
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
-	go.etcd.io/bbolt v1.3.7 // indirect
 	go.etcd.io/etcd/api/v3 v3.5.8 // indirect
-	go.etcd.io/etcd/client/pkg/v3 v3.5.8 // indirect
 	go.etcd.io/etcd/client/v2 v2.305.8 // indirect
-	go.etcd.io/etcd/client/v3 v3.5.8 // indirect
 	go.etcd.io/etcd/pkg/v3 v3.5.8 // indirect
 	go.etcd.io/etcd/raft/v3 v3.5.8 // indirect
 	go.etcd.io/etcd/server/v3 v3.5.8 // indirect
@@ -95,8 +97,6 @@ require (
 	golang.org/x/text v0.13.0 // indirect
 	golang.org/x/time v0.3.0 // indirect
 	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
-	google.golang.org/grpc v1.54.1 // indirect
-	google.golang.org/protobuf v1.30.0 // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
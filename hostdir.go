@@ -9,19 +9,45 @@ import (
 	coretypes "github.com/projecteru2/core/types"
 
 	"github.com/yuyang0/resource-hostdir/cmd"
+	"github.com/yuyang0/resource-hostdir/cmd/admin"
+	"github.com/yuyang0/resource-hostdir/cmd/agent"
 	"github.com/yuyang0/resource-hostdir/cmd/calculate"
+	"github.com/yuyang0/resource-hostdir/cmd/check"
 	"github.com/yuyang0/resource-hostdir/cmd/hostdir"
 	"github.com/yuyang0/resource-hostdir/cmd/metrics"
 	"github.com/yuyang0/resource-hostdir/cmd/node"
+	"github.com/yuyang0/resource-hostdir/cmd/plan"
+	"github.com/yuyang0/resource-hostdir/cmd/server"
+	"github.com/yuyang0/resource-hostdir/cmd/snapshot"
 	hostdirlib "github.com/yuyang0/resource-hostdir/hostdir"
 	"github.com/yuyang0/resource-hostdir/version"
 
 	"github.com/urfave/cli/v2"
 )
 
+const (
+	defaultConfigPath = "hostdir.yaml"
+	configPathEnv     = "ERU_RESOURCE_CONFIG_PATH"
+)
+
+// NewPlugin is the entry of plugin linked into eru-core, the config of eru-core is given,
+// the hostdir config is read from the config file of plugin, see the config flag
 func NewPlugin(ctx context.Context, config coretypes.Config) (plugins.Plugin, error) {
-	p, err := hostdirlib.NewPlugin(ctx, config, nil)
-	return p, err
+	if cmd.ConfigPath == "" {
+		cmd.ConfigPath = defaultConfigPath
+		if path := os.Getenv(configPathEnv); path != "" {
+			cmd.ConfigPath = path
+		}
+	}
+	hostdirConfig, err := cmd.LoadHostdirConfig()
+	if err != nil {
+		return nil, err
+	}
+	p, err := hostdirlib.NewPlugin(ctx, config, hostdirConfig)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
 }
 
 func main() {
@@ -35,6 +61,7 @@ func main() {
 	app.Version = version.VERSION
 	app.Commands = []*cli.Command{
 		hostdir.Name(),
+		hostdir.Schema(),
 		metrics.Description(),
 		metrics.GetMetrics(),
 
@@ -51,20 +78,42 @@ func main() {
 		calculate.CalculateDeploy(),
 		calculate.CalculateRealloc(),
 		calculate.CalculateRemap(),
+
+		server.Serve(),
+		server.GRPC(),
+		agent.Agent(),
+		agent.Cleanup(),
+		plan.Plan(),
+		admin.Admin(),
+		check.Check(),
+		snapshot.Export(),
+		snapshot.Import(),
 	}
 	app.Flags = []cli.Flag{
 		&cli.StringFlag{
 			Name:        "config",
-			Value:       "hostdir.yaml",
+			Value:       defaultConfigPath,
 			Usage:       "config file path for plugin, in yaml",
 			Destination: &cmd.ConfigPath,
-			EnvVars:     []string{"ERU_RESOURCE_CONFIG_PATH"},
+			EnvVars:     []string{configPathEnv},
 		},
 		&cli.BoolFlag{
 			Name:        "embedded-storage",
-			Usage:       "active embedded storage",
+			Usage:       "keep node records in a local data dir instead of etcd",
 			Destination: &cmd.EmbeddedStorage,
 		},
+		&cli.StringFlag{
+			Name:        "socket",
+			Usage:       "unix socket of server mode, commands are forwarded to the server if set",
+			Destination: &cmd.Socket,
+			EnvVars:     []string{"ERU_RESOURCE_SOCKET"},
+		},
+		&cli.StringFlag{
+			Name:        "data-dir",
+			Usage:       "data dir of embedded storage, overrides hostdir.store.data_dir in config",
+			Destination: &cmd.DataDir,
+			EnvVars:     []string{"ERU_RESOURCE_DATA_DIR"},
+		},
 	}
 	_ = app.Run(os.Args)
 }
@@ -0,0 +1,133 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/projecteru2/core/log"
+	coretypes "github.com/projecteru2/core/types"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// coreNodeWorkloadsKey is where eru-core keeps the workloads of a node, in the same etcd as plugins
+const coreNodeWorkloadsKey = "/node/%s:workloads/"
+
+// ListNodes returns the usage summary of all nodes in the store, sorted by nodename
+func (p Plugin) ListNodes(ctx context.Context) ([]*types.NodeUsage, error) {
+	nodes, err := p.listNodeResourceInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ans := []*types.NodeUsage{}
+	for nodename, nodeResourceInfo := range nodes {
+		roots, err := p.rootUsages(nodeResourceInfo)
+		if err != nil {
+			return nil, err
+		}
+		nodeUsage := &types.NodeUsage{
+			Nodename: nodename,
+			Roots:    len(roots),
+			Bindings: len(nodeResourceInfo.Bindings),
+		}
+		for _, root := range roots {
+			nodeUsage.Capacity += root.Capacity
+			nodeUsage.Used += root.Used
+			nodeUsage.Free += root.Free
+		}
+		ans = append(ans, nodeUsage)
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Nodename < ans[j].Nodename })
+	return ans, nil
+}
+
+// GetNodeRoots returns the capacity and usage of roots of node, sorted by path
+func (p Plugin) GetNodeRoots(ctx context.Context, nodename string) ([]*types.RootUsage, error) {
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+	if err != nil {
+		return nil, err
+	}
+	return p.rootUsages(nodeResourceInfo)
+}
+
+// ListNodeBindings returns the bindings of node with the workloads using them, sorted by source.
+// Workloads are read from the records of eru-core, so they are only known when the store is shared with eru-core.
+func (p Plugin) ListNodeBindings(ctx context.Context, nodename string) ([]*types.NodeBinding, error) {
+	bindings, err := p.GetNodeBindings(ctx, nodename)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := p.getBindingOwners(ctx, nodename)
+	if err != nil {
+		return nil, err
+	}
+	ans := []*types.NodeBinding{}
+	for _, vb := range bindings {
+		workloads := owners[vb.GetMapKey()]
+		if workloads == nil {
+			workloads = []string{}
+		}
+		sort.Strings(workloads)
+		ans = append(ans, &types.NodeBinding{
+			Binding:     vb.ToString(),
+			Source:      vb.Source,
+			Destination: vb.Destination,
+			SizeInBytes: vb.SizeInBytes,
+			Workloads:   workloads,
+		})
+	}
+	sort.SliceStable(ans, func(i, j int) bool { return ans[i].Source < ans[j].Source })
+	return ans, nil
+}
+
+func (p Plugin) rootUsages(nodeResourceInfo *types.NodeResourceInfo) ([]*types.RootUsage, error) {
+	effective, err := nodeResourceInfo.GetEffectiveCapacity(&p.hostdirConfig)
+	if err != nil {
+		return nil, err
+	}
+	ans := []*types.RootUsage{}
+	for path, root := range nodeResourceInfo.Capacity.Roots {
+		used := nodeResourceInfo.Usage.Roots[path].Size
+		ans = append(ans, &types.RootUsage{
+			Path:       path,
+			Size:       root.Size,
+			Overcommit: root.Overcommit,
+			Reserved:   root.Reserved,
+			Pool:       p.hostdirConfig.RootPool(path, root),
+			Domain:     p.hostdirConfig.RootDomain(path, root),
+			Capacity:   effective[path],
+			Used:       used,
+			Free:       effective[path] - used,
+		})
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Path < ans[j].Path })
+	return ans, nil
+}
+
+// getBindingOwners returns the IDs of workloads using each binding of node
+func (p Plugin) getBindingOwners(ctx context.Context, nodename string) (map[[2]string][]string, error) {
+	logger := log.WithFunc("resource.hostdir.getBindingOwners").WithField("node", nodename)
+	resps, err := p.store.List(ctx, fmt.Sprintf(coreNodeWorkloadsKey, nodename))
+	if err != nil {
+		return nil, err
+	}
+	ans := map[[2]string][]string{}
+	for key, value := range resps {
+		workload := &coretypes.Workload{}
+		if err := json.Unmarshal(value, workload); err != nil {
+			logger.Warnf(ctx, "failed to decode workload %s: %s", key, err)
+			continue
+		}
+		workloadResource := &types.WorkloadResource{}
+		if err := workloadResource.Parse(workload.Resources[p.name]); err != nil {
+			logger.Warnf(ctx, "failed to parse resource of workload %s: %s", workload.ID, err)
+			continue
+		}
+		for _, vb := range workloadResource.Volumes {
+			ans[vb.GetMapKey()] = append(ans[vb.GetMapKey()], workload.ID)
+		}
+	}
+	return ans, nil
+}
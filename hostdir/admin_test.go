@@ -0,0 +1,78 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	resourcetypes "github.com/projecteru2/core/resource/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+
+	_, err := st.AddNode(ctx, "node1", plugintypes.NodeResourceRequest{
+		"roots": []string{"/data0:100GiB", "/data1:100GiB:2:10GiB"},
+	}, nil)
+	assert.NoError(t, err)
+	_, err = st.AddNode(ctx, "node0", nil, nil)
+	assert.NoError(t, err)
+
+	req := plugintypes.WorkloadResourceRequest{"volumes": []string{"/data0/img0:/dir0:10GiB", "/data1/img1:/dir1:20GiB"}}
+	deploy, err := st.CalculateDeploy(ctx, "node1", 2, req)
+	assert.NoError(t, err)
+	_, err = st.SetNodeResourceUsage(ctx, "node1", nil, nil, deploy.WorkloadsResource, true, true)
+	assert.NoError(t, err)
+	// workloads kept by eru-core
+	for i, resource := range deploy.WorkloadsResource {
+		workload := &coretypes.Workload{
+			ID:        fmt.Sprintf("wrk%d", i),
+			Nodename:  "node1",
+			Resources: resourcetypes.Resources{"hostdir": resourcetypes.RawParams(resource)},
+		}
+		data, err := json.Marshal(workload)
+		assert.NoError(t, err)
+		assert.NoError(t, st.store.Put(ctx, fmt.Sprintf(coreNodeWorkloadsKey, "node1")+workload.ID, data))
+	}
+
+	nodes, err := st.ListNodes(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Equal(t, "node0", nodes[0].Nodename)
+	assert.Equal(t, "node1", nodes[1].Nodename)
+	assert.Equal(t, 2, nodes[1].Roots)
+	assert.Equal(t, 2, nodes[1].Bindings)
+	assert.Equal(t, int64(290*units.GiB), nodes[1].Capacity)
+	assert.Equal(t, int64(60*units.GiB), nodes[1].Used)
+	assert.Equal(t, int64(230*units.GiB), nodes[1].Free)
+
+	roots, err := st.GetNodeRoots(ctx, "node1")
+	assert.NoError(t, err)
+	assert.Len(t, roots, 2)
+	assert.Equal(t, "/data1", roots[1].Path)
+	assert.Equal(t, int64(100*units.GiB), roots[1].Size)
+	assert.Equal(t, int64(190*units.GiB), roots[1].Capacity)
+	assert.Equal(t, int64(40*units.GiB), roots[1].Used)
+	assert.Equal(t, int64(150*units.GiB), roots[1].Free)
+
+	bindings, err := st.ListNodeBindings(ctx, "node1")
+	assert.NoError(t, err)
+	assert.Len(t, bindings, 2)
+	assert.Equal(t, "/data0/img0", bindings[0].Source)
+	assert.Equal(t, int64(20*units.GiB), bindings[0].SizeInBytes)
+	assert.Equal(t, []string{"wrk0", "wrk1"}, bindings[0].Workloads)
+
+	// workloads are unknown without records of eru-core
+	bindings, err = st.ListNodeBindings(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Len(t, bindings, 0)
+
+	_, err = st.GetNodeRoots(ctx, "node2")
+	assert.ErrorIs(t, err, coretypes.ErrNodeNotExists)
+}
@@ -3,6 +3,7 @@ package hostdir
 import (
 	"context"
 
+	"github.com/cockroachdb/errors"
 	"github.com/projecteru2/core/log"
 	plugintypes "github.com/projecteru2/core/resource/plugins/types"
 	resourcetypes "github.com/projecteru2/core/resource/types"
@@ -22,22 +23,48 @@ func (p Plugin) CalculateDeploy(
 	if err := req.Parse(resourceRequest); err != nil {
 		return nil, err
 	}
-	if err := req.Validate(); err != nil {
+	if err := req.ValidateDeploy(); err != nil {
 		logger.Errorf(ctx, err, "invalid resource opts %+v", req)
 		return nil, err
 	}
+	if err := p.applyPolicy(ctx, nodename, req); err != nil {
+		logger.Errorf(ctx, err, "resource opts %+v violates quota policy", req)
+		return nil, err
+	}
+
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+	if err != nil {
+		logger.Error(ctx, err, "failed to get resource info of node")
+		return nil, err
+	}
+	capacityInfo, err := p.doGetNodeDeployCapacity(nodeResourceInfo, req)
+	if err != nil {
+		return nil, err
+	}
+	if capacityInfo.Capacity < deployCount {
+		return nil, errors.Wrapf(types.ErrInsufficientCapacity, "node %s can only deploy %d, need %d", nodename, capacityInfo.Capacity, deployCount)
+	}
+	if err := p.checkTenantQuota(ctx, req.TenantOf(), (&types.WorkloadResource{Volumes: req.Volumes}).Size()*int64(deployCount)); err != nil {
+		return nil, err
+	}
+
+	replicas, err := p.allocatePools(nodename, nodeResourceInfo, req.Volumes, deployCount, req.AntiAffinity, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	var enginesParams []*types.EngineParams
 	var workloadsResource []*types.WorkloadResource
 
-	for i := 0; i < deployCount; i++ {
+	for _, volumes := range replicas {
 		wrkRes := types.NewWorkloadResoure()
 		eParams := types.EngineParams{}
-		for _, vb := range req.Volumes {
-			vb1 := *vb
-			wrkRes.Volumes = append(wrkRes.Volumes, &vb1)
-			eParams.Volumes = append(eParams.Volumes, vb1.ToString())
+		for _, vb := range volumes {
+			wrkRes.Volumes = append(wrkRes.Volumes, vb)
+			eParams.Volumes = append(eParams.Volumes, vb.ToEngineString())
 		}
+		recordRoots(nodeResourceInfo, wrkRes)
+		wrkRes.App, wrkRes.Tenant = req.App, req.Tenant
 		enginesParams = append(enginesParams, &eParams)
 		workloadsResource = append(workloadsResource, wrkRes)
 	}
@@ -75,32 +102,63 @@ func (p Plugin) CalculateRealloc(
 	if err := originResource.Parse(resource); err != nil {
 		return nil, err
 	}
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+	if err != nil {
+		return nil, err
+	}
+	if req.Volumes, err = p.resolveReallocPools(nodename, nodeResourceInfo, originResource.Volumes, req); err != nil {
+		return nil, err
+	}
+	originVolumes, reqVolumes, err := moveVolumeBindings(originResource.Volumes, req.Volumes)
+	if err != nil {
+		return nil, err
+	}
+	app, tenant := req.App, req.Tenant
+	if app == "" {
+		app = originResource.App
+	}
+	if tenant == "" {
+		tenant = originResource.Tenant
+	}
 	req = &types.WorkloadResourceRequest{
-		Volumes: types.MergeVolumeBindings(req.Volumes, originResource.Volumes),
+		Volumes: types.MergeVolumeBindings(reqVolumes, originVolumes),
+		App:     app,
+		Tenant:  tenant,
 	}
 
-	if err := req.Validate(); err != nil {
+	// the quota policy is checked against the resulting workload, not the delta
+	if err := p.applyPolicy(ctx, nodename, req); err != nil {
 		logger.Errorf(ctx, err, "invalid resource opts %+v", litter.Sdump(req))
 		return nil, err
 	}
 
 	targetWorkloadResource := &types.WorkloadResource{
 		Volumes: req.Volumes,
+		App:     app,
+		Tenant:  tenant,
 	}
-	originResSet := map[[2]string]any{}
+	recordRoots(nodeResourceInfo, targetWorkloadResource)
+	originResSet := map[[2]string]*types.VolumeBinding{}
 	for _, vb := range originResource.Volumes {
-		originResSet[vb.GetMapKey()] = struct{}{}
+		originResSet[vb.GetMapKey()] = vb
 	}
 	engineParams := &types.EngineParams{
 		VolumeChanged: len(originResSet) != len(targetWorkloadResource.Volumes),
 	}
 	for _, vb := range targetWorkloadResource.Volumes {
-		if _, ok := originResSet[vb.GetMapKey()]; !ok {
+		// owner, group and mode are applied by engine, changing them is a volume change
+		if originVB, ok := originResSet[vb.GetMapKey()]; !ok || !originVB.SameAttrs(vb) {
 			engineParams.VolumeChanged = true
 		}
-		engineParams.Volumes = append(engineParams.Volumes, vb.ToString())
+		engineParams.Volumes = append(engineParams.Volumes, vb.ToEngineString())
 	}
 	deltaWorkloadResource := getDeltaWorkloadResourceArgs(originResource, targetWorkloadResource)
+	if err := p.checkReallocCapacity(nodename, nodeResourceInfo, deltaWorkloadResource); err != nil {
+		return nil, err
+	}
+	if err := p.checkTenantQuota(ctx, targetWorkloadResource.TenantOf(), deltaWorkloadResource.Size()); err != nil {
+		return nil, err
+	}
 	return &plugintypes.CalculateReallocResponse{
 		EngineParams:     engineParams.AsRawParams(),
 		DeltaResource:    deltaWorkloadResource.AsRawParams(),
@@ -120,18 +178,107 @@ func (p Plugin) CalculateRemap(
 	}, nil
 }
 
+// checkReallocCapacity checks whether the growth fits in the effective capacity of each root.
+// Deltas are netted per source, since a source takes disk no matter where it is mounted,
+// then the positive part is summed per root, space freed by shrinking bindings isn't counted.
+func (p Plugin) checkReallocCapacity(nodename string, nodeResourceInfo *types.NodeResourceInfo, deltaWorkloadResource *types.WorkloadResource) error {
+	available, err := nodeResourceInfo.GetAvailableResource(&p.hostdirConfig)
+	if err != nil {
+		return err
+	}
+
+	sources := []string{}
+	deltas := map[string]int64{}
+	bindings := map[string]*types.VolumeBinding{}
+	for _, vb := range deltaWorkloadResource.Volumes {
+		if _, ok := deltas[vb.Source]; !ok {
+			sources = append(sources, vb.Source)
+		}
+		deltas[vb.Source] += vb.SizeInBytes
+		if vb.SizeInBytes > 0 {
+			bindings[vb.Source] = vb
+		}
+	}
+	growth := map[string]int64{}
+	for _, source := range sources {
+		if deltas[source] <= 0 {
+			continue
+		}
+		root, ok := nodeResourceInfo.Capacity.Roots.Find(source)
+		if !ok {
+			return errors.Wrapf(types.ErrRootNotFound, "%s", source)
+		}
+		growth[root] += deltas[source]
+		if growth[root] > available[root] {
+			return &types.InsufficientCapacityError{
+				Node:      nodename,
+				Root:      root,
+				Binding:   bindings[source].ToString(),
+				Need:      growth[root],
+				Available: available[root],
+			}
+		}
+	}
+	return nil
+}
+
+// moveVolumeBindings moves the origin bindings replaced by the request, their allocation and attributes are kept.
+// The request is returned without moves, its sizes and attributes are merged as usual.
+// The delta of a move is the removal of the old binding and the addition of the new one.
+func moveVolumeBindings(origin, req types.VolumeBindings) (types.VolumeBindings, types.VolumeBindings, error) {
+	moves := map[[2]string]*types.VolumeBinding{}
+	reqVolumes := types.VolumeBindings{}
+	for _, vb := range req {
+		vb1 := vb.DeepCopy()
+		if vb.IsMove() {
+			if _, ok := moves[vb.MovedFrom()]; ok {
+				return nil, nil, errors.Wrapf(types.ErrInvalidVolumes, "binding is moved twice: %s", vb.ToString())
+			}
+			moves[vb.MovedFrom()] = vb
+			vb1.FromSource, vb1.FromDestination = "", ""
+		}
+		reqVolumes = append(reqVolumes, vb1)
+	}
+
+	originVolumes := types.VolumeBindings{}
+	for _, vb := range origin {
+		vb1 := vb.DeepCopy()
+		if move, ok := moves[vb.GetMapKey()]; ok {
+			vb1.Source, vb1.Destination = move.Source, move.Destination
+			delete(moves, vb.GetMapKey())
+		}
+		originVolumes = append(originVolumes, vb1)
+	}
+	for _, move := range moves {
+		return nil, nil, errors.Wrapf(types.ErrInvalidVolumes, "no binding to move: %s", move.ToString())
+	}
+	return originVolumes, reqVolumes, nil
+}
+
+// getDeltaWorkloadResourceArgs returns target - origin, removed volumes are included with negative size.
+// The delta keeps the app and tenant of target, so the tenant counts the growth once it is applied.
 func getDeltaWorkloadResourceArgs(originResource, targetWorkloadResource *types.WorkloadResource) *types.WorkloadResource {
 	ans := types.NewWorkloadResoure()
+	ans.App, ans.Tenant = targetWorkloadResource.App, targetWorkloadResource.Tenant
 	originSeen := map[[2]string]*types.VolumeBinding{}
 	for _, vb := range originResource.Volumes {
 		originSeen[vb.GetMapKey()] = vb
 	}
 	for _, vb := range targetWorkloadResource.Volumes {
-		newVB := *vb
+		newVB := vb.DeepCopy()
 		if originVB, ok := originSeen[vb.GetMapKey()]; ok {
 			newVB.SizeInBytes = vb.SizeInBytes - originVB.SizeInBytes
+			delete(originSeen, vb.GetMapKey())
+		}
+		ans.Volumes = append(ans.Volumes, newVB)
+	}
+	for _, vb := range originResource.Volumes {
+		if _, ok := originSeen[vb.GetMapKey()]; !ok {
+			continue
 		}
-		ans.Volumes = append(ans.Volumes, &newVB)
+		newVB := vb.DeepCopy()
+		newVB.SizeInBytes = -vb.SizeInBytes
+		ans.Volumes = append(ans.Volumes, newVB)
 	}
 	return ans
 }
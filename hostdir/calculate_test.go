@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/cockroachdb/errors"
 	"github.com/docker/go-units"
 	"github.com/mitchellh/mapstructure"
 	plugintypes "github.com/projecteru2/core/resource/plugins/types"
@@ -54,6 +55,20 @@ func TestCalculateDeploy(t *testing.T) {
 		fmt.Sprintf("/eru/img0:/dir0:%v", units.GiB))
 	assert.Equal(t, eParams[0].Volumes[1],
 		fmt.Sprintf("/eru/img1:/dir1:%v", units.GiB))
+
+	// insufficient capacity
+	req = plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/eru/img0:/dir0:1TiB"},
+	}
+	_, err = st.CalculateDeploy(ctx, node, 11, req)
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
+
+	// source outside of roots
+	req = plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/data/img0:/dir0:1GiB"},
+	}
+	_, err = st.CalculateDeploy(ctx, node, 1, req)
+	assert.ErrorIs(t, err, types.ErrRootNotFound)
 }
 
 func TestCalculateRealloc(t *testing.T) {
@@ -136,6 +151,136 @@ func TestCalculateRealloc(t *testing.T) {
 	]
 	`)))
 	assert.Truef(t, vbs.Equal(wResource.Volumes), "===\n%s\n===\n%s\n", litter.Sdump(vbs), litter.Sdump(&wResource.Volumes))
+
+	// 3. growth exceeds the capacity of root
+	req = plugintypes.WorkloadResourceRequest{
+		"volume-request": []string{"/eru/img1:/dir1:11TiB"},
+	}
+	_, err = st.CalculateRealloc(ctx, node, resource, req)
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
+
+	// 4. change owner and mode, in object form
+	req = plugintypes.WorkloadResourceRequest{
+		"volume-request": []map[string]any{
+			{"source": "/eru/img1", "destination": "/dir1", "uid": 1000, "gid": 1000, "mode": "0750"},
+		},
+	}
+	d, err = st.CalculateRealloc(ctx, node, resource, req)
+	assert.NoError(t, err)
+	eParam, wResource, dResource := parse(d)
+	assert.True(t, eParam.VolumeChanged)
+	assert.Contains(t, eParam.Volumes, "/eru/img1:/dir1:107374182400:uid=1000,gid=1000,mode=0750")
+	assert.Len(t, wResource.Volumes, 3)
+	assert.Equal(t, int64(0), dResource.Size())
+
+	// 5. move destination, the allocation is kept
+	req = plugintypes.WorkloadResourceRequest{
+		"volume-request": []string{"/eru/img0:/dir9::from_destination=/dir0"},
+	}
+	d, err = st.CalculateRealloc(ctx, node, resource, req)
+	assert.NoError(t, err)
+	eParam, wResource, dResource = parse(d)
+	assert.True(t, eParam.VolumeChanged)
+	vbs = &types.VolumeBindings{}
+	assert.NoError(t, vbs.UnmarshalJSON([]byte(`
+	[
+		"/eru/img0:/dir9:100GiB",
+		"/eru/img1:/dir1:100GiB",
+		"/eru/img2:/dir2:1TB"
+	]
+	`)))
+	assert.Truef(t, vbs.Equal(wResource.Volumes), "===\n%s\n===\n%s\n", litter.Sdump(vbs), litter.Sdump(&wResource.Volumes))
+	assert.Equal(t, int64(0), dResource.Size())
+	deltas := map[[2]string]int64{}
+	for _, vb := range dResource.Volumes {
+		deltas[vb.GetMapKey()] = vb.SizeInBytes
+	}
+	assert.Equal(t, int64(100*units.GiB), deltas[[2]string{"/eru/img0", "/dir9"}])
+	assert.Equal(t, int64(-100*units.GiB), deltas[[2]string{"/eru/img0", "/dir0"}])
+
+	// the delta moves the binding of node
+	_, err = st.SetNodeResourceUsage(ctx, node, nil, nil, []plugintypes.WorkloadResource{resource}, true, true)
+	assert.NoError(t, err)
+	_, err = st.SetNodeResourceUsage(ctx, node, nil, nil, []plugintypes.WorkloadResource{d.DeltaResource}, true, true)
+	assert.NoError(t, err)
+	nodeBindings, err := st.GetNodeBindings(ctx, node)
+	assert.NoError(t, err)
+	assert.True(t, vbs.Equal(nodeBindings))
+
+	// 6. replace source and grow
+	req = plugintypes.WorkloadResourceRequest{
+		"volume-request": []map[string]any{
+			{"source": "/eru/img5", "destination": "/dir1", "size": "10GiB", "from_source": "/eru/img1"},
+		},
+	}
+	d, err = st.CalculateRealloc(ctx, node, resource, req)
+	assert.NoError(t, err)
+	eParam, wResource, dResource = parse(d)
+	assert.True(t, eParam.VolumeChanged)
+	assert.Contains(t, eParam.Volumes, "/eru/img5:/dir1:118111600640")
+	assert.Len(t, wResource.Volumes, 3)
+	assert.Equal(t, int64(10*units.GiB), dResource.Size())
+
+	// 7. invalid moves
+	for _, volume := range []string{
+		"/eru/img0:/dir9::from_destination=/dir8",
+		"/eru/img0:/dir0::from_destination=/dir0",
+	} {
+		req = plugintypes.WorkloadResourceRequest{"volume-request": []string{volume}}
+		_, err = st.CalculateRealloc(ctx, node, resource, req)
+		assert.Error(t, err, volume)
+	}
+	_, err = st.CalculateDeploy(ctx, node, 1, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/eru/img0:/dir9::from_destination=/dir0"},
+	})
+	assert.ErrorIs(t, err, types.ErrInvalidVolumes)
+}
+
+func TestCalculateReallocCapacity(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	_, err := st.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{
+		"roots": []string{"/data:1TiB"},
+	}, nil)
+	assert.NoError(t, err)
+	resource := plugintypes.WorkloadResource{
+		"volumes": []string{"/data/img0:/dir0:500GiB", "/data/img1:/dir1:400GiB"},
+	}
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, []plugintypes.WorkloadResource{resource}, true, true)
+	assert.NoError(t, err)
+
+	// 124GiB available
+	_, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/data/img1:/dir1:100GiB"},
+	})
+	assert.NoError(t, err)
+
+	_, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/data/img1:/dir1:200GiB"},
+	})
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
+	e := &types.InsufficientCapacityError{}
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "/data", e.Root)
+	assert.Equal(t, "/data/img1:/dir1:214748364800", e.Binding)
+	assert.Equal(t, int64(200*units.GiB), e.Need)
+	assert.Equal(t, int64(124*units.GiB), e.Available)
+
+	// space freed by shrinking isn't counted
+	_, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/data/img0:/dir0:-200GiB", "/data/img1:/dir1:200GiB"},
+	})
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
+
+	// moving destination takes no space, replacing source does
+	_, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/data/img0:/dir9::from_destination=/dir0"},
+	})
+	assert.NoError(t, err)
+	_, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/data/img9:/dir0::from_source=/data/img0"},
+	})
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
 }
 
 func TestCalculateRemap(t *testing.T) {
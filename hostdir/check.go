@@ -0,0 +1,58 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/utils"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// CheckStore reads a key which never exists to tell whether the store is reachable
+func (p Plugin) CheckStore(ctx context.Context) error {
+	_, err := p.store.Get(ctx, strings.TrimSuffix(nodeResourceInfoKey, "/%s"))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// CheckRecords decodes all node and tenant records in store, the records failed to decode are reported rather than returned
+func (p Plugin) CheckRecords(ctx context.Context) (*types.RecordsCheck, error) {
+	nodes, err := p.store.List(ctx, strings.TrimSuffix(nodeResourceInfoKey, "%s"))
+	if err != nil {
+		return nil, err
+	}
+	tenants, err := p.store.List(ctx, strings.TrimSuffix(tenantUsageKey, "%s"))
+	if err != nil {
+		return nil, err
+	}
+	ans := &types.RecordsCheck{Nodes: len(nodes), Tenants: len(tenants), Invalid: map[string]string{}}
+	for key, value := range nodes {
+		nodename := utils.Tail(key)
+		nodeResourceInfo, version, err := types.DecodeNodeResourceInfo(value)
+		if err == nil {
+			err = nodeResourceInfo.Validate()
+		}
+		if err != nil {
+			ans.Invalid[fmt.Sprintf("node %s", nodename)] = err.Error()
+			continue
+		}
+		if version != types.SchemaVersion {
+			ans.Outdated = append(ans.Outdated, nodename)
+		}
+	}
+	for key, value := range tenants {
+		if err := json.Unmarshal(value, &types.TenantUsage{}); err != nil {
+			ans.Invalid[fmt.Sprintf("tenant %s", utils.Tail(key))] = err.Error()
+		}
+	}
+	sort.Strings(ans.Outdated)
+	return ans, nil
+}
@@ -0,0 +1,43 @@
+package hostdir
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestCheckRecords(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	st.hostdirConfig.Tenants = map[string]types.TenantConfig{"team-a": {Quota: "1TiB"}}
+	assert.NoError(t, st.CheckStore(ctx))
+
+	records, err := st.CheckRecords(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, &types.RecordsCheck{Invalid: map[string]string{}}, records)
+
+	_, err = st.AddNode(ctx, "node0", nil, nil)
+	assert.NoError(t, err)
+	deploy, err := st.CalculateDeploy(ctx, "node0", 1, map[string]any{"volumes": []string{"/eru/img0:/dir0:1GiB"}, "tenant": "team-a"})
+	assert.NoError(t, err)
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, deploy.WorkloadsResource, true, true)
+	assert.NoError(t, err)
+	// written before versioning
+	v0 := `{"capacity":{"roots":{"/data":{"size":107374182400}}},"usage":{"roots":{}}}`
+	assert.NoError(t, st.store.Put(ctx, fmt.Sprintf(nodeResourceInfoKey, "node1"), []byte(v0)))
+	assert.NoError(t, st.store.Put(ctx, fmt.Sprintf(nodeResourceInfoKey, "node2"), []byte(fmt.Sprintf(`{"schema_version":%d}`, types.SchemaVersion+1))))
+	assert.NoError(t, st.store.Put(ctx, fmt.Sprintf(tenantUsageKey, "team-b"), []byte("{")))
+
+	records, err = st.CheckRecords(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, records.Nodes)
+	assert.Equal(t, 2, records.Tenants)
+	assert.Equal(t, []string{"node1"}, records.Outdated)
+	assert.Len(t, records.Invalid, 2)
+	assert.Contains(t, records.Invalid, "node node2")
+	assert.Contains(t, records.Invalid, "tenant team-b")
+}
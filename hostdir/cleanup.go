@@ -0,0 +1,82 @@
+package hostdir
+
+import (
+	"context"
+	"time"
+
+	"github.com/projecteru2/core/log"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// GetCleanupTasks returns the pending cleanups of sources of removed bindings on the node
+func (p Plugin) GetCleanupTasks(ctx context.Context, nodename string) ([]*types.CleanupTask, error) {
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+	if err != nil {
+		return nil, err
+	}
+	return nodeResourceInfo.Cleanups, nil
+}
+
+// FinishCleanupTasks removes the cleanups of sources, it is called after the sources are cleaned up
+func (p Plugin) FinishCleanupTasks(ctx context.Context, nodename string, sources []string) error {
+	return p.retryOnConflict(ctx, nodename, func() error {
+		return p.finishCleanupTasks(ctx, nodename, sources)
+	})
+}
+
+func (p Plugin) finishCleanupTasks(ctx context.Context, nodename string, sources []string) error {
+	logger := log.WithFunc("resource.hostdir.FinishCleanupTasks").WithField("node", nodename)
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+	if err != nil {
+		return err
+	}
+	finished := map[string]bool{}
+	for _, source := range sources {
+		finished[source] = true
+	}
+	cleanups := []*types.CleanupTask{}
+	for _, task := range nodeResourceInfo.Cleanups {
+		if !finished[task.Source] {
+			cleanups = append(cleanups, task)
+		}
+	}
+	nodeResourceInfo.Cleanups = cleanups
+	if err := p.doSetNodeResourceInfo(ctx, nodename, nodeResourceInfo); err != nil {
+		logger.Error(ctx, err, "failed to finish cleanup tasks")
+		return err
+	}
+	return nil
+}
+
+// updateCleanupTasks records cleanups for the sources no longer bound,
+// cleanups of sources bound again are dropped, so a redeployed workload keeps its data.
+func (p Plugin) updateCleanupTasks(nodeResourceInfo *types.NodeResourceInfo, bindings types.VolumeBindings, now time.Time) {
+	bound := map[string]bool{}
+	for _, vb := range bindings {
+		bound[vb.Source] = true
+	}
+	cleanups := []*types.CleanupTask{}
+	pending := map[string]bool{}
+	for _, task := range nodeResourceInfo.Cleanups {
+		if !bound[task.Source] {
+			cleanups = append(cleanups, task)
+			pending[task.Source] = true
+		}
+	}
+	for _, vb := range nodeResourceInfo.Bindings {
+		if bound[vb.Source] || pending[vb.Source] {
+			continue
+		}
+		root, _ := nodeResourceInfo.Capacity.Roots.Find(vb.Source)
+		cleanups = append(cleanups, &types.CleanupTask{
+			Source:      vb.Source,
+			Destination: vb.Destination,
+			SizeInBytes: vb.SizeInBytes,
+			Retention:   p.hostdirConfig.RetentionOf(root, vb),
+			RemovedAt:   now,
+		})
+		pending[vb.Source] = true
+	}
+	nodeResourceInfo.Cleanups = cleanups
+}
@@ -0,0 +1,73 @@
+package hostdir
+
+import (
+	"context"
+	"testing"
+
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestCleanupTasks(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewPlugin(ctx, coretypes.Config{}, types.Config{
+		Retention: "archive-after-7d",
+		Roots: []types.RootConfig{
+			{Path: "/eru", Size: "10TiB", Retention: "delete"},
+			{Path: "/data", Size: "10TiB"},
+		},
+		Store: types.StoreConfig{Type: types.StoreMemory},
+	})
+	assert.NoError(t, err)
+	node := generateNodes(ctx, t, p, 1, 0)[0]
+
+	workloads := []plugintypes.WorkloadResource{
+		{"volumes": []string{"/eru/img0:/dir0:1GiB", "/data/img0:/dir1:1GiB"}},
+		{"volumes": []string{"/eru/img1:/dir0:1GiB:retention=keep"}},
+	}
+	_, err = p.SetNodeResourceUsage(ctx, node, nil, nil, workloads, true, true)
+	assert.NoError(t, err)
+	tasks, err := p.GetCleanupTasks(ctx, node)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 0)
+
+	// remove all workloads
+	_, err = p.SetNodeResourceUsage(ctx, node, nil, nil, workloads, true, false)
+	assert.NoError(t, err)
+	tasks, err = p.GetCleanupTasks(ctx, node)
+	assert.NoError(t, err)
+	retentions := map[string]string{}
+	for _, task := range tasks {
+		retentions[task.Source] = task.Retention
+	}
+	assert.Equal(t, map[string]string{
+		"/eru/img0":  "delete",
+		"/data/img0": "archive-after-7d",
+		"/eru/img1":  "keep",
+	}, retentions)
+
+	// redeploy on /eru/img1 keeps its data
+	_, err = p.SetNodeResourceUsage(ctx, node, nil, nil, workloads[1:], true, true)
+	assert.NoError(t, err)
+	tasks, err = p.GetCleanupTasks(ctx, node)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	assert.NoError(t, p.FinishCleanupTasks(ctx, node, []string{"/eru/img0"}))
+	tasks, err = p.GetCleanupTasks(ctx, node)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, "/data/img0", tasks[0].Source)
+
+	// cleanups are kept when eru-core sets the resource of node
+	info, err := p.GetNodeResourceInfo(ctx, node, nil)
+	assert.NoError(t, err)
+	_, err = p.SetNodeResourceInfo(ctx, node, info.Capacity, info.Usage)
+	assert.NoError(t, err)
+	tasks, err = p.GetCleanupTasks(ctx, node)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+}
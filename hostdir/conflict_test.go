@@ -0,0 +1,96 @@
+package hostdir
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// TestConcurrentUsage runs concurrent increments of usage against embedded storage, none of them may be lost
+func TestConcurrentUsage(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewPlugin(ctx, coretypes.Config{}, types.Config{
+		Roots: []types.RootConfig{{Path: "/data", Size: "1TiB"}},
+		// generous retries, so that no update gives up under contention
+		Store: types.StoreConfig{Type: types.StoreBolt, DataDir: filepath.Join(t.TempDir(), "data"), Retries: 1000},
+	})
+	assert.NoError(t, err)
+	defer p.Close()
+	_, err = p.AddNode(ctx, "node0", nil, nil)
+	assert.NoError(t, err)
+
+	workers, increments := 16, 25
+	wrk := plugintypes.WorkloadResource{"volumes": []string{"/data/img0:/dir0:1MiB"}}
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				_, err := p.SetNodeResourceUsage(ctx, "node0", nil, nil, []plugintypes.WorkloadResource{wrk}, true, true)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	roots, err := p.GetNodeRoots(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(workers*increments)*units.MiB, roots[0].Used)
+	bindings, err := p.GetNodeBindings(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Len(t, bindings, 1)
+	assert.Equal(t, int64(workers*increments)*units.MiB, bindings[0].SizeInBytes)
+}
+
+// conflictStore writes the record again before every compare-and-swap, as others would do
+type conflictStore struct {
+	store.Store
+}
+
+func (s conflictStore) CompareAndSwap(ctx context.Context, key string, value []byte, revision int64) error {
+	current, err := s.Store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.Put(ctx, key, current); err != nil {
+		return err
+	}
+	return s.Store.CompareAndSwap(ctx, key, value, revision)
+}
+
+func TestConflict(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	st.hostdirConfig.Store.Retries = 3
+	_, err := st.AddNode(ctx, "node0", nil, nil)
+	assert.NoError(t, err)
+
+	// node added by others meanwhile
+	_, err = st.AddNode(ctx, "node0", nil, nil)
+	assert.ErrorIs(t, err, coretypes.ErrNodeExists)
+
+	st.store = conflictStore{Store: st.store}
+	wrk := plugintypes.WorkloadResource{"volumes": []string{"/eru/img0:/dir0:1GiB"}}
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, []plugintypes.WorkloadResource{wrk}, true, true)
+	assert.ErrorIs(t, err, types.ErrConflict)
+	_, err = st.SetNodeResourceCapacity(ctx, "node0", nil, plugintypes.NodeResourceRequest{"roots": []string{"/eru:1TiB"}}, false, false)
+	assert.ErrorIs(t, err, types.ErrConflict)
+	_, err = st.FixNodeResource(ctx, "node0", []plugintypes.WorkloadResource{wrk})
+	assert.ErrorIs(t, err, types.ErrConflict)
+
+	// nothing is written by the failed updates
+	st.store = st.store.(conflictStore).Store
+	roots, err := st.GetNodeRoots(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), roots[0].Used)
+}
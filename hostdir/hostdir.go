@@ -2,11 +2,14 @@ package hostdir
 
 import (
 	"context"
-	"testing"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/projecteru2/core/log"
-	"github.com/projecteru2/core/store/etcdv3/meta"
 	coretypes "github.com/projecteru2/core/types"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
 )
 
 const (
@@ -14,30 +17,61 @@ const (
 	rate                = 8
 	nodeResourceInfoKey = "/resource/hostdir/%s"
 	priority            = -10000
+	defaultRetries      = 10
+	retryInterval       = 5 * time.Millisecond
 )
 
+// PluginName is the name of plugin in eru-core, the same as Plugin.Name
+const PluginName = name
+
 // Plugin
 type Plugin struct {
-	name   string
-	config coretypes.Config
-	store  meta.KV
+	name          string
+	config        coretypes.Config
+	hostdirConfig types.Config
+	store         store.Store
+	// core is the store of eru-core records, only set if there are policies of pods, the store of plugin is read if not
+	core    store.Store
+	journal journal
 }
 
-// NewPlugin .
-func NewPlugin(ctx context.Context, cfg coretypes.Config, t *testing.T) (*Plugin, error) {
-	if t == nil && len(cfg.Etcd.Machines) < 1 {
-		return nil, coretypes.ErrConfigInvaild
+// NewPlugin creates the store selected by hostdirCfg.Store
+func NewPlugin(ctx context.Context, cfg coretypes.Config, hostdirCfg types.Config) (*Plugin, error) {
+	if err := hostdirCfg.Validate(); err != nil {
+		return nil, err
 	}
 	var err error
-	plugin := &Plugin{name: name, config: cfg}
-	if plugin.store, err = meta.NewETCD(cfg.Etcd, t); err != nil {
+	plugin := &Plugin{name: name, config: cfg, hostdirConfig: hostdirCfg}
+	if plugin.store, err = store.New(ctx, cfg, hostdirCfg.Store); err != nil {
 		log.WithFunc("resource.hostdir.NewPlugin").Error(ctx, err)
 		return nil, err
 	}
+	if len(hostdirCfg.Policies.Pods) > 0 {
+		if plugin.core, err = newCoreStore(ctx, cfg, hostdirCfg, plugin.store); err != nil {
+			log.WithFunc("resource.hostdir.NewPlugin").Error(ctx, err)
+			_ = plugin.store.Close()
+			return nil, err
+		}
+	}
+	plugin.journal = plugin.newJournal()
 	return plugin, nil
 }
 
+// Close releases the stores
+func (p Plugin) Close() error {
+	err := p.store.Close()
+	if p.core != nil && p.core != p.store {
+		err = errors.CombineErrors(err, p.core.Close())
+	}
+	return err
+}
+
 // Name .
 func (p Plugin) Name() string {
 	return p.name
 }
+
+// Config returns the hostdir config of plugin
+func (p Plugin) Config() types.Config {
+	return p.hostdirConfig
+}
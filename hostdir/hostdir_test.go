@@ -7,6 +7,7 @@ import (
 
 	coretypes "github.com/projecteru2/core/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
 )
 
 func TestName(t *testing.T) {
@@ -21,7 +22,16 @@ func initHostdir(ctx context.Context, t *testing.T) *Plugin {
 		},
 	}
 
-	p, err := NewPlugin(ctx, config, t)
+	hostdirConfig := types.Config{
+		Overcommit: 1,
+		Roots: []types.RootConfig{
+			{Path: "/eru", Size: "10TiB"},
+		},
+		Store:   types.StoreConfig{Type: types.StoreMemory},
+		Journal: types.JournalConfig{Type: types.JournalStore},
+	}
+
+	p, err := NewPlugin(ctx, config, hostdirConfig)
 	assert.NoError(t, err)
 	return p
 }
@@ -32,6 +42,8 @@ func generateNodes(
 	names := []string{}
 	for i := startIdx; i < startIdx+nums; i++ {
 		name := fmt.Sprintf("test%v", i)
+		_, err := st.AddNode(ctx, name, nil, nil)
+		assert.NoError(t, err)
 		names = append(names, name)
 	}
 	return names
@@ -0,0 +1,326 @@
+package hostdir
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/log"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+const (
+	// journalSeqKey is the sequence of entries of store journal, entries are kept in a ring of slots by it
+	journalSeqKey   = "/resource/hostdir-journal/seq"
+	journalEntryKey = "/resource/hostdir-journal/entries/%d"
+	// maxJournalLine is the longest line of file journal, an entry holds two node records
+	maxJournalLine = 64 << 20
+	// journalChunk is the size of chunks to read the last line of file journal
+	journalChunk = 64 << 10
+)
+
+// journal keeps the audit entries of mutating calls
+type journal interface {
+	// append sets the ID of entry and keeps it
+	append(ctx context.Context, entry *types.JournalEntry) error
+	// query returns the entries matched by filter, ordered by ID
+	query(ctx context.Context, filter *types.JournalFilter) ([]*types.JournalEntry, error)
+	// get returns the entry of ID, types.ErrJournalNotFound if it isn't kept
+	get(ctx context.Context, id int64) (*types.JournalEntry, error)
+}
+
+func (p *Plugin) newJournal() journal {
+	switch p.hostdirConfig.Journal.Type {
+	case types.JournalStore:
+		maxEntries := p.hostdirConfig.Journal.MaxEntries
+		if maxEntries == 0 {
+			maxEntries = types.DefaultJournalEntries
+		}
+		return &storeJournal{store: p.store, maxEntries: int64(maxEntries), retry: p.retryRecordOnConflict}
+	case types.JournalFile:
+		return &fileJournal{path: p.hostdirConfig.Journal.Path}
+	default:
+		return nil
+	}
+}
+
+// storeJournal keeps the latest entries in the store, each entry takes the slot of its ID modulo max entries,
+// so the oldest entry is overwritten once the journal is full.
+type storeJournal struct {
+	store      store.Store
+	maxEntries int64
+	retry      func(ctx context.Context, record string, f func() error) error
+}
+
+func (j *storeJournal) append(ctx context.Context, entry *types.JournalEntry) error {
+	err := j.retry(ctx, "journal", func() error {
+		value, revision, err := j.store.GetRevision(ctx, journalSeqKey)
+		seq := int64(0)
+		switch {
+		case errors.Is(err, store.ErrKeyNotFound):
+		case err != nil:
+			return err
+		default:
+			if seq, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+				return errors.Wrapf(err, "invalid sequence of journal: %s", value)
+			}
+		}
+		entry.ID = seq + 1
+		return j.store.CompareAndSwap(ctx, journalSeqKey, []byte(strconv.FormatInt(entry.ID, 10)), revision)
+	})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return j.store.Put(ctx, fmt.Sprintf(journalEntryKey, entry.ID%j.maxEntries), data)
+}
+
+func (j *storeJournal) query(ctx context.Context, filter *types.JournalFilter) ([]*types.JournalEntry, error) {
+	resps, err := j.store.List(ctx, strings.TrimSuffix(journalEntryKey, "%d"))
+	if err != nil {
+		return nil, err
+	}
+	entries := []*types.JournalEntry{}
+	for key, value := range resps {
+		entry := &types.JournalEntry{}
+		if err := json.Unmarshal(value, entry); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode journal entry %s", key)
+		}
+		entries = append(entries, entry)
+	}
+	return filterJournal(entries, filter), nil
+}
+
+func (j *storeJournal) get(ctx context.Context, id int64) (*types.JournalEntry, error) {
+	value, err := j.store.Get(ctx, fmt.Sprintf(journalEntryKey, id%j.maxEntries))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil, errors.Wrapf(types.ErrJournalNotFound, "id: %d", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry := &types.JournalEntry{}
+	if err := json.Unmarshal(value, entry); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode journal entry %d", id)
+	}
+	// the slot is taken by a later entry once the journal is full
+	if entry.ID != id {
+		return nil, errors.Wrapf(types.ErrJournalNotFound, "id: %d", id)
+	}
+	return entry, nil
+}
+
+// fileJournal appends entries to a local file in JSON lines, IDs are the time of entries in nanoseconds,
+// or the one after the last ID of the file if it isn't later, so they are unique and increasing in the file.
+// Appends of processes sharing the file are serialized by flock. It isn't bounded, rotate it by logrotate or alike.
+type fileJournal struct {
+	path string
+}
+
+func (j *fileJournal) append(_ context.Context, entry *types.JournalEntry) error {
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	// the lock is released by closing the file
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return errors.Wrapf(err, "failed to lock journal %s", j.path)
+	}
+	last, err := lastJournalID(f)
+	if err != nil {
+		return err
+	}
+	entry.ID = entry.Time.UnixNano()
+	if entry.ID <= last {
+		entry.ID = last + 1
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// lastJournalID returns the ID of the last entry of file journal, 0 if empty.
+// The file is read backwards by chunks until the last line is complete.
+func lastJournalID(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := info.Size()
+	line := []byte{}
+	for end > 0 {
+		start := end - journalChunk
+		if start < 0 {
+			start = 0
+		}
+		chunk := make([]byte, end-start)
+		if _, err := f.ReadAt(chunk, start); err != nil {
+			return 0, err
+		}
+		line = append(chunk, line...)
+		end = start
+		trimmed := bytes.TrimRight(line, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			line = trimmed[i+1:]
+			break
+		}
+		if end == 0 {
+			line = trimmed
+		}
+	}
+	if len(bytes.TrimSpace(line)) == 0 {
+		return 0, nil
+	}
+	entry := struct {
+		ID int64 `json:"id"`
+	}{}
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return 0, errors.Wrapf(err, "failed to decode the last line of journal %s", f.Name())
+	}
+	return entry.ID, nil
+}
+
+func (j *fileJournal) query(_ context.Context, filter *types.JournalFilter) ([]*types.JournalEntry, error) {
+	entries, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+	return filterJournal(entries, filter), nil
+}
+
+func (j *fileJournal) get(_ context.Context, id int64) (*types.JournalEntry, error) {
+	entries, err := j.read()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return nil, errors.Wrapf(types.ErrJournalNotFound, "id: %d", id)
+}
+
+// read returns all entries of the file, in the order they are appended
+func (j *fileJournal) read() ([]*types.JournalEntry, error) {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return []*types.JournalEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []*types.JournalEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxJournalLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := &types.JournalEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode line %d of journal %s", line, j.path)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// filterJournal returns the entries matched by filter, ordered by ID, only the latest ones are kept by limit
+func filterJournal(entries []*types.JournalEntry, filter *types.JournalFilter) []*types.JournalEntry {
+	ans := []*types.JournalEntry{}
+	for _, entry := range entries {
+		if filter.Match(entry) {
+			ans = append(ans, entry)
+		}
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].ID < ans[j].ID })
+	if filter.Limit > 0 && len(ans) > filter.Limit {
+		ans = ans[len(ans)-filter.Limit:]
+	}
+	return ans
+}
+
+// journalKey is the context key of journalRecord
+type journalKey struct{}
+
+// journalRecord collects the change of node made in a mutating call, it is filled where the node is written
+type journalRecord struct {
+	written bool
+	before  []byte
+	after   []byte
+}
+
+// journaling returns true if the change of node is to be recorded in journal
+func journaling(ctx context.Context) bool {
+	_, ok := ctx.Value(journalKey{}).(*journalRecord)
+	return ok
+}
+
+// recordJournal records the change of node in the journal record of ctx, if any
+func recordJournal(ctx context.Context, before, after []byte) {
+	if record, ok := ctx.Value(journalKey{}).(*journalRecord); ok {
+		record.written, record.before, record.after = true, before, after
+	}
+}
+
+// journaled runs f of a mutating call, the change of node it makes is appended to the journal with input.
+// The journal is appended after the change, so a failure is logged rather than returned.
+func (p Plugin) journaled(ctx context.Context, command string, nodename string, input map[string]any, f func(ctx context.Context) error) error {
+	if p.journal == nil {
+		return f(ctx)
+	}
+	record := &journalRecord{}
+	if err := f(context.WithValue(ctx, journalKey{}, record)); err != nil || !record.written {
+		return err
+	}
+	logger := log.WithFunc("resource.hostdir.journaled").WithField("node", nodename).WithField("command", command)
+	data, err := json.Marshal(input)
+	if err != nil {
+		logger.Error(ctx, err, "failed to encode input")
+	}
+	entry := &types.JournalEntry{
+		Time:    time.Now().UTC(),
+		Command: command,
+		Node:    nodename,
+		Input:   data,
+		Before:  record.before,
+		After:   record.after,
+	}
+	if err := p.journal.append(ctx, entry); err != nil {
+		logger.Error(ctx, err, "failed to append journal")
+	}
+	return nil
+}
+
+// QueryJournal returns the entries of journal matched by filter, ordered by ID
+func (p Plugin) QueryJournal(ctx context.Context, filter *types.JournalFilter) ([]*types.JournalEntry, error) {
+	if p.journal == nil {
+		return nil, errors.Wrap(types.ErrInvalidConfig, "journal is disabled")
+	}
+	return p.journal.query(ctx, filter)
+}
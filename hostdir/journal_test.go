@@ -0,0 +1,245 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestJournal(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	start := time.Now()
+
+	_, err := st.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{"roots": []string{"/data:1TiB"}}, nil)
+	assert.NoError(t, err)
+	_, err = st.AddNode(ctx, "node1", nil, nil)
+	assert.NoError(t, err)
+	deploy, err := st.CalculateDeploy(ctx, "node0", 1, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:1GiB"}})
+	assert.NoError(t, err)
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, deploy.WorkloadsResource, true, true)
+	assert.NoError(t, err)
+	_, err = st.RemoveNode(ctx, "node1")
+	assert.NoError(t, err)
+
+	// failed calls change nothing, so they aren't recorded
+	_, err = st.AddNode(ctx, "node0", nil, nil)
+	assert.ErrorIs(t, err, coretypes.ErrNodeExists)
+	_, err = st.RemoveNode(ctx, "node2")
+	assert.NoError(t, err)
+
+	entries, err := st.QueryJournal(ctx, &types.JournalFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4)
+	for i, command := range []string{"AddNode", "AddNode", "SetNodeResourceUsage", "RemoveNode"} {
+		assert.Equal(t, int64(i+1), entries[i].ID)
+		assert.Equal(t, command, entries[i].Command)
+	}
+
+	// before and after are the node records around the change
+	entries, err = st.QueryJournal(ctx, &types.JournalFilter{Node: "node0"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "null", string(entries[0].Before))
+	assert.JSONEq(t, string(entries[0].After), string(entries[1].Before))
+	before, _, err := types.DecodeNodeResourceInfo(entries[1].Before)
+	assert.NoError(t, err)
+	after, _, err := types.DecodeNodeResourceInfo(entries[1].After)
+	assert.NoError(t, err)
+	assert.Len(t, before.Bindings, 0)
+	assert.Len(t, after.Bindings, 1)
+	assert.Contains(t, string(entries[1].Input), `"incr":true`)
+
+	entries, err = st.QueryJournal(ctx, &types.JournalFilter{Node: "node1"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.NotEqual(t, "null", string(entries[1].Before))
+	assert.Equal(t, "null", string(entries[1].After))
+
+	// by time range and limit
+	entries, err = st.QueryJournal(ctx, &types.JournalFilter{Since: start, Until: time.Now(), Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "RemoveNode", entries[0].Command)
+	entries, err = st.QueryJournal(ctx, &types.JournalFilter{Until: start})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 0)
+}
+
+func TestJournalBounded(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	st.hostdirConfig.Journal.MaxEntries = 3
+	st.journal = st.newJournal()
+
+	_, err := st.AddNode(ctx, "node0", nil, nil)
+	assert.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		_, err = st.SetNodeResourceCapacity(ctx, "node0", nil, plugintypes.NodeResourceRequest{"roots": []string{"/eru:1GiB"}}, true, true)
+		assert.NoError(t, err)
+	}
+	entries, err := st.QueryJournal(ctx, &types.JournalFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	for i, entry := range entries {
+		assert.Equal(t, int64(i+3), entry.ID)
+		assert.Equal(t, "SetNodeResourceCapacity", entry.Command)
+	}
+}
+
+func TestFileJournal(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	st.hostdirConfig.Journal = types.JournalConfig{Type: types.JournalFile, Path: filepath.Join(t.TempDir(), "journal.log")}
+	st.journal = st.newJournal()
+
+	entries, err := st.QueryJournal(ctx, &types.JournalFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 0)
+
+	_, err = st.AddNode(ctx, "node0", nil, nil)
+	assert.NoError(t, err)
+	// nothing to fix, so nothing is recorded
+	_, err = st.FixNodeResource(ctx, "node0", nil)
+	assert.NoError(t, err)
+	_, err = st.SetNodeResourceInfo(ctx, "node0", nil, nil)
+	assert.NoError(t, err)
+
+	entries, err = st.QueryJournal(ctx, &types.JournalFilter{Node: "node0"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	for i, command := range []string{"AddNode", "SetNodeResourceInfo"} {
+		assert.Equal(t, command, entries[i].Command)
+	}
+	assert.Less(t, entries[0].ID, entries[1].ID)
+
+	// the journal is disabled by default, then it can't be queried
+	st.hostdirConfig.Journal = types.JournalConfig{}
+	st.journal = st.newJournal()
+	_, err = st.QueryJournal(ctx, &types.JournalFilter{})
+	assert.ErrorIs(t, err, types.ErrInvalidConfig)
+}
+
+func TestFileJournalID(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "journal.log")
+	now := time.Now()
+	// the node record is larger than a chunk read for the last ID
+	record, err := json.Marshal(strings.Repeat("x", 2*journalChunk))
+	assert.NoError(t, err)
+
+	// entries of the same time, appended concurrently by journals sharing the file
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			j := &fileJournal{path: path}
+			assert.NoError(t, j.append(ctx, &types.JournalEntry{Time: now, Command: "AddNode", Node: "node0", After: record}))
+		}()
+	}
+	wg.Wait()
+	j := &fileJournal{path: path}
+	// an entry earlier than the last one still gets a later ID
+	assert.NoError(t, j.append(ctx, &types.JournalEntry{Time: now.Add(-time.Hour), Command: "RemoveNode", Node: "node0"}))
+
+	entries, err := j.read()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 21)
+	for i := 1; i < len(entries); i++ {
+		assert.Equal(t, entries[i-1].ID+1, entries[i].ID)
+	}
+	entry, err := j.get(ctx, entries[20].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "RemoveNode", entry.Command)
+}
+
+func TestRevertJournal(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	st.hostdirConfig.Tenants = map[string]types.TenantConfig{"team-a": {Quota: "1TiB"}}
+
+	_, err := st.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{"roots": []string{"/data:1TiB"}}, nil)
+	assert.NoError(t, err)
+	deploy, err := st.CalculateDeploy(ctx, "node0", 1, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:1GiB"}, "tenant": "team-a"})
+	assert.NoError(t, err)
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, deploy.WorkloadsResource, true, true)
+	assert.NoError(t, err)
+	entries, err := st.QueryJournal(ctx, &types.JournalFilter{Node: "node0"})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	added, used := entries[0], entries[1]
+
+	_, err = st.RevertJournal(ctx, 100, false)
+	assert.ErrorIs(t, err, types.ErrJournalNotFound)
+	// the node is changed after the entry
+	_, err = st.RevertJournal(ctx, added.ID, true)
+	assert.ErrorIs(t, err, types.ErrConflict)
+
+	// preview changes nothing
+	revert, err := st.RevertJournal(ctx, used.ID, true)
+	assert.NoError(t, err)
+	assert.False(t, revert.Applied)
+	assert.Equal(t, []*types.RecordChange{
+		{Item: "binding /data/img0:/dir0", From: "1GiB"},
+		{Item: "usage /data", From: "1GiB", To: "0B"},
+	}, revert.Changes)
+	info, err := st.doGetNodeResourceInfo(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Len(t, info.Bindings, 1)
+
+	revert, err = st.RevertJournal(ctx, used.ID, false)
+	assert.NoError(t, err)
+	assert.True(t, revert.Applied)
+	info, err = st.doGetNodeResourceInfo(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Len(t, info.Bindings, 0)
+	assert.Equal(t, int64(0), info.Usage.Roots["/data"].Size)
+	usage, err := st.getTenantUsage(ctx, "team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), usage.Size())
+	// only once
+	_, err = st.RevertJournal(ctx, used.ID, false)
+	assert.ErrorIs(t, err, types.ErrConflict)
+
+	// the revert is journaled, and reverting it restores the node again
+	entries, err = st.QueryJournal(ctx, &types.JournalFilter{Node: "node0", Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "RevertJournal", entries[0].Command)
+	_, err = st.RevertJournal(ctx, entries[0].ID, false)
+	assert.NoError(t, err)
+	info, err = st.doGetNodeResourceInfo(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Len(t, info.Bindings, 1)
+
+	// reverting the creation removes the node, reverting the removal adds it back
+	_, err = st.RemoveNode(ctx, "node0")
+	assert.NoError(t, err)
+	entries, err = st.QueryJournal(ctx, &types.JournalFilter{Node: "node0", Limit: 1})
+	assert.NoError(t, err)
+	_, err = st.RevertJournal(ctx, entries[0].ID, false)
+	assert.NoError(t, err)
+	info, err = st.doGetNodeResourceInfo(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Len(t, info.Bindings, 1)
+
+	_, err = st.AddNode(ctx, "node1", nil, nil)
+	assert.NoError(t, err)
+	entries, err = st.QueryJournal(ctx, &types.JournalFilter{Node: "node1"})
+	assert.NoError(t, err)
+	revert, err = st.RevertJournal(ctx, entries[0].ID, false)
+	assert.NoError(t, err)
+	assert.Contains(t, revert.Changes, &types.RecordChange{Item: "node", From: "exists"})
+	_, err = st.doGetNodeResourceInfo(ctx, "node1")
+	assert.ErrorIs(t, err, coretypes.ErrNodeNotExists)
+}
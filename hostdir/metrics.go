@@ -2,6 +2,8 @@ package hostdir
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/mitchellh/mapstructure"
 	plugintypes "github.com/projecteru2/core/resource/plugins/types"
@@ -11,27 +13,73 @@ import (
 func (p Plugin) GetMetricsDescription(context.Context) (*plugintypes.GetMetricsDescriptionResponse, error) {
 	resp := &plugintypes.GetMetricsDescriptionResponse{}
 	return resp, mapstructure.Decode([]map[string]any{
-		// {
-		// 	"name":   "hostdir_used",
-		// 	"help":   "node used host directories.",
-		// 	"type":   "gauge",
-		// 	"labels": []string{"podname", "nodename"},
-		// },
+		{
+			"name":   "hostdir_capacity",
+			"help":   "node raw capacity of hostdir root.",
+			"type":   "gauge",
+			"labels": []string{"podname", "nodename", "root"},
+		},
+		{
+			"name":   "hostdir_effective_capacity",
+			"help":   "node effective capacity of hostdir root, after overcommit and reserved headroom.",
+			"type":   "gauge",
+			"labels": []string{"podname", "nodename", "root"},
+		},
+		{
+			"name":   "hostdir_used",
+			"help":   "node used space of hostdir root.",
+			"type":   "gauge",
+			"labels": []string{"podname", "nodename", "root"},
+		},
 	}, resp)
 }
 
 // GetMetrics .
-func (p Plugin) GetMetrics(ctx context.Context, podname, nodename string) (*plugintypes.GetMetricsResponse, error) { //nolint
-	// safeNodename := strings.ReplaceAll(nodename, ".", "_")
-	metrics := []map[string]any{
-		// {
-		// 	"name":   "hostdir_used",
-		// 	"labels": []string{podname, nodename},
-		// 	"value":  fmt.Sprintf("%+v", 0),
-		// 	"key":    fmt.Sprintf("core.node.%s.hostdir.used", safeNodename),
-		// },
+func (p Plugin) GetMetrics(ctx context.Context, podname, nodename string) (*plugintypes.GetMetricsResponse, error) {
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+	if err != nil {
+		return nil, err
+	}
+	effective, err := nodeResourceInfo.GetEffectiveCapacity(&p.hostdirConfig)
+	if err != nil {
+		return nil, err
+	}
+	safeNodename := strings.ReplaceAll(nodename, ".", "_")
+	metrics := []map[string]any{}
+	for path, root := range nodeResourceInfo.Capacity.Roots {
+		safePath := metricKeyOfRoot(path)
+		metrics = append(metrics,
+			map[string]any{
+				"name":   "hostdir_capacity",
+				"labels": []string{podname, nodename, path},
+				"value":  fmt.Sprintf("%+v", root.Size),
+				"key":    fmt.Sprintf("core.node.%s.hostdir.%s.capacity", safeNodename, safePath),
+			},
+			map[string]any{
+				"name":   "hostdir_effective_capacity",
+				"labels": []string{podname, nodename, path},
+				"value":  fmt.Sprintf("%+v", effective[path]),
+				"key":    fmt.Sprintf("core.node.%s.hostdir.%s.effective_capacity", safeNodename, safePath),
+			},
+			map[string]any{
+				"name":   "hostdir_used",
+				"labels": []string{podname, nodename, path},
+				"value":  fmt.Sprintf("%+v", nodeResourceInfo.Usage.Roots[path].Size),
+				"key":    fmt.Sprintf("core.node.%s.hostdir.%s.used", safeNodename, safePath),
+			},
+		)
 	}
 
 	resp := &plugintypes.GetMetricsResponse{}
 	return resp, mapstructure.Decode(metrics, resp)
 }
+
+// metricKeyOfRoot returns the part of root in metric keys, separators of keys in path are replaced,
+// the root / is "_" so the key keeps its levels
+func metricKeyOfRoot(path string) string {
+	key := strings.NewReplacer("/", "_", ".", "_").Replace(strings.Trim(path, "/"))
+	if key == "" {
+		return "_"
+	}
+	return key
+}
@@ -0,0 +1,54 @@
+package hostdir
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/log"
+	"github.com/projecteru2/core/utils"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// MigrateNodes rewrites the records of old schema versions in the current one.
+// Records are migrated on read anyway, this is for upgrading them all at once. Nothing is written if dryRun.
+func (p Plugin) MigrateNodes(ctx context.Context, dryRun bool) ([]*types.NodeMigration, error) {
+	logger := log.WithFunc("resource.hostdir.MigrateNodes")
+	resps, err := p.store.List(ctx, strings.TrimSuffix(nodeResourceInfoKey, "%s"))
+	if err != nil {
+		return nil, err
+	}
+	ans := []*types.NodeMigration{}
+	for key, value := range resps {
+		nodename := utils.Tail(key)
+		nodeResourceInfo, version, err := types.DecodeNodeResourceInfo(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "node %s", nodename)
+		}
+		if version == types.SchemaVersion {
+			continue
+		}
+		if err := nodeResourceInfo.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "node %s", nodename)
+		}
+		ans = append(ans, &types.NodeMigration{Nodename: nodename, From: version, To: types.SchemaVersion})
+		if dryRun {
+			continue
+		}
+		// read again with revision, the record is migrated on read and written in the current version
+		if err := p.retryOnConflict(ctx, nodename, func() error {
+			nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+			if err != nil {
+				return err
+			}
+			return p.doSetNodeResourceInfo(ctx, nodename, nodeResourceInfo)
+		}); err != nil {
+			logger.Errorf(ctx, err, "failed to migrate node %s", nodename)
+			return nil, err
+		}
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Nodename < ans[j].Nodename })
+	return ans, nil
+}
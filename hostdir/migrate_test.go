@@ -0,0 +1,53 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestMigrateNodes(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+
+	_, err := st.AddNode(ctx, "node0", nil, nil)
+	assert.NoError(t, err)
+	// written before versioning
+	v0 := `{"capacity":{"roots":{"/data":{"size":107374182400}}},"usage":{"roots":{}}}`
+	assert.NoError(t, st.store.Put(ctx, fmt.Sprintf(nodeResourceInfoKey, "node1"), []byte(v0)))
+
+	// old records are migrated on read
+	roots, err := st.GetNodeRoots(ctx, "node1")
+	assert.NoError(t, err)
+	assert.Len(t, roots, 1)
+	assert.Equal(t, int64(0), roots[0].Used)
+
+	migrations, err := st.MigrateNodes(ctx, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.NodeMigration{{Nodename: "node1", From: 0, To: types.SchemaVersion}}, migrations)
+
+	migrations, err = st.MigrateNodes(ctx, false)
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 1)
+	data, err := st.store.Get(ctx, fmt.Sprintf(nodeResourceInfoKey, "node1"))
+	assert.NoError(t, err)
+	record := map[string]any{}
+	assert.NoError(t, json.Unmarshal(data, &record))
+	assert.EqualValues(t, types.SchemaVersion, record["schema_version"])
+
+	migrations, err = st.MigrateNodes(ctx, false)
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 0)
+
+	// records of a newer plugin are refused
+	assert.NoError(t, st.store.Put(ctx, fmt.Sprintf(nodeResourceInfoKey, "node2"), []byte(fmt.Sprintf(`{"schema_version":%d}`, types.SchemaVersion+1))))
+	_, err = st.GetNodeRoots(ctx, "node2")
+	assert.ErrorIs(t, err, types.ErrInvalidSchema)
+	_, err = st.MigrateNodes(ctx, true)
+	assert.ErrorIs(t, err, types.ErrInvalidSchema)
+}
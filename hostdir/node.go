@@ -2,43 +2,157 @@ package hostdir
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
 
+	"github.com/cockroachdb/errors"
 	enginetypes "github.com/projecteru2/core/engine/types"
+	"github.com/projecteru2/core/log"
 	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/projecteru2/core/utils"
+	"github.com/sanity-io/litter"
 
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
 	"github.com/yuyang0/resource-hostdir/hostdir/types"
 )
 
 // AddNode .
-func (p Plugin) AddNode(ctx context.Context, nodename string, resource plugintypes.NodeResourceRequest, info *enginetypes.Info) (*plugintypes.AddNodeResponse, error) { //nolint
-	capacity := &types.NodeResource{}
-	usage := &types.NodeResource{}
+func (p Plugin) AddNode(ctx context.Context, nodename string, resource plugintypes.NodeResourceRequest, info *enginetypes.Info) (resp *plugintypes.AddNodeResponse, err error) {
+	input := map[string]any{"resource": resource}
+	err = p.journaled(ctx, "AddNode", nodename, input, func(ctx context.Context) error {
+		resp, err = p.addNode(ctx, nodename, resource, info)
+		return err
+	})
+	return resp, err
+}
+
+func (p Plugin) addNode(ctx context.Context, nodename string, resource plugintypes.NodeResourceRequest, info *enginetypes.Info) (*plugintypes.AddNodeResponse, error) {
+	// try to get the node resource
+	var err error
+	if _, err = p.doGetNodeResourceInfo(ctx, nodename); err == nil {
+		return nil, coretypes.ErrNodeExists
+	}
+
+	if !errors.Is(err, coretypes.ErrNodeNotExists) {
+		log.WithFunc("resource.hostdir.AddNode").WithField("node", nodename).Error(ctx, err, "failed to get resource info of node")
+		return nil, err
+	}
+
+	req := &types.NodeResourceRequest{}
+	if err := req.Parse(resource); err != nil {
+		return nil, err
+	}
+
+	if len(req.Roots) == 0 && info != nil {
+		// extract NodeResource from Resources
+		if b, ok := info.Resources[p.Name()]; ok {
+			nodeRes := types.NewNodeResource()
+			if err := json.Unmarshal(b, nodeRes); err != nil {
+				return nil, err
+			}
+			req.Roots = nodeRes.Roots
+		}
+	}
+	if len(req.Roots) == 0 {
+		req.Roots = p.hostdirConfig.DefaultRoots()
+	}
+
+	nodeResourceInfo := &types.NodeResourceInfo{
+		Capacity: &types.NodeResource{Roots: req.Roots},
+	}
+	if err = p.doSetNodeResourceInfo(ctx, nodename, nodeResourceInfo); errors.Is(err, store.ErrConflict) {
+		// added by others meanwhile
+		return nil, coretypes.ErrNodeExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	return &plugintypes.AddNodeResponse{
-		Capacity: capacity.AsRawParams(),
-		Usage:    usage.AsRawParams(),
+		Capacity: nodeResourceInfo.Capacity.AsRawParams(),
+		Usage:    nodeResourceInfo.Usage.AsRawParams(),
 	}, nil
 }
 
 // RemoveNode .
-func (p Plugin) RemoveNode(ctx context.Context, nodename string) (*plugintypes.RemoveNodeResponse, error) { //nolint
-	return &plugintypes.RemoveNodeResponse{}, nil
+func (p Plugin) RemoveNode(ctx context.Context, nodename string) (*plugintypes.RemoveNodeResponse, error) {
+	err := p.journaled(ctx, "RemoveNode", nodename, nil, func(ctx context.Context) error {
+		key := fmt.Sprintf(nodeResourceInfoKey, nodename)
+		var before []byte
+		if journaling(ctx) {
+			value, err := p.store.Get(ctx, key)
+			if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+				return err
+			}
+			before = value
+		}
+		if err := p.store.Delete(ctx, key); err != nil {
+			return err
+		}
+		if before != nil {
+			recordJournal(ctx, before, nil)
+		}
+		return nil
+	})
+	if err == nil {
+		// the node is gone, so is its usage of tenants, the removal is retried by calling again
+		err = p.removeTenantsUsage(ctx, nodename)
+	}
+	if err != nil {
+		log.WithFunc("resource.hostdir.RemoveNode").WithField("node", nodename).Error(ctx, err, "failed to delete node")
+	}
+	return &plugintypes.RemoveNodeResponse{}, err
 }
 
 // GetNodesDeployCapacity returns available nodes and total capacity
-func (p Plugin) GetNodesDeployCapacity(ctx context.Context, nodenames []string, resource plugintypes.WorkloadResourceRequest) (*plugintypes.GetNodesDeployCapacityResponse, error) { //nolint
+func (p Plugin) GetNodesDeployCapacity(ctx context.Context, nodenames []string, resource plugintypes.WorkloadResourceRequest) (*plugintypes.GetNodesDeployCapacityResponse, error) {
+	logger := log.WithFunc("resource.hostdir.GetNodesDeployCapacity")
+	req := &types.WorkloadResourceRequest{}
+	if err := req.Parse(resource); err != nil {
+		return nil, err
+	}
+	if err := req.ValidateDeploy(); err != nil {
+		logger.Errorf(ctx, err, "invalid resource opts %+v", req)
+		return nil, err
+	}
+
+	nodesResourceInfos, err := p.doGetNodesResourceInfo(ctx, nodenames)
+	if err != nil {
+		return nil, err
+	}
+
 	nodesDeployCapacityMap := map[string]*plugintypes.NodeDeployCapacity{}
 	total := 0
-
-	for _, nodename := range nodenames {
-		count := 1000000
-		nodeDeployCapacity := &plugintypes.NodeDeployCapacity{
-			Weight:   1,
-			Capacity: count,
-			Usage:    0.001,
-			Rate:     0.0001,
+	var policyErr error
+	for nodename, nodeResourceInfo := range nodesResourceInfos {
+		// policies may differ by pod of node
+		nodeReq := req.DeepCopy()
+		if err := p.applyPolicy(ctx, nodename, nodeReq); err != nil {
+			logger.WithField("node", nodename).Warnf(ctx, "quota policy is violated: %s", err)
+			policyErr = err
+			continue
 		}
-		total += count
-		nodesDeployCapacityMap[nodename] = nodeDeployCapacity
+		nodeDeployCapacity, err := p.doGetNodeDeployCapacity(nodeResourceInfo, nodeReq)
+		if err != nil {
+			logger.WithField("node", nodename).Warnf(ctx, "failed to get deploy capacity: %s", err)
+			continue
+		}
+		if nodeDeployCapacity.Capacity > 0 {
+			nodesDeployCapacityMap[nodename] = nodeDeployCapacity
+			if total == math.MaxInt || nodeDeployCapacity.Capacity == math.MaxInt {
+				total = math.MaxInt
+			} else {
+				total += nodeDeployCapacity.Capacity
+			}
+		}
+	}
+	// tell why if no node is left for the policy
+	if len(nodesDeployCapacityMap) == 0 && policyErr != nil {
+		return nil, policyErr
 	}
 	return &plugintypes.GetNodesDeployCapacityResponse{
 		NodeDeployCapacityMap: nodesDeployCapacityMap,
@@ -47,47 +161,204 @@ func (p Plugin) GetNodesDeployCapacity(ctx context.Context, nodenames []string,
 }
 
 // SetNodeResourceCapacity sets the amount of total resource info
-func (p Plugin) SetNodeResourceCapacity(ctx context.Context, nodename string, resource plugintypes.NodeResource, resourceRequest plugintypes.NodeResourceRequest, delta bool, incr bool) (*plugintypes.SetNodeResourceCapacityResponse, error) { //nolint
-	before := &types.NodeResource{}
-	after := &types.NodeResource{}
+func (p Plugin) SetNodeResourceCapacity(ctx context.Context, nodename string, resource plugintypes.NodeResource, resourceRequest plugintypes.NodeResourceRequest, delta bool, incr bool) (resp *plugintypes.SetNodeResourceCapacityResponse, err error) {
+	input := map[string]any{"resource": resource, "resource_request": resourceRequest, "delta": delta, "incr": incr}
+	err = p.journaled(ctx, "SetNodeResourceCapacity", nodename, input, func(ctx context.Context) error {
+		return p.retryOnConflict(ctx, nodename, func() error {
+			resp, err = p.setNodeResourceCapacity(ctx, nodename, resource, resourceRequest, delta, incr)
+			return err
+		})
+	})
+	return resp, err
+}
+
+func (p Plugin) setNodeResourceCapacity(ctx context.Context, nodename string, resource plugintypes.NodeResource, resourceRequest plugintypes.NodeResourceRequest, delta bool, incr bool) (*plugintypes.SetNodeResourceCapacityResponse, error) {
+	logger := log.WithFunc("resource.hostdir.SetNodeResourceCapacity").WithField("node", nodename)
+	req, nodeResource, _, nodeResourceInfo, err := p.parseNodeResourceInfos(ctx, nodename, resource, resourceRequest, nil)
+	if err != nil {
+		return nil, err
+	}
+	origin := nodeResourceInfo.Capacity
+	before := origin.DeepCopy()
+
+	if req != nil {
+		nodeResource = &types.NodeResource{Roots: req.Roots}
+	}
+	after := origin.DeepCopy()
+	if nodeResource != nil {
+		for path, root := range nodeResource.Roots {
+			r, ok := after.Roots[path]
+			if !ok {
+				r = &types.Root{}
+				after.Roots[path] = r
+			}
+			switch {
+			case !delta:
+				r.Size = root.Size
+			case incr:
+				r.Size += root.Size
+			default:
+				r.Size -= root.Size
+			}
+			if root.Overcommit > 0 {
+				r.Overcommit = root.Overcommit
+			}
+			if root.Reserved != "" {
+				r.Reserved = root.Reserved
+			}
+			if root.Pool != "" {
+				r.Pool = root.Pool
+			}
+			if root.Domain != "" {
+				r.Domain = root.Domain
+			}
+			// remove roots with no space and no usage
+			if used, ok := nodeResourceInfo.Usage.Roots[path]; r.Size == 0 && (!ok || used.Size == 0) {
+				delete(after.Roots, path)
+				delete(nodeResourceInfo.Usage.Roots, path)
+			}
+		}
+	}
+	nodeResourceInfo.Capacity = after
+
+	if err := p.doSetNodeResourceInfo(ctx, nodename, nodeResourceInfo); err != nil {
+		logger.Errorf(ctx, err, "node resource info %+v", litter.Sdump(nodeResourceInfo))
+		return nil, err
+	}
+
 	return &plugintypes.SetNodeResourceCapacityResponse{
 		Before: before.AsRawParams(),
-		After:  after.AsRawParams(),
+		After:  nodeResourceInfo.Capacity.AsRawParams(),
 	}, nil
 }
 
 // GetNodeResourceInfo .
-func (p Plugin) GetNodeResourceInfo(ctx context.Context, nodename string, workloadsResource []plugintypes.WorkloadResource) (*plugintypes.GetNodeResourceInfoResponse, error) { //nolint
-	capacity := &types.NodeResource{}
-	usage := &types.NodeResource{}
+func (p Plugin) GetNodeResourceInfo(ctx context.Context, nodename string, workloadsResource []plugintypes.WorkloadResource) (*plugintypes.GetNodeResourceInfoResponse, error) {
+	nodeResourceInfo, _, diffs, err := p.getNodeResourceInfo(ctx, nodename, workloadsResource)
+	if err != nil {
+		return nil, err
+	}
+
 	return &plugintypes.GetNodeResourceInfoResponse{
-		Capacity: capacity.AsRawParams(),
-		Usage:    usage.AsRawParams(),
-		Diffs:    nil,
+		Capacity: nodeResourceInfo.Capacity.AsRawParams(),
+		Usage:    nodeResourceInfo.Usage.AsRawParams(),
+		Diffs:    diffs,
 	}, nil
 }
 
 // SetNodeResourceInfo .
-func (p Plugin) SetNodeResourceInfo(ctx context.Context, nodename string, capacity plugintypes.NodeResource, usage plugintypes.NodeResource) (*plugintypes.SetNodeResourceInfoResponse, error) { //nolint
-	return &plugintypes.SetNodeResourceInfoResponse{}, nil
+func (p Plugin) SetNodeResourceInfo(ctx context.Context, nodename string, capacity plugintypes.NodeResource, usage plugintypes.NodeResource) (*plugintypes.SetNodeResourceInfoResponse, error) {
+	capacityResource := types.NewNodeResource()
+	usageResource := types.NewNodeResource()
+	if err := capacityResource.Parse(capacity); err != nil {
+		return nil, err
+	}
+	if err := usageResource.Parse(usage); err != nil {
+		return nil, err
+	}
+	resourceInfo := &types.NodeResourceInfo{
+		Capacity: capacityResource,
+		Usage:    usageResource,
+	}
+	input := map[string]any{"capacity": capacity, "usage": usage}
+	return &plugintypes.SetNodeResourceInfoResponse{}, p.journaled(ctx, "SetNodeResourceInfo", nodename, input, func(ctx context.Context) error {
+		return p.retryOnConflict(ctx, nodename, func() error {
+			return p.setNodeResourceInfo(ctx, nodename, resourceInfo)
+		})
+	})
+}
+
+func (p Plugin) setNodeResourceInfo(ctx context.Context, nodename string, resourceInfo *types.NodeResourceInfo) error {
+	resourceInfo = resourceInfo.DeepCopy()
+	// bindings and cleanups are not part of eru resource, keep them
+	origin, err := p.doGetNodeResourceInfo(ctx, nodename)
+	switch {
+	case err == nil:
+		resourceInfo.Revision = origin.Revision
+		resourceInfo.Bindings = origin.Bindings
+		resourceInfo.Cleanups = origin.Cleanups
+	case !errors.Is(err, coretypes.ErrNodeNotExists):
+		return err
+	}
+	return p.doSetNodeResourceInfo(ctx, nodename, resourceInfo)
 }
 
 // SetNodeResourceUsage .
-func (p Plugin) SetNodeResourceUsage(ctx context.Context, nodename string, resource plugintypes.NodeResource, resourceRequest plugintypes.NodeResourceRequest, workloadsResource []plugintypes.WorkloadResource, delta bool, incr bool) (*plugintypes.SetNodeResourceUsageResponse, error) { //nolint
-	before := &types.NodeResource{}
-	after := &types.NodeResource{}
+// The usage of tenants is updated after the node, by the workloads.
+func (p Plugin) SetNodeResourceUsage(ctx context.Context, nodename string, resource plugintypes.NodeResource, resourceRequest plugintypes.NodeResourceRequest, workloadsResource []plugintypes.WorkloadResource, delta bool, incr bool) (resp *plugintypes.SetNodeResourceUsageResponse, err error) {
+	input := map[string]any{"resource": resource, "resource_request": resourceRequest, "workloads_resource": workloadsResource, "delta": delta, "incr": incr}
+	if err = p.journaled(ctx, "SetNodeResourceUsage", nodename, input, func(ctx context.Context) error {
+		return p.retryOnConflict(ctx, nodename, func() error {
+			resp, err = p.setNodeResourceUsage(ctx, nodename, resource, resourceRequest, workloadsResource, delta, incr)
+			return err
+		})
+	}); err != nil {
+		return nil, err
+	}
+	if resource == nil && resourceRequest == nil {
+		// the node is already updated, so a failure is logged rather than returned
+		if err := p.setTenantsUsage(ctx, nodename, workloadsResource, delta, incr); err != nil {
+			log.WithFunc("resource.hostdir.SetNodeResourceUsage").WithField("node", nodename).Error(ctx, err, "failed to update usage of tenants")
+		}
+	}
+	return resp, nil
+}
+
+func (p Plugin) setNodeResourceUsage(ctx context.Context, nodename string, resource plugintypes.NodeResource, resourceRequest plugintypes.NodeResourceRequest, workloadsResource []plugintypes.WorkloadResource, delta bool, incr bool) (*plugintypes.SetNodeResourceUsageResponse, error) {
+	logger := log.WithFunc("resource.hostdir.SetNodeResourceUsage").WithField("node", nodename)
+	req, nodeResource, wrksResource, nodeResourceInfo, err := p.parseNodeResourceInfos(ctx, nodename, resource, resourceRequest, workloadsResource)
+	if err != nil {
+		return nil, err
+	}
+	origin := nodeResourceInfo.Usage
+	before := origin.DeepCopy()
+
+	if nodeResourceInfo.Usage, err = p.calculateNodeResource(nodeResourceInfo, req, nodeResource, origin, wrksResource, delta, incr); err != nil {
+		return nil, err
+	}
+	if req == nil && nodeResource == nil {
+		bindings := calculateNodeBindings(nodeResourceInfo.Bindings, wrksResource, delta, incr)
+		p.updateCleanupTasks(nodeResourceInfo, bindings, time.Now())
+		nodeResourceInfo.Bindings = bindings
+	}
+
+	if err := p.doSetNodeResourceInfo(ctx, nodename, nodeResourceInfo); err != nil {
+		logger.Errorf(ctx, err, "node resource info %+v", litter.Sdump(nodeResourceInfo))
+		return nil, err
+	}
+
 	return &plugintypes.SetNodeResourceUsageResponse{
 		Before: before.AsRawParams(),
-		After:  after.AsRawParams(),
+		After:  nodeResourceInfo.Usage.AsRawParams(),
 	}, nil
 }
 
 // GetMostIdleNode .
-func (p Plugin) GetMostIdleNode(ctx context.Context, nodenames []string) (*plugintypes.GetMostIdleNodeResponse, error) { //nolint
+func (p Plugin) GetMostIdleNode(ctx context.Context, nodenames []string) (*plugintypes.GetMostIdleNodeResponse, error) {
 	var mostIdleNode string
-	if len(nodenames) > 0 {
-		mostIdleNode = nodenames[0]
+	var minIdle = math.MaxFloat64
+
+	nodesResourceInfo, err := p.doGetNodesResourceInfo(ctx, nodenames)
+	if err != nil {
+		return nil, err
+	}
+
+	for nodename, nodeResourceInfo := range nodesResourceInfo {
+		effective, err := nodeResourceInfo.GetEffectiveCapacity(&p.hostdirConfig)
+		if err != nil {
+			return nil, err
+		}
+		total := int64(0)
+		for _, size := range effective {
+			total += size
+		}
+		idle := utils.AdvancedDivide(float64(nodeResourceInfo.Usage.Roots.Size()), float64(total))
+		if idle < minIdle {
+			mostIdleNode = nodename
+			minIdle = idle
+		}
 	}
+
 	return &plugintypes.GetMostIdleNodeResponse{
 		Nodename: mostIdleNode,
 		Priority: priority,
@@ -95,12 +366,364 @@ func (p Plugin) GetMostIdleNode(ctx context.Context, nodenames []string) (*plugi
 }
 
 // FixNodeResource .
-func (p Plugin) FixNodeResource(ctx context.Context, nodename string, workloadsResource []plugintypes.WorkloadResource) (*plugintypes.GetNodeResourceInfoResponse, error) { //nolint
-	capacity := &types.NodeResource{}
-	usage := &types.NodeResource{}
+func (p Plugin) FixNodeResource(ctx context.Context, nodename string, workloadsResource []plugintypes.WorkloadResource) (resp *plugintypes.GetNodeResourceInfoResponse, err error) {
+	input := map[string]any{"workloads_resource": workloadsResource}
+	err = p.journaled(ctx, "FixNodeResource", nodename, input, func(ctx context.Context) error {
+		return p.retryOnConflict(ctx, nodename, func() error {
+			resp, err = p.fixNodeResource(ctx, nodename, workloadsResource)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the usage of tenants drifts if their updates by SetNodeResourceUsage failed, it is rebuilt by the workloads
+	diffs, err := p.fixTenantsUsage(ctx, nodename, workloadsResource)
+	resp.Diffs = append(resp.Diffs, diffs...)
+	return resp, err
+}
+
+func (p Plugin) fixNodeResource(ctx context.Context, nodename string, workloadsResource []plugintypes.WorkloadResource) (*plugintypes.GetNodeResourceInfoResponse, error) {
+	nodeResourceInfo, actuallyWorkloadsUsage, diffs, err := p.getNodeResourceInfo(ctx, nodename, workloadsResource)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := types.VolumeBindings{}
+	for _, workloadResource := range workloadsResource {
+		wr := &types.WorkloadResource{}
+		if err := wr.Parse(workloadResource); err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, wr.Volumes...)
+	}
+	if !bindings.Equal(nodeResourceInfo.Bindings) {
+		diffs = append(diffs, fmt.Sprintf("node.Bindings != workload.Volumes: %d != %d", len(nodeResourceInfo.Bindings), len(bindings)))
+	}
+
+	if len(diffs) != 0 {
+		nodeResourceInfo.Usage = actuallyWorkloadsUsage
+		p.updateCleanupTasks(nodeResourceInfo, bindings, time.Now())
+		nodeResourceInfo.Bindings = bindings
+		err = p.doSetNodeResourceInfo(ctx, nodename, nodeResourceInfo)
+		if errors.Is(err, store.ErrConflict) {
+			return nil, err
+		}
+		if err != nil {
+			log.WithFunc("resource.hostdir.FixNodeResource").Error(ctx, err)
+			diffs = append(diffs, err.Error())
+		}
+	}
+
 	return &plugintypes.GetNodeResourceInfoResponse{
-		Capacity: capacity.AsRawParams(),
-		Usage:    usage.AsRawParams(),
-		Diffs:    nil,
+		Capacity: nodeResourceInfo.Capacity.AsRawParams(),
+		Usage:    nodeResourceInfo.Usage.AsRawParams(),
+		Diffs:    diffs,
 	}, nil
 }
+
+// GetNodeBindings returns the volume bindings of workloads on the node
+func (p Plugin) GetNodeBindings(ctx context.Context, nodename string) (types.VolumeBindings, error) {
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+	if err != nil {
+		return nil, err
+	}
+	return nodeResourceInfo.Bindings, nil
+}
+
+func (p Plugin) getNodeResourceInfo(ctx context.Context, nodename string, workloadsResource []plugintypes.WorkloadResource) (*types.NodeResourceInfo, *types.NodeResource, []string, error) {
+	logger := log.WithFunc("resource.hostdir.getNodeResourceInfo").WithField("node", nodename)
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+	if err != nil {
+		logger.Error(ctx, err)
+		return nil, nil, nil, err
+	}
+
+	actuallyWorkloadsUsage := types.NewNodeResource()
+	for path := range nodeResourceInfo.Capacity.Roots {
+		actuallyWorkloadsUsage.Roots[path] = &types.Root{}
+	}
+	for _, workloadResource := range workloadsResource {
+		workloadUsage := &types.WorkloadResource{}
+		if err := workloadUsage.Parse(workloadResource); err != nil {
+			logger.Error(ctx, err)
+			return nil, nil, nil, err
+		}
+		usage, err := p.workloadUsage(nodeResourceInfo, workloadUsage)
+		if err != nil {
+			logger.Error(ctx, err)
+			return nil, nil, nil, err
+		}
+		actuallyWorkloadsUsage.Add(usage)
+	}
+
+	diffs := []string{}
+	for path, root := range actuallyWorkloadsUsage.Roots {
+		used := int64(0)
+		if r, ok := nodeResourceInfo.Usage.Roots[path]; ok {
+			used = r.Size
+		}
+		if used != root.Size {
+			diffs = append(diffs, fmt.Sprintf("node.Roots[%s] != sum(workload.Volumes under %s): %d != %d", path, path, used, root.Size))
+		}
+	}
+
+	return nodeResourceInfo, actuallyWorkloadsUsage, diffs, nil
+}
+
+// doGetNodeResourceInfo reads the node record with its revision, so that it can be written back by compare-and-swap
+func (p Plugin) doGetNodeResourceInfo(ctx context.Context, nodename string) (*types.NodeResourceInfo, error) {
+	value, revision, err := p.store.GetRevision(ctx, fmt.Sprintf(nodeResourceInfoKey, nodename))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil, errors.Wrap(coretypes.ErrNodeNotExists, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	nodeResourceInfo, _, err := types.DecodeNodeResourceInfo(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "node %s", nodename)
+	}
+	if err := nodeResourceInfo.Validate(); err != nil {
+		return nil, err
+	}
+	nodeResourceInfo.Revision = revision
+	return nodeResourceInfo, nil
+}
+
+func (p Plugin) doGetNodesResourceInfo(ctx context.Context, nodenames []string) (map[string]*types.NodeResourceInfo, error) {
+	keys := []string{}
+	for _, nodename := range nodenames {
+		keys = append(keys, fmt.Sprintf(nodeResourceInfoKey, nodename))
+	}
+	resps, err := p.store.GetMulti(ctx, keys)
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return nil, errors.Wrap(coretypes.ErrNodeNotExists, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]*types.NodeResourceInfo{}
+
+	for key, value := range resps {
+		r, _, err := types.DecodeNodeResourceInfo(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "node %s", utils.Tail(key))
+		}
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		result[utils.Tail(key)] = r
+	}
+	return result, nil
+}
+
+// doSetNodeResourceInfo writes the node record by compare-and-swap against the revision it is read at,
+// records not read from the store have revision 0, they are only written if the node doesn't exist.
+// store.ErrConflict is returned if the record is changed by others, see retryOnConflict.
+func (p Plugin) doSetNodeResourceInfo(ctx context.Context, nodename string, resourceInfo *types.NodeResourceInfo) error {
+	if err := resourceInfo.Validate(); err != nil {
+		return err
+	}
+	resourceInfo.SchemaVersion = types.SchemaVersion
+
+	data, err := json.Marshal(resourceInfo)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf(nodeResourceInfoKey, nodename)
+	var before []byte
+	if journaling(ctx) {
+		// the record replaced is kept in journal, it must be of the revision, otherwise the swap fails anyway
+		value, revision, err := p.store.GetRevision(ctx, key)
+		if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+			return err
+		}
+		if revision != resourceInfo.Revision {
+			return store.ErrConflict
+		}
+		before = value
+	}
+	if err := p.store.CompareAndSwap(ctx, key, data, resourceInfo.Revision); err != nil {
+		return err
+	}
+	recordJournal(ctx, before, data)
+	return nil
+}
+
+// retryOnConflict calls f again when the node record is changed by others between its read and write in f,
+// it gives up with types.ErrConflict after the retries of store config.
+func (p Plugin) retryOnConflict(ctx context.Context, nodename string, f func() error) error {
+	return p.retryRecordOnConflict(ctx, "node "+nodename, f)
+}
+
+// retryRecordOnConflict is retryOnConflict of any record in store, record names it in logs and errors, e.g. tenant foo
+func (p Plugin) retryRecordOnConflict(ctx context.Context, record string, f func() error) error {
+	logger := log.WithFunc("resource.hostdir.retryOnConflict").WithField("record", record)
+	retries := p.hostdirConfig.Store.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+	for i := 0; ; i++ {
+		err := f()
+		if !errors.Is(err, store.ErrConflict) {
+			return err
+		}
+		if i >= retries {
+			return errors.Wrapf(types.ErrConflict, "%s is changed by others, gave up after %d retries", record, retries)
+		}
+		logger.Debugf(ctx, "record is changed by others, retry %d", i+1)
+		// random backoff, so that the updates racing with each other are spread
+		backoff := time.Duration(rand.Int63n(int64(retryInterval << utils.Min(i, 5)))) //nolint:gosec
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// doGetNodeDeployCapacity calculates how many workloads can be deployed on the node, based on the effective capacity of roots
+func (p Plugin) doGetNodeDeployCapacity(nodeResourceInfo *types.NodeResourceInfo, req *types.WorkloadResourceRequest) (*plugintypes.NodeDeployCapacity, error) {
+	bindings, poolNeed := splitPoolBindings(req.Volumes)
+	need, err := nodeResourceInfo.Capacity.Roots.Bucket(bindings)
+	if err != nil {
+		return nil, err
+	}
+	effective, err := nodeResourceInfo.GetEffectiveCapacity(&p.hostdirConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	capacityInfo := &plugintypes.NodeDeployCapacity{
+		Weight: 1,
+	}
+	available := map[string]int64{}
+	totalEffective, totalUsed, totalNeed := int64(0), int64(0), int64(0)
+	for path, size := range effective {
+		used := nodeResourceInfo.Usage.Roots[path].Size
+		available[path] = size - used
+		totalEffective += size
+		totalUsed += used
+		if need[path] > 0 {
+			totalNeed += need[path]
+		}
+	}
+	for _, size := range poolNeed {
+		totalNeed += size
+	}
+	capacityInfo.Capacity = p.deployable(nodeResourceInfo.Capacity.Roots, available, need, poolNeed, p.usedDomains(nodeResourceInfo, req.AntiAffinity, nil))
+	capacityInfo.Usage = utils.AdvancedDivide(float64(totalUsed), float64(totalEffective))
+	capacityInfo.Rate = utils.AdvancedDivide(float64(totalNeed), float64(totalEffective))
+	return capacityInfo, nil
+}
+
+// workloadUsage converts workload resource to the usage of roots
+func (p Plugin) workloadUsage(nodeResourceInfo *types.NodeResourceInfo, workloadResource *types.WorkloadResource) (*types.NodeResource, error) {
+	sizes, err := nodeResourceInfo.Capacity.Roots.Bucket(workloadResource.Volumes)
+	if err != nil {
+		return nil, err
+	}
+	ans := types.NewNodeResource()
+	for path, size := range sizes {
+		ans.Roots[path] = &types.Root{Size: size}
+	}
+	return ans, nil
+}
+
+// calculateNodeResource priority: node resource request > node resource > workload resource args list
+func (p Plugin) calculateNodeResource(nodeResourceInfo *types.NodeResourceInfo, req *types.NodeResourceRequest, nodeResource *types.NodeResource, origin *types.NodeResource, workloadsResource []*types.WorkloadResource, delta bool, incr bool) (*types.NodeResource, error) {
+	var resp *types.NodeResource
+	if origin == nil || !delta { // no delta means node resource rewrite with whole new data
+		resp = types.NewNodeResource()
+		// if delta is false, the resource is written as a whole,
+		// incr must be true, otherwise negative values will be set.
+		incr = true
+	} else {
+		resp = origin.DeepCopy()
+	}
+
+	if req != nil {
+		nodeResource = &types.NodeResource{Roots: req.Roots}
+	}
+
+	if nodeResource != nil {
+		if incr {
+			resp.Add(nodeResource)
+		} else {
+			resp.Sub(nodeResource)
+		}
+		return resp, nil
+	}
+
+	for _, workloadResource := range workloadsResource {
+		usage, err := p.workloadUsage(nodeResourceInfo, workloadResource)
+		if err != nil {
+			return nil, err
+		}
+		if incr {
+			resp.Add(usage)
+		} else {
+			resp.Sub(usage)
+		}
+	}
+	return resp, nil
+}
+
+// calculateNodeBindings applies workloads to bindings, no delta means the bindings are rewritten
+func calculateNodeBindings(bindings types.VolumeBindings, workloadsResource []*types.WorkloadResource, delta bool, incr bool) types.VolumeBindings {
+	if !delta {
+		bindings, incr = nil, true
+	}
+	for _, workloadResource := range workloadsResource {
+		bindings = types.ApplyVolumeBindings(bindings, workloadResource.Volumes, incr)
+	}
+	return bindings
+}
+
+func (p Plugin) parseNodeResourceInfos(
+	ctx context.Context, nodename string,
+	resource plugintypes.NodeResource,
+	resourceRequest plugintypes.NodeResourceRequest,
+	workloadsResource []plugintypes.WorkloadResource,
+) (
+	*types.NodeResourceRequest,
+	*types.NodeResource,
+	[]*types.WorkloadResource,
+	*types.NodeResourceInfo,
+	error,
+) {
+	var req *types.NodeResourceRequest
+	var nodeResource *types.NodeResource
+	wrksResource := []*types.WorkloadResource{}
+
+	if resourceRequest != nil {
+		req = &types.NodeResourceRequest{}
+		if err := req.Parse(resourceRequest); err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+
+	if resource != nil {
+		nodeResource = types.NewNodeResource()
+		if err := nodeResource.Parse(resource); err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+
+	for _, workloadResource := range workloadsResource {
+		wrkResource := &types.WorkloadResource{}
+		if err := wrkResource.Parse(workloadResource); err != nil {
+			return nil, nil, nil, nil, err
+		}
+		wrksResource = append(wrksResource, wrkResource)
+	}
+
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodename)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return req, nodeResource, wrksResource, nodeResourceInfo, nil
+}
@@ -0,0 +1,190 @@
+package hostdir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestAddNode(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+
+	// roots from config
+	r, err := st.AddNode(ctx, "node0", nil, nil)
+	assert.NoError(t, err)
+	capacity := types.NewNodeResource()
+	assert.NoError(t, capacity.Parse(r.Capacity))
+	assert.Equal(t, int64(10*units.TiB), capacity.Roots["/eru"].Size)
+
+	// node already exists
+	_, err = st.AddNode(ctx, "node0", nil, nil)
+	assert.ErrorIs(t, err, coretypes.ErrNodeExists)
+
+	// roots from request
+	r, err = st.AddNode(ctx, "node1", plugintypes.NodeResourceRequest{
+		"roots": []string{"/data0:1TiB:1.5:10%", "/data1:100GiB"},
+	}, nil)
+	assert.NoError(t, err)
+	capacity = types.NewNodeResource()
+	assert.NoError(t, capacity.Parse(r.Capacity))
+	assert.Len(t, capacity.Roots, 2)
+	assert.Equal(t, 1.5, capacity.Roots["/data0"].Overcommit)
+	assert.Equal(t, "10%", capacity.Roots["/data0"].Reserved)
+
+	// invalid root
+	_, err = st.AddNode(ctx, "node2", plugintypes.NodeResourceRequest{
+		"roots": []string{"data:1TiB"},
+	}, nil)
+	assert.ErrorIs(t, err, types.ErrInvalidRoot)
+}
+
+func TestGetNodesDeployCapacity(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+
+	_, err := st.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{
+		"roots": []string{"/data:100GiB"},
+	}, nil)
+	assert.NoError(t, err)
+	// overcommit 2 and 10GiB reserved: effective capacity is 190GiB
+	_, err = st.AddNode(ctx, "node1", plugintypes.NodeResourceRequest{
+		"roots": []string{"/data:100GiB:2:10GiB"},
+	}, nil)
+	assert.NoError(t, err)
+	// 10% reserved: effective capacity is 90GiB
+	_, err = st.AddNode(ctx, "node2", plugintypes.NodeResourceRequest{
+		"roots": []string{"/data:100GiB::10%"},
+	}, nil)
+	assert.NoError(t, err)
+
+	req := plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/data/img0:/dir0:10GiB"},
+	}
+	r, err := st.GetNodesDeployCapacity(ctx, []string{"node0", "node1", "node2"}, req)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, r.NodeDeployCapacityMap["node0"].Capacity)
+	assert.Equal(t, 19, r.NodeDeployCapacityMap["node1"].Capacity)
+	assert.Equal(t, 9, r.NodeDeployCapacityMap["node2"].Capacity)
+	assert.Equal(t, 38, r.Total)
+
+	// source outside of roots
+	req = plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/eru/img0:/dir0:10GiB"},
+	}
+	r, err = st.GetNodesDeployCapacity(ctx, []string{"node0"}, req)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, r.Total)
+}
+
+func TestSetNodeResourceUsage(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	nodes := generateNodes(ctx, t, st, 1, 0)
+	node := nodes[0]
+
+	workloadsResource := []plugintypes.WorkloadResource{
+		{"volumes": []string{"/eru/img0:/dir0:100GiB", "/eru/img1:/dir1:200GiB"}},
+		{"volumes": []string{"/eru/img2:/dir0:100GiB"}},
+	}
+	r, err := st.SetNodeResourceUsage(ctx, node, nil, nil, workloadsResource, true, true)
+	assert.NoError(t, err)
+	usage := types.NewNodeResource()
+	assert.NoError(t, usage.Parse(r.After))
+	assert.Equal(t, int64(400*units.GiB), usage.Roots["/eru"].Size)
+
+	info, err := st.GetNodeResourceInfo(ctx, node, workloadsResource)
+	assert.NoError(t, err)
+	assert.Len(t, info.Diffs, 0)
+
+	r, err = st.SetNodeResourceUsage(ctx, node, nil, nil, workloadsResource[1:], true, false)
+	assert.NoError(t, err)
+	usage = types.NewNodeResource()
+	assert.NoError(t, usage.Parse(r.After))
+	assert.Equal(t, int64(300*units.GiB), usage.Roots["/eru"].Size)
+
+	info, err = st.GetNodeResourceInfo(ctx, node, workloadsResource)
+	assert.NoError(t, err)
+	assert.Len(t, info.Diffs, 1)
+
+	info, err = st.FixNodeResource(ctx, node, workloadsResource)
+	assert.NoError(t, err)
+	usage = types.NewNodeResource()
+	assert.NoError(t, usage.Parse(info.Usage))
+	assert.Equal(t, int64(400*units.GiB), usage.Roots["/eru"].Size)
+
+	// bindings are fixed as well
+	bindings, err := st.GetNodeBindings(ctx, node)
+	assert.NoError(t, err)
+	assert.Len(t, bindings, 3)
+}
+
+func TestSetNodeResourceCapacity(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	nodes := generateNodes(ctx, t, st, 1, 0)
+	node := nodes[0]
+
+	r, err := st.SetNodeResourceCapacity(ctx, node, nil, plugintypes.NodeResourceRequest{
+		"roots": []string{"/eru:1TiB:1.2", "/data:1TiB"},
+	}, true, true)
+	assert.NoError(t, err)
+	after := types.NewNodeResource()
+	assert.NoError(t, after.Parse(r.After))
+	assert.Equal(t, int64(11*units.TiB), after.Roots["/eru"].Size)
+	assert.Equal(t, 1.2, after.Roots["/eru"].Overcommit)
+	assert.Equal(t, int64(units.TiB), after.Roots["/data"].Size)
+
+	r, err = st.SetNodeResourceCapacity(ctx, node, nil, plugintypes.NodeResourceRequest{
+		"roots": []string{"/data:1TiB"},
+	}, true, false)
+	assert.NoError(t, err)
+	after = types.NewNodeResource()
+	assert.NoError(t, after.Parse(r.After))
+	assert.NotContains(t, after.Roots, "/data")
+
+	r, err = st.SetNodeResourceCapacity(ctx, node, nil, plugintypes.NodeResourceRequest{
+		"roots": []string{"/eru:2TiB"},
+	}, false, true)
+	assert.NoError(t, err)
+	after = types.NewNodeResource()
+	assert.NoError(t, after.Parse(r.After))
+	assert.Equal(t, int64(2*units.TiB), after.Roots["/eru"].Size)
+}
+
+func TestGetMetrics(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	_, err := st.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{
+		"roots": []string{"/data:100GiB:2:10GiB"},
+	}, nil)
+	assert.NoError(t, err)
+
+	r, err := st.GetMetrics(ctx, "pod", "node0")
+	assert.NoError(t, err)
+	values := map[string]string{}
+	for _, m := range *r {
+		values[m.Name] = m.Value
+	}
+	assert.Equal(t, "107374182400", values["hostdir_capacity"])
+	assert.Equal(t, "204010946560", values["hostdir_effective_capacity"])
+	assert.Equal(t, "0", values["hostdir_used"])
+
+	// separators in roots don't break the levels of keys
+	_, err = st.AddNode(ctx, "node1", plugintypes.NodeResourceRequest{"roots": []string{"/:100GiB", "/data/v1.0:100GiB"}}, nil)
+	assert.NoError(t, err)
+	r, err = st.GetMetrics(ctx, "pod", "node1")
+	assert.NoError(t, err)
+	keys := []string{}
+	for _, m := range *r {
+		if m.Name == "hostdir_capacity" {
+			keys = append(keys, m.Key)
+		}
+	}
+	assert.ElementsMatch(t, []string{"core.node.node1.hostdir._.capacity", "core.node.node1.hostdir.data_v1_0.capacity"}, keys)
+}
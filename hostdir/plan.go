@@ -0,0 +1,99 @@
+package hostdir
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// Plan places count workloads across nodes as a dry run.
+// Workloads are spread one by one to the node which can take the most, the placement of each node
+// is checked by CalculateDeploy. Workloads that can't be placed are counted in Unplaced.
+func (p Plugin) Plan(ctx context.Context, nodenames []string, resource plugintypes.WorkloadResourceRequest, count int) (*types.DeployPlan, error) {
+	capacity, err := p.GetNodesDeployCapacity(ctx, nodenames, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &types.DeployPlan{Count: count, Nodes: []*types.NodePlan{}}
+	nodePlans := map[string]*types.NodePlan{}
+	for _, nodename := range nodenames {
+		if _, ok := nodePlans[nodename]; ok {
+			continue
+		}
+		nodePlan := &types.NodePlan{Nodename: nodename}
+		if c, ok := capacity.NodeDeployCapacityMap[nodename]; ok {
+			nodePlan.Capacity = c.Capacity
+		}
+		nodePlans[nodename] = nodePlan
+		plan.Nodes = append(plan.Nodes, nodePlan)
+	}
+
+	for plan.Placed < count {
+		var best *types.NodePlan
+		for _, nodePlan := range plan.Nodes {
+			if left := nodePlan.Capacity - nodePlan.Deploy; left > 0 && (best == nil || left > best.Capacity-best.Deploy) {
+				best = nodePlan
+			}
+		}
+		if best == nil {
+			break
+		}
+		best.Deploy++
+		plan.Placed++
+	}
+	plan.Unplaced = count - plan.Placed
+
+	for _, nodePlan := range plan.Nodes {
+		workloadsResource := []*types.WorkloadResource{}
+		if nodePlan.Deploy > 0 {
+			// make sure the placement is accepted by deploy, pool bindings are resolved by it as well
+			resp, err := p.CalculateDeploy(ctx, nodePlan.Nodename, nodePlan.Deploy, resource)
+			if err != nil {
+				return nil, err
+			}
+			for _, raw := range resp.WorkloadsResource {
+				workloadResource := &types.WorkloadResource{}
+				if err := workloadResource.Parse(raw); err != nil {
+					return nil, err
+				}
+				workloadsResource = append(workloadsResource, workloadResource)
+			}
+		}
+		if err := p.fillNodePlan(ctx, nodePlan, workloadsResource); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(plan.Nodes, func(i, j int) bool { return plan.Nodes[i].Deploy > plan.Nodes[j].Deploy })
+	return plan, nil
+}
+
+// fillNodePlan calculates the free space and remaining capacity of node after the deploy
+func (p Plugin) fillNodePlan(ctx context.Context, nodePlan *types.NodePlan, workloadsResource []*types.WorkloadResource) error {
+	nodeResourceInfo, err := p.doGetNodeResourceInfo(ctx, nodePlan.Nodename)
+	if err != nil {
+		return err
+	}
+	if nodePlan.Free, err = nodeResourceInfo.GetAvailableResource(&p.hostdirConfig); err != nil {
+		return err
+	}
+	for _, workloadResource := range workloadsResource {
+		usage, err := p.workloadUsage(nodeResourceInfo, workloadResource)
+		if err != nil {
+			return err
+		}
+		for path, root := range usage.Roots {
+			nodePlan.Free[path] -= root.Size
+		}
+	}
+	if nodePlan.Capacity == math.MaxInt {
+		nodePlan.Remaining = math.MaxInt
+	} else {
+		nodePlan.Remaining = nodePlan.Capacity - nodePlan.Deploy
+	}
+	return nil
+}
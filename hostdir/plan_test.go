@@ -0,0 +1,60 @@
+package hostdir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+
+	for nodename, root := range map[string]string{
+		"node0": "/data:100GiB",
+		"node1": "/data:50GiB",
+		"node2": "/data:20GiB",
+	} {
+		_, err := st.AddNode(ctx, nodename, plugintypes.NodeResourceRequest{"roots": []string{root}}, nil)
+		assert.NoError(t, err)
+	}
+	nodenames := []string{"node0", "node1", "node2"}
+	req := plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:10GiB"}}
+
+	// spread to the node which can take the most
+	plan, err := st.Plan(ctx, nodenames, req, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, plan.Placed)
+	assert.Equal(t, 0, plan.Unplaced)
+	assert.Len(t, plan.Nodes, 3)
+	deploy := map[string]int{}
+	for _, nodePlan := range plan.Nodes {
+		deploy[nodePlan.Nodename] = nodePlan.Deploy
+		assert.Equal(t, nodePlan.Capacity-nodePlan.Deploy, nodePlan.Remaining)
+		assert.Equal(t, int64(nodePlan.Remaining)*10*units.GiB, nodePlan.Free["/data"])
+	}
+	assert.Equal(t, map[string]int{"node0": 7, "node1": 1, "node2": 0}, deploy)
+	assert.Equal(t, "node0", plan.Nodes[0].Nodename)
+
+	// the stored state isn't touched
+	plan, err = st.Plan(ctx, nodenames, req, 8)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, plan.Placed)
+
+	// more than the total capacity
+	plan, err = st.Plan(ctx, nodenames, req, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 17, plan.Placed)
+	assert.Equal(t, 3, plan.Unplaced)
+	for _, nodePlan := range plan.Nodes {
+		assert.Equal(t, 0, nodePlan.Remaining)
+		assert.Equal(t, int64(0), nodePlan.Free["/data"])
+	}
+
+	// unknown node
+	_, err = st.Plan(ctx, []string{"node3"}, req, 1)
+	assert.Error(t, err)
+}
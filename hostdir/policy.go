@@ -0,0 +1,71 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	coretypes "github.com/projecteru2/core/types"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// coreNodeInfoKey is where eru-core keeps the meta of a node under its prefix, the pod of node is read from it
+const coreNodeInfoKey = "/node/%s"
+
+// newCoreStore returns the store of eru-core records, the etcd of plugin is shared with eru-core,
+// a client of the prefix of eru-core is created if the plugin keeps its records under another prefix
+func newCoreStore(ctx context.Context, config coretypes.Config, hostdirConfig types.Config, s store.Store) (store.Store, error) {
+	etcdConfig := config.Etcd
+	if etcdConfig.Prefix == hostdirConfig.Policies.CorePrefixOf() {
+		return s, nil
+	}
+	etcdConfig.Prefix = hostdirConfig.Policies.CorePrefixOf()
+	return store.NewETCD(ctx, etcdConfig)
+}
+
+// getPolicy returns the quota policy of workloads of app on the node.
+// The pod of node is only looked up if there are policies of pods.
+func (p Plugin) getPolicy(ctx context.Context, nodename string, app string) (*types.Policy, error) {
+	pod := ""
+	if len(p.hostdirConfig.Policies.Pods) > 0 {
+		var err error
+		if pod, err = p.getNodePod(ctx, nodename); err != nil {
+			return nil, err
+		}
+	}
+	return p.hostdirConfig.PolicyOf(pod, app), nil
+}
+
+// getNodePod returns the pod of node from eru-core, the node must be known to eru-core,
+// otherwise the policy of its pod would be silently skipped
+func (p Plugin) getNodePod(ctx context.Context, nodename string) (string, error) {
+	core := p.core
+	if core == nil {
+		core = p.store
+	}
+	value, err := core.Get(ctx, fmt.Sprintf(coreNodeInfoKey, nodename))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return "", errors.Wrapf(coretypes.ErrNodeNotExists, "no meta of node %s in eru-core to find its pod, is core_prefix of policies the etcd prefix of eru-core?", nodename)
+	}
+	if err != nil {
+		return "", err
+	}
+	node := &coretypes.NodeMeta{}
+	if err := json.Unmarshal(value, node); err != nil {
+		return "", errors.Wrapf(err, "failed to decode meta of node %s", nodename)
+	}
+	return node.Podname, nil
+}
+
+// applyPolicy applies the quota policy of node to the request and checks it
+func (p Plugin) applyPolicy(ctx context.Context, nodename string, req *types.WorkloadResourceRequest) error {
+	policy, err := p.getPolicy(ctx, nodename, req.App)
+	if err != nil {
+		return err
+	}
+	req.ApplyPolicy(policy)
+	return req.Validate()
+}
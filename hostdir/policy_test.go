@@ -0,0 +1,129 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	"github.com/projecteru2/core/store/etcdv3/embedded"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestPolicy(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	st.hostdirConfig.Policies = types.PoliciesConfig{
+		Default: types.PolicyConfig{MaxBindingSize: "10GiB", MaxTotal: "15GiB", Granularity: "1GiB"},
+		Pods:    map[string]types.PolicyConfig{"db": {MaxBindingSize: "100GiB", MaxTotal: "200GiB"}},
+		Apps:    map[string]types.PolicyConfig{"mysql": {MaxBindings: 1}},
+	}
+	for _, nodename := range []string{"node0", "node1"} {
+		_, err := st.AddNode(ctx, nodename, plugintypes.NodeResourceRequest{"roots": []string{"/data:1TiB"}}, nil)
+		assert.NoError(t, err)
+	}
+	// node1 is in pod db of eru-core, node0 in pod web
+	for nodename, podname := range map[string]string{"node0": "web", "node1": "db"} {
+		data, err := json.Marshal(coretypes.NodeMeta{Name: nodename, Podname: podname})
+		assert.NoError(t, err)
+		assert.NoError(t, st.store.Put(ctx, fmt.Sprintf(coreNodeInfoKey, nodename), data))
+	}
+
+	policyError := func(err error) string {
+		assert.ErrorIs(t, err, types.ErrQuotaExceeded)
+		e := &types.PolicyError{}
+		if !errors.As(err, &e) {
+			return ""
+		}
+		return e.Rule
+	}
+
+	// sizes are rounded up by granularity
+	req := plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:1500MiB"}}
+	deploy, err := st.CalculateDeploy(ctx, "node0", 1, req)
+	assert.NoError(t, err)
+	workloadResource := &types.WorkloadResource{}
+	assert.NoError(t, workloadResource.Parse(deploy.WorkloadsResource[0]))
+	assert.Equal(t, int64(2*units.GiB), workloadResource.Volumes[0].SizeInBytes)
+
+	// pod of node overrides default policy
+	req = plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:50GiB"}}
+	_, err = st.CalculateDeploy(ctx, "node0", 1, req)
+	assert.Equal(t, types.RuleMaxBindingSize, policyError(err))
+	_, err = st.CalculateDeploy(ctx, "node1", 1, req)
+	assert.NoError(t, err)
+	r, err := st.GetNodesDeployCapacity(ctx, []string{"node0", "node1"}, req)
+	assert.NoError(t, err)
+	assert.NotContains(t, r.NodeDeployCapacityMap, "node0")
+	assert.Contains(t, r.NodeDeployCapacityMap, "node1")
+	_, err = st.GetNodesDeployCapacity(ctx, []string{"node0"}, req)
+	assert.Equal(t, types.RuleMaxBindingSize, policyError(err))
+
+	// the policy of pod can't be skipped for nodes unknown to eru-core
+	_, err = st.AddNode(ctx, "node2", plugintypes.NodeResourceRequest{"roots": []string{"/data:1TiB"}}, nil)
+	assert.NoError(t, err)
+	_, err = st.CalculateDeploy(ctx, "node2", 1, req)
+	assert.ErrorIs(t, err, coretypes.ErrNodeNotExists)
+
+	// app of request is recorded in workload
+	req = plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:1GiB", "/data/img1:/dir1:1GiB"}, "app": "mysql"}
+	_, err = st.CalculateDeploy(ctx, "node0", 1, req)
+	assert.Equal(t, types.RuleMaxBindings, policyError(err))
+	req = plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:8GiB"}, "app": "mysql"}
+	deploy, err = st.CalculateDeploy(ctx, "node0", 1, req)
+	assert.NoError(t, err)
+	resource := deploy.WorkloadsResource[0]
+	workloadResource = &types.WorkloadResource{}
+	assert.NoError(t, workloadResource.Parse(resource))
+	assert.Equal(t, "mysql", workloadResource.App)
+
+	// realloc checks the resulting workload with the policy of its app
+	_, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img1:/dir1:1GiB"}})
+	assert.Equal(t, types.RuleMaxBindings, policyError(err))
+	_, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:3GiB"}})
+	assert.Equal(t, types.RuleMaxBindingSize, policyError(err))
+	realloc, err := st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:100MiB"}})
+	assert.NoError(t, err)
+	target := &types.WorkloadResource{}
+	assert.NoError(t, target.Parse(realloc.WorkloadResource))
+	assert.Equal(t, int64(9*units.GiB), target.Volumes[0].SizeInBytes)
+	assert.Equal(t, "mysql", target.App)
+}
+
+func TestPolicyOfPrefixedStore(t *testing.T) {
+	ctx := context.Background()
+	cluster := embedded.NewCluster(t, "/eru-hostdir")
+	config := coretypes.Config{Etcd: coretypes.EtcdConfig{Machines: cluster.RandClient().Endpoints(), Prefix: "/eru-hostdir"}}
+	hostdirConfig := types.Config{
+		Overcommit: 1,
+		Store:      types.StoreConfig{Type: types.StoreETCD},
+		Policies: types.PoliciesConfig{
+			Default: types.PolicyConfig{MaxBindingSize: "10GiB"},
+			Pods:    map[string]types.PolicyConfig{"db": {MaxBindingSize: "100GiB"}},
+		},
+	}
+	st, err := NewPlugin(ctx, config, hostdirConfig)
+	assert.NoError(t, err)
+	defer st.Close()
+	_, err = st.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{"roots": []string{"/data:1TiB"}}, nil)
+	assert.NoError(t, err)
+
+	// the meta of node is kept by eru-core under its own prefix
+	core, err := store.NewETCD(ctx, coretypes.EtcdConfig{Machines: config.Etcd.Machines, Prefix: types.DefaultCorePrefix})
+	assert.NoError(t, err)
+	defer core.Close()
+	_, err = st.CalculateDeploy(ctx, "node0", 1, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:50GiB"}})
+	assert.ErrorIs(t, err, coretypes.ErrNodeNotExists)
+	data, err := json.Marshal(coretypes.NodeMeta{Name: "node0", Podname: "db"})
+	assert.NoError(t, err)
+	assert.NoError(t, core.Put(ctx, fmt.Sprintf(coreNodeInfoKey, "node0"), data))
+	_, err = st.CalculateDeploy(ctx, "node0", 1, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:50GiB"}})
+	assert.NoError(t, err)
+}
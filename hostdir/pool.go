@@ -0,0 +1,352 @@
+package hostdir
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/utils"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// poolDirPrefix is the prefix of directories created on roots for pool bindings
+const poolDirPrefix = "hostdir-"
+
+// splitPoolBindings returns the bindings with source, and the size of pool bindings by pool.
+// Pools without size are included with 0, they still need a root.
+func splitPoolBindings(vbs types.VolumeBindings) (types.VolumeBindings, map[string]int64) {
+	bindings := types.VolumeBindings{}
+	poolNeed := map[string]int64{}
+	for _, vb := range vbs {
+		if vb.IsPool() {
+			poolNeed[vb.Pool] += vb.SizeInBytes
+			continue
+		}
+		bindings = append(bindings, vb)
+	}
+	return bindings, poolNeed
+}
+
+// getAvailable returns the free space of each root, minus the need of count replicas
+func (p Plugin) getAvailable(nodeResourceInfo *types.NodeResourceInfo, need map[string]int64, count int) (map[string]int64, error) {
+	available, err := nodeResourceInfo.GetAvailableResource(&p.hostdirConfig)
+	if err != nil {
+		return nil, err
+	}
+	for path, size := range need {
+		available[path] -= size * int64(count)
+	}
+	return available, nil
+}
+
+// deployable returns how many replicas fit in the available space of roots, math.MaxInt if nothing is needed.
+// need is the size of bindings with source of a replica by root, poolNeed is the size of pool bindings by pool.
+// Pool bindings of a replica are put on the same root of the pool, so a root takes available / need of pool replicas.
+// With anti-affinity, used is the failure domains of each pool taken by the group, a free domain takes one replica.
+func (p Plugin) deployable(roots types.Roots, available map[string]int64, need map[string]int64, poolNeed map[string]int64, used map[string]map[string]bool) int {
+	hi := math.MaxInt
+	for path, size := range need {
+		if size <= 0 {
+			continue
+		}
+		count := 0
+		if available[path] > 0 {
+			count = int(available[path] / size)
+		}
+		hi = utils.Min(hi, count)
+	}
+	pools := map[string][]string{}
+	for pool, size := range poolNeed {
+		paths := roots.InPool(&p.hostdirConfig, pool)
+		if len(paths) == 0 {
+			return 0
+		}
+		if used != nil {
+			paths = p.freeDomainRoots(roots, paths, used[pool])
+			pools[pool] = paths
+			hi = utils.Min(hi, p.countDomains(roots, paths, available, nil, size, 0))
+			continue
+		}
+		if size <= 0 {
+			continue
+		}
+		pools[pool] = paths
+		count := 0
+		for _, path := range paths {
+			if available[path] > 0 {
+				count += int(available[path] / size)
+			}
+		}
+		hi = utils.Min(hi, count)
+	}
+	if hi == math.MaxInt {
+		return hi
+	}
+
+	// roots may be shared by bindings with source and pools, so check them together
+	fits := func(n int) bool {
+		for pool, paths := range pools {
+			if used != nil {
+				if p.countDomains(roots, paths, available, need, poolNeed[pool], n) < n {
+					return false
+				}
+				continue
+			}
+			count := 0
+			for _, path := range paths {
+				if left := available[path] - need[path]*int64(n); left > 0 {
+					count += int(left / poolNeed[pool])
+				}
+			}
+			if count < n {
+				return false
+			}
+		}
+		return true
+	}
+	return sort.Search(hi+1, func(n int) bool { return !fits(n) }) - 1
+}
+
+// countDomains returns the number of failure domains having a root of paths with room for size,
+// after n replicas of need are taken.
+func (p Plugin) countDomains(roots types.Roots, paths []string, available map[string]int64, need map[string]int64, size int64, n int) int {
+	domains := map[string]bool{}
+	for _, path := range paths {
+		if available[path]-need[path]*int64(n) >= size {
+			domains[p.hostdirConfig.RootDomain(path, roots[path])] = true
+		}
+	}
+	return len(domains)
+}
+
+// freeDomainRoots returns the roots of paths whose failure domain isn't used
+func (p Plugin) freeDomainRoots(roots types.Roots, paths []string, used map[string]bool) []string {
+	ans := []string{}
+	for _, path := range paths {
+		if !used[p.hostdirConfig.RootDomain(path, roots[path])] {
+			ans = append(ans, path)
+		}
+	}
+	return ans
+}
+
+// usedDomains returns the failure domains of each pool taken by pool bindings of the anti-affinity group on node,
+// bindings whose source is in exclude aren't counted. It returns nil if there is no group.
+func (p Plugin) usedDomains(nodeResourceInfo *types.NodeResourceInfo, group string, exclude types.VolumeBindings) map[string]map[string]bool {
+	if group == "" {
+		return nil
+	}
+	excluded := map[string]bool{}
+	for _, vb := range exclude {
+		excluded[vb.Source] = true
+	}
+	ans := map[string]map[string]bool{}
+	for _, vb := range nodeResourceInfo.Bindings {
+		if excluded[vb.Source] || vb.AntiAffinity != group {
+			continue
+		}
+		root, ok := nodeResourceInfo.Capacity.Roots.Find(vb.Source)
+		if !ok {
+			continue
+		}
+		r := nodeResourceInfo.Capacity.Roots[root]
+		pool := p.hostdirConfig.RootPool(root, r)
+		if ans[pool] == nil {
+			ans[pool] = map[string]bool{}
+		}
+		ans[pool][p.hostdirConfig.RootDomain(root, r)] = true
+	}
+	return ans
+}
+
+// allocatePools picks the sources of pool bindings for count replicas, others are copied as they are.
+// Pool bindings of a replica are put on the same root of the pool, picked by the placement strategy per replica,
+// so replicas spill to other roots when one root can't hold them all. A new directory on the root is the source of each binding,
+// the anti-affinity group is recorded on the binding, so replicas deployed later avoid the failure domains taken by the group.
+func (p Plugin) allocatePools(nodename string, nodeResourceInfo *types.NodeResourceInfo, vbs types.VolumeBindings, count int, group string, exclude types.VolumeBindings) ([]types.VolumeBindings, error) {
+	bindings, poolNeed := splitPoolBindings(vbs)
+	need, err := nodeResourceInfo.Capacity.Roots.Bucket(bindings)
+	if err != nil {
+		return nil, err
+	}
+	available, err := p.getAvailable(nodeResourceInfo, need, count)
+	if err != nil {
+		return nil, err
+	}
+	pools := []string{}
+	for pool := range poolNeed {
+		pools = append(pools, pool)
+	}
+	sort.Strings(pools)
+
+	roots := nodeResourceInfo.Capacity.Roots
+	strategy := p.hostdirConfig.PlacementOf()
+	used := p.usedDomains(nodeResourceInfo, group, exclude)
+	paths := map[string][]string{}
+	next := map[string]int{}
+	for _, pool := range pools {
+		paths[pool] = roots.InPool(&p.hostdirConfig, pool)
+		next[pool] = p.roundRobinStart(nodeResourceInfo, paths[pool])
+		if used != nil && used[pool] == nil {
+			used[pool] = map[string]bool{}
+		}
+	}
+
+	ans := make([]types.VolumeBindings, count)
+	for i := 0; i < count; i++ {
+		picked := map[string]string{}
+		for _, pool := range pools {
+			skip := map[string]bool{}
+			for _, path := range paths[pool] {
+				skip[path] = used != nil && used[pool][p.hostdirConfig.RootDomain(path, roots[path])]
+			}
+			var best string
+			best, next[pool] = pickRoot(strategy, paths[pool], skip, available, poolNeed[pool], next[pool])
+			if best == "" {
+				return nil, &types.InsufficientCapacityError{
+					Node:    nodename,
+					Root:    poolRoot(pool),
+					Binding: poolBindings(vbs, pool),
+					Need:    poolNeed[pool],
+				}
+			}
+			available[best] -= poolNeed[pool]
+			picked[pool] = best
+			if used != nil {
+				used[pool][p.hostdirConfig.RootDomain(best, roots[best])] = true
+			}
+		}
+		for _, vb := range vbs {
+			vb1 := vb.DeepCopy()
+			if vb.IsPool() {
+				vb1.Source, vb1.Pool = filepath.Join(picked[vb.Pool], poolDir()), ""
+				vb1.AntiAffinity = group
+			}
+			ans[i] = append(ans[i], vb1)
+		}
+	}
+	return ans, nil
+}
+
+// resolveReallocPools resolves the pool bindings of realloc request.
+// The binding of origin with the same destination on a root of the pool is resized,
+// otherwise a new source is picked like deploy, away from the failure domains taken by other replicas of the group.
+func (p Plugin) resolveReallocPools(nodename string, nodeResourceInfo *types.NodeResourceInfo, origin types.VolumeBindings, req *types.WorkloadResourceRequest) (types.VolumeBindings, error) {
+	ans := types.VolumeBindings{}
+	unresolved := types.VolumeBindings{}
+	for _, vb := range req.Volumes {
+		if !vb.IsPool() {
+			ans = append(ans, vb)
+			continue
+		}
+		var resolved *types.VolumeBinding
+		for _, originVB := range origin {
+			root, ok := nodeResourceInfo.Capacity.Roots.Find(originVB.Source)
+			if ok && originVB.Destination == vb.Destination && p.hostdirConfig.RootPool(root, nodeResourceInfo.Capacity.Roots[root]) == vb.Pool {
+				resolved = vb.DeepCopy()
+				resolved.Source, resolved.Pool, resolved.AntiAffinity = originVB.Source, "", originVB.AntiAffinity
+				break
+			}
+		}
+		if resolved != nil {
+			ans = append(ans, resolved)
+			continue
+		}
+		if vb.SizeInBytes < 0 {
+			return nil, errors.Wrapf(types.ErrInvalidVolume, "no binding of pool %s to shrink: %s", vb.Pool, vb.ToString())
+		}
+		unresolved = append(unresolved, vb)
+	}
+	if len(unresolved) == 0 {
+		return ans, nil
+	}
+	resolved, err := p.allocatePools(nodename, nodeResourceInfo, unresolved, 1, req.AntiAffinity, origin)
+	if err != nil {
+		return nil, err
+	}
+	return append(ans, resolved[0]...), nil
+}
+
+// pickRoot picks a root of paths with enough space for need by strategy, roots in skip aren't picked, empty if none fits.
+// next is the index to start with for round-robin, the one after the picked root is returned.
+func pickRoot(strategy string, paths []string, skip map[string]bool, available map[string]int64, need int64, next int) (string, int) {
+	best := -1
+	for i := range paths {
+		idx := i
+		if strategy == types.PlacementRoundRobin {
+			idx = (next + i) % len(paths)
+		}
+		if skip[paths[idx]] || available[paths[idx]] < need {
+			continue
+		}
+		if best == -1 {
+			best = idx
+			if strategy == types.PlacementRoundRobin {
+				break
+			}
+			continue
+		}
+		switch left, bestLeft := available[paths[idx]], available[paths[best]]; {
+		case strategy == types.PlacementBestFit && left < bestLeft:
+			best = idx
+		case strategy == types.PlacementWorstFit && left > bestLeft:
+			best = idx
+		}
+	}
+	if best == -1 {
+		return "", next
+	}
+	return paths[best], best + 1
+}
+
+// roundRobinStart returns the index of root to start round-robin with, it follows the number of bindings already on the roots,
+// so that deploys go on where the previous ones stopped without keeping state.
+func (p Plugin) roundRobinStart(nodeResourceInfo *types.NodeResourceInfo, paths []string) int {
+	if len(paths) == 0 {
+		return 0
+	}
+	inPaths := map[string]bool{}
+	for _, path := range paths {
+		inPaths[path] = true
+	}
+	count := 0
+	for _, vb := range nodeResourceInfo.Bindings {
+		if root, ok := nodeResourceInfo.Capacity.Roots.Find(vb.Source); ok && inPaths[root] {
+			count++
+		}
+	}
+	return count % len(paths)
+}
+
+// recordRoots records the root holding the source of each binding of workload resource
+func recordRoots(nodeResourceInfo *types.NodeResourceInfo, workloadResource *types.WorkloadResource) {
+	workloadResource.Roots = map[string]string{}
+	for _, vb := range workloadResource.Volumes {
+		if root, ok := nodeResourceInfo.Capacity.Roots.Find(vb.Source); ok {
+			workloadResource.Roots[vb.Destination] = root
+		}
+	}
+}
+
+// poolDir returns a new directory name for a pool binding
+func poolDir() string {
+	return poolDirPrefix + strings.ToLower(utils.RandomString(16))
+}
+
+func poolRoot(pool string) string {
+	return "pool=" + pool
+}
+
+// poolBindings returns the pool bindings of pool in string form
+func poolBindings(vbs types.VolumeBindings, pool string) string {
+	ans := []string{}
+	for _, vb := range vbs {
+		if vb.IsPool() && vb.Pool == pool {
+			ans = append(ans, vb.ToString())
+		}
+	}
+	return strings.Join(ans, ",")
+}
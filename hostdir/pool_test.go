@@ -0,0 +1,255 @@
+package hostdir
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestPool(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	_, err := st.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{
+		"roots": []string{"/ssd0:100GiB:pool=ssd", "/ssd1:50GiB:pool=ssd", "/hdd:1TiB:pool=hdd"},
+	}, nil)
+	assert.NoError(t, err)
+	_, err = st.AddNode(ctx, "node1", plugintypes.NodeResourceRequest{
+		"roots": []string{"/hdd:1TiB:pool=hdd"},
+	}, nil)
+	assert.NoError(t, err)
+	nodes := []string{"node0", "node1"}
+
+	// node1 has no ssd root
+	req := plugintypes.WorkloadResourceRequest{"volumes": []string{"pool=ssd:/data:30GiB"}}
+	r, err := st.GetNodesDeployCapacity(ctx, nodes, req)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, r.NodeDeployCapacityMap["node0"].Capacity)
+	assert.NotContains(t, r.NodeDeployCapacityMap, "node1")
+	assert.Equal(t, 4, r.Total)
+
+	// bindings of a pool in one replica share a root
+	r, err = st.GetNodesDeployCapacity(ctx, nodes, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"pool=ssd:/data:20GiB", "pool=ssd:/logs:10GiB"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, r.Total)
+
+	// bindings with source take space of the pool as well
+	r, err = st.GetNodesDeployCapacity(ctx, nodes, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"/ssd0/img0:/img:40GiB", "pool=ssd:/data:30GiB"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r.Total)
+
+	r, err = st.GetNodesDeployCapacity(ctx, nodes, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"pool=nvme:/data:1GiB"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, r.Total)
+
+	// replicas are spread to the root with the most space left
+	deploy, err := st.CalculateDeploy(ctx, "node0", 4, req)
+	assert.NoError(t, err)
+	roots := map[string]int{}
+	sources := map[string]bool{}
+	workloadsResource := []plugintypes.WorkloadResource{}
+	for _, raw := range deploy.WorkloadsResource {
+		workloadResource := &types.WorkloadResource{}
+		assert.NoError(t, workloadResource.Parse(raw))
+		assert.Len(t, workloadResource.Volumes, 1)
+		vb := workloadResource.Volumes[0]
+		assert.False(t, vb.IsPool())
+		assert.True(t, strings.HasPrefix(filepath.Base(vb.Source), poolDirPrefix))
+		roots[filepath.Dir(vb.Source)]++
+		sources[vb.Source] = true
+		workloadsResource = append(workloadsResource, raw)
+	}
+	assert.Equal(t, map[string]int{"/ssd0": 3, "/ssd1": 1}, roots)
+	assert.Len(t, sources, 4)
+
+	_, err = st.CalculateDeploy(ctx, "node0", 5, req)
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
+	_, err = st.CalculateDeploy(ctx, "node1", 1, req)
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
+
+	// 10GiB left on /ssd0, 20GiB left on /ssd1
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, workloadsResource, true, true)
+	assert.NoError(t, err)
+	r, err = st.GetNodesDeployCapacity(ctx, nodes, req)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, r.Total)
+
+	// the binding of the pool with the same destination is resized
+	resource := workloadsResource[0]
+	origin := &types.WorkloadResource{}
+	assert.NoError(t, origin.Parse(resource))
+	realloc, err := st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"pool=ssd:/data:5GiB"},
+	})
+	assert.NoError(t, err)
+	target := &types.WorkloadResource{}
+	assert.NoError(t, target.Parse(realloc.WorkloadResource))
+	assert.Len(t, target.Volumes, 1)
+	assert.Equal(t, origin.Volumes[0].Source, target.Volumes[0].Source)
+	assert.Equal(t, int64(35*units.GiB), target.Volumes[0].SizeInBytes)
+
+	// a new destination gets a new source in the pool
+	realloc, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"pool=ssd:/logs:15GiB"},
+	})
+	assert.NoError(t, err)
+	target = &types.WorkloadResource{}
+	assert.NoError(t, target.Parse(realloc.WorkloadResource))
+	assert.Len(t, target.Volumes, 2)
+	for _, vb := range target.Volumes {
+		if vb.Destination == "/logs" {
+			assert.Equal(t, "/ssd1", filepath.Dir(vb.Source))
+		}
+	}
+
+	_, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"pool=ssd:/logs:30GiB"},
+	})
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
+	_, err = st.CalculateRealloc(ctx, "node0", resource, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"pool=hdd:/data:-1GiB"},
+	})
+	assert.ErrorIs(t, err, types.ErrInvalidVolume)
+}
+
+func TestPlacement(t *testing.T) {
+	ctx := context.Background()
+	deploy := func(st *Plugin, size string, count int) ([]string, []plugintypes.WorkloadResource) {
+		resp, err := st.CalculateDeploy(ctx, "node0", count, plugintypes.WorkloadResourceRequest{
+			"volumes": []string{"pool=ssd:/data:" + size},
+		})
+		assert.NoError(t, err)
+		roots := []string{}
+		for _, raw := range resp.WorkloadsResource {
+			workloadResource := &types.WorkloadResource{}
+			assert.NoError(t, workloadResource.Parse(raw))
+			// the picked root is recorded
+			assert.Equal(t, filepath.Dir(workloadResource.Volumes[0].Source), workloadResource.Roots["/data"])
+			roots = append(roots, workloadResource.Roots["/data"])
+		}
+		return roots, resp.WorkloadsResource
+	}
+	newPlugin := func(placement string) *Plugin {
+		st := initHostdir(ctx, t)
+		st.hostdirConfig.Placement = placement
+		_, err := st.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{
+			"roots": []string{"/a:50GiB:pool=ssd", "/b:30GiB:pool=ssd"},
+		}, nil)
+		assert.NoError(t, err)
+		return st
+	}
+	capacity := func(st *Plugin, size string) int {
+		r, err := st.GetNodesDeployCapacity(ctx, []string{"node0"}, plugintypes.WorkloadResourceRequest{
+			"volumes": []string{"pool=ssd:/data:" + size},
+		})
+		assert.NoError(t, err)
+		return r.Total
+	}
+
+	// best-fit keeps the large root free for a large binding
+	st := newPlugin(types.PlacementBestFit)
+	roots, workloadsResource := deploy(st, "20GiB", 1)
+	assert.Equal(t, []string{"/b"}, roots)
+	_, err := st.SetNodeResourceUsage(ctx, "node0", nil, nil, workloadsResource, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, capacity(st, "50GiB"))
+
+	// worst-fit fragments both roots
+	st = newPlugin(types.PlacementWorstFit)
+	roots, workloadsResource = deploy(st, "20GiB", 1)
+	assert.Equal(t, []string{"/a"}, roots)
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, workloadsResource, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, capacity(st, "50GiB"))
+	assert.Equal(t, 2, capacity(st, "30GiB"))
+
+	// replicas spill to other roots when the picked one is full
+	st = newPlugin(types.PlacementBestFit)
+	roots, _ = deploy(st, "20GiB", 3)
+	assert.Equal(t, []string{"/b", "/a", "/a"}, roots)
+	_, err = st.CalculateDeploy(ctx, "node0", 4, plugintypes.WorkloadResourceRequest{
+		"volumes": []string{"pool=ssd:/data:20GiB"},
+	})
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
+
+	// round-robin goes on from the bindings already deployed, roots without space are skipped
+	st = newPlugin(types.PlacementRoundRobin)
+	roots, workloadsResource = deploy(st, "10GiB", 3)
+	assert.Equal(t, []string{"/a", "/b", "/a"}, roots)
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, workloadsResource, true, true)
+	assert.NoError(t, err)
+	roots, _ = deploy(st, "10GiB", 2)
+	assert.Equal(t, []string{"/b", "/a"}, roots)
+	roots, _ = deploy(st, "25GiB", 1)
+	assert.Equal(t, []string{"/a"}, roots)
+}
+
+func TestAntiAffinity(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	_, err := st.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{
+		"roots": []string{"/a:100GiB:pool=ssd,domain=sda", "/b:100GiB:pool=ssd,domain=sda", "/c:100GiB:pool=ssd,domain=sdb"},
+	}, nil)
+	assert.NoError(t, err)
+	request := func(group string, volumes ...string) plugintypes.WorkloadResourceRequest {
+		return plugintypes.WorkloadResourceRequest{"volumes": volumes, "anti-affinity": group}
+	}
+	capacity := func(req plugintypes.WorkloadResourceRequest) int {
+		r, err := st.GetNodesDeployCapacity(ctx, []string{"node0"}, req)
+		assert.NoError(t, err)
+		return r.Total
+	}
+	domainOf := func(root string) string {
+		return map[string]string{"/a": "sda", "/b": "sda", "/c": "sdb"}[root]
+	}
+
+	// capacity is the number of distinct domains with room
+	assert.Equal(t, 30, capacity(request("", "pool=ssd:/data:10GiB")))
+	assert.Equal(t, 2, capacity(request("db", "pool=ssd:/data:10GiB")))
+	assert.Equal(t, 1, capacity(request("db", "/c/img0:/img:95GiB", "pool=ssd:/data:10GiB")))
+
+	deploy, err := st.CalculateDeploy(ctx, "node0", 2, request("db", "pool=ssd:/data:10GiB"))
+	assert.NoError(t, err)
+	domains := map[string]bool{}
+	for _, raw := range deploy.WorkloadsResource {
+		workloadResource := &types.WorkloadResource{}
+		assert.NoError(t, workloadResource.Parse(raw))
+		vb := workloadResource.Volumes[0]
+		assert.Equal(t, "db", vb.AntiAffinity)
+		assert.False(t, strings.HasPrefix(filepath.Base(vb.Source), poolDirPrefix+"db-"))
+		domains[domainOf(workloadResource.Roots["/data"])] = true
+	}
+	assert.Len(t, domains, 2)
+	_, err = st.CalculateDeploy(ctx, "node0", 3, request("db", "pool=ssd:/data:10GiB"))
+	assert.ErrorIs(t, err, types.ErrInsufficientCapacity)
+
+	// replicas deployed later avoid the domains taken by the group
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, deploy.WorkloadsResource, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, capacity(request("db", "pool=ssd:/data:10GiB")))
+	assert.Equal(t, 2, capacity(request("cache", "pool=ssd:/data:10GiB")))
+
+	// realloc of a replica keeps away from the domains of others
+	origin := &types.WorkloadResource{}
+	assert.NoError(t, origin.Parse(deploy.WorkloadsResource[0]))
+	realloc, err := st.CalculateRealloc(ctx, "node0", deploy.WorkloadsResource[0], request("db", "pool=ssd:/logs:1GiB"))
+	assert.NoError(t, err)
+	target := &types.WorkloadResource{}
+	assert.NoError(t, target.Parse(realloc.WorkloadResource))
+	assert.Equal(t, domainOf(origin.Roots["/data"]), domainOf(target.Roots["/logs"]))
+	for _, vb := range target.Volumes {
+		assert.Equal(t, "db", vb.AntiAffinity, vb.Destination)
+	}
+}
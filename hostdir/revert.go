@@ -0,0 +1,119 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/log"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// usageInput is the input of SetNodeResourceUsage kept in journal
+type usageInput struct {
+	Resource          json.RawMessage                `json:"resource"`
+	ResourceRequest   json.RawMessage                `json:"resource_request"`
+	WorkloadsResource []plugintypes.WorkloadResource `json:"workloads_resource"`
+	Delta             bool                           `json:"delta"`
+	Incr              bool                           `json:"incr"`
+}
+
+// RevertJournal restores the node of journal entry id to its record before the entry.
+// The node must not be changed after the entry, the restore is compare-and-swap against the record after it,
+// so types.ErrConflict is returned if others changed the node meanwhile.
+// With dryRun the changes are only previewed. The revert is journaled as well, so it can be reverted again.
+func (p Plugin) RevertJournal(ctx context.Context, id int64, dryRun bool) (*types.JournalRevert, error) {
+	if p.journal == nil {
+		return nil, errors.Wrap(types.ErrInvalidConfig, "journal is disabled")
+	}
+	entry, err := p.journal.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf(nodeResourceInfoKey, entry.Node)
+	current, revision, err := p.store.GetRevision(ctx, key)
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return nil, err
+	}
+	if !sameRecord(current, entry.After) {
+		return nil, errors.Wrapf(types.ErrConflict, "node %s is changed after journal entry %d", entry.Node, id)
+	}
+	changes, err := types.DiffNodeRecords(current, entry.Before)
+	if err != nil {
+		return nil, err
+	}
+	revert := &types.JournalRevert{Entry: entry, Changes: changes}
+	if dryRun {
+		return revert, nil
+	}
+
+	err = p.journaled(ctx, "RevertJournal", entry.Node, map[string]any{"id": id}, func(ctx context.Context) error {
+		var err error
+		if isNullRecord(entry.Before) {
+			err = p.store.CompareAndDelete(ctx, key, revision)
+		} else {
+			err = p.store.CompareAndSwap(ctx, key, entry.Before, revision)
+		}
+		if errors.Is(err, store.ErrConflict) {
+			return errors.Wrapf(types.ErrConflict, "node %s is changed by others", entry.Node)
+		}
+		if err != nil {
+			return err
+		}
+		recordJournal(ctx, current, entry.Before)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	revert.Applied = true
+	// the node is already restored, so a failure is logged rather than returned
+	if err := p.revertTenantsUsage(ctx, entry); err != nil {
+		log.WithFunc("resource.hostdir.RevertJournal").WithField("node", entry.Node).Error(ctx, err, "failed to revert usage of tenants")
+	}
+	return revert, nil
+}
+
+// revertTenantsUsage undoes the change of tenants made by SetNodeResourceUsage of entry.
+// Only delta updates by workloads can be undone, others don't tell the usage they replaced.
+func (p Plugin) revertTenantsUsage(ctx context.Context, entry *types.JournalEntry) error {
+	if entry.Command != "SetNodeResourceUsage" {
+		return nil
+	}
+	input := &usageInput{}
+	if err := json.Unmarshal(entry.Input, input); err != nil {
+		return errors.Wrapf(err, "failed to decode input of journal entry %d", entry.ID)
+	}
+	if !isNullRecord(input.Resource) || !isNullRecord(input.ResourceRequest) {
+		return nil
+	}
+	if !input.Delta {
+		log.WithFunc("resource.hostdir.revertTenantsUsage").WithField("node", entry.Node).Warnf(ctx, "usage of tenants can't be reverted for journal entry %d, it isn't delta", entry.ID)
+		return nil
+	}
+	return p.setTenantsUsage(ctx, entry.Node, input.WorkloadsResource, true, !input.Incr)
+}
+
+// sameRecord returns whether the records are of the same content, null or empty records are of absent nodes
+func sameRecord(a, b []byte) bool {
+	if isNullRecord(a) || isNullRecord(b) {
+		return isNullRecord(a) == isNullRecord(b)
+	}
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func isNullRecord(data []byte) bool {
+	return len(data) == 0 || string(data) == "null"
+}
@@ -0,0 +1,228 @@
+package hostdir
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/log"
+	"github.com/projecteru2/core/utils"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// Export returns the state of all nodes in the store, usage counters of tenants included
+func (p Plugin) Export(ctx context.Context) (*types.Snapshot, error) {
+	nodes, err := p.listNodeResourceInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenants, err := p.listTenantUsages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &types.Snapshot{
+		Version:    types.SnapshotVersion,
+		Prefix:     p.config.Etcd.Prefix,
+		ExportedAt: time.Now(),
+		Nodes:      nodes,
+		Tenants:    tenants,
+	}, nil
+}
+
+// Import writes the nodes of snapshot to the store, the changes are returned sorted by nodename.
+// The usage of tenants on the imported nodes is restored as well, unless the snapshot has no tenants.
+// With ImportReplace, nodes not in the snapshot are removed with their usage of tenants. Nothing is written if dryRun.
+func (p Plugin) Import(ctx context.Context, snapshot *types.Snapshot, mode string, dryRun bool) ([]*types.NodeChange, error) {
+	logger := log.WithFunc("resource.hostdir.Import")
+	if mode != types.ImportMerge && mode != types.ImportReplace {
+		return nil, errors.Wrapf(types.ErrInvalidSnapshot, "unknown import mode: %s", mode)
+	}
+	if err := snapshot.Validate(); err != nil {
+		return nil, err
+	}
+	current, err := p.listNodeResourceInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usages, err := p.listTenantUsages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []*types.NodeChange{}
+	for nodename, nodeResourceInfo := range snapshot.Nodes {
+		change := &types.NodeChange{Nodename: nodename, Action: types.NodeAdded}
+		if origin, ok := current[nodename]; ok {
+			change.Action = types.NodeUpdated
+			change.Diffs = diffNodeResourceInfo(origin, nodeResourceInfo)
+		}
+		if snapshot.Tenants != nil {
+			change.Diffs = append(change.Diffs, diffTenantSizes(types.TenantSizesOf(usages, nodename), types.TenantSizesOf(snapshot.Tenants, nodename))...)
+		}
+		if change.Action == types.NodeUpdated && len(change.Diffs) == 0 {
+			change.Action = types.NodeUnchanged
+		}
+		changes = append(changes, change)
+	}
+	if mode == types.ImportReplace {
+		for nodename := range current {
+			if _, ok := snapshot.Nodes[nodename]; !ok {
+				changes = append(changes, &types.NodeChange{Nodename: nodename, Action: types.NodeRemoved})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Nodename < changes[j].Nodename })
+	if dryRun {
+		return changes, nil
+	}
+
+	for _, change := range changes {
+		switch change.Action {
+		case types.NodeAdded, types.NodeUpdated:
+			err = p.retryOnConflict(ctx, change.Nodename, func() error {
+				return p.putNodeResourceInfo(ctx, change.Nodename, snapshot.Nodes[change.Nodename])
+			})
+			if err == nil && snapshot.Tenants != nil {
+				err = p.importTenantSizes(ctx, change.Nodename, types.TenantSizesOf(usages, change.Nodename), types.TenantSizesOf(snapshot.Tenants, change.Nodename))
+			}
+		case types.NodeRemoved:
+			if err = p.store.Delete(ctx, fmt.Sprintf(nodeResourceInfoKey, change.Nodename)); err == nil {
+				err = p.removeTenantsUsage(ctx, change.Nodename)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			logger.Errorf(ctx, err, "failed to %s node %s", change.Action, change.Nodename)
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// importTenantSizes sets the usage of tenants on node from origin to target, the tenants of the same usage are untouched
+func (p Plugin) importTenantSizes(ctx context.Context, nodename string, origin, target map[string]int64) error {
+	tenants := []string{}
+	for tenant := range origin {
+		tenants = append(tenants, tenant)
+	}
+	for tenant := range target {
+		if _, ok := origin[tenant]; !ok {
+			tenants = append(tenants, tenant)
+		}
+	}
+	sort.Strings(tenants)
+	for _, tenant := range tenants {
+		size := target[tenant]
+		if origin[tenant] == size {
+			continue
+		}
+		if err := p.updateTenantUsage(ctx, tenant, nodename, func(int64) int64 { return size }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putNodeResourceInfo overwrites the node record no matter what it is now
+func (p Plugin) putNodeResourceInfo(ctx context.Context, nodename string, nodeResourceInfo *types.NodeResourceInfo) error {
+	_, revision, err := p.store.GetRevision(ctx, fmt.Sprintf(nodeResourceInfoKey, nodename))
+	if err != nil && !errors.Is(err, store.ErrKeyNotFound) {
+		return err
+	}
+	nodeResourceInfo = nodeResourceInfo.DeepCopy()
+	nodeResourceInfo.Revision = revision
+	return p.doSetNodeResourceInfo(ctx, nodename, nodeResourceInfo)
+}
+
+func (p Plugin) listNodeResourceInfos(ctx context.Context) (map[string]*types.NodeResourceInfo, error) {
+	resps, err := p.store.List(ctx, strings.TrimSuffix(nodeResourceInfoKey, "%s"))
+	if err != nil {
+		return nil, err
+	}
+	ans := map[string]*types.NodeResourceInfo{}
+	for key, value := range resps {
+		nodeResourceInfo, _, err := types.DecodeNodeResourceInfo(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "node %s", utils.Tail(key))
+		}
+		if err := nodeResourceInfo.Validate(); err != nil {
+			return nil, err
+		}
+		ans[utils.Tail(key)] = nodeResourceInfo
+	}
+	return ans, nil
+}
+
+// diffNodeResourceInfo describes the changes from origin to target
+func diffNodeResourceInfo(origin, target *types.NodeResourceInfo) []string {
+	diffs := []string{}
+	diffRoots := func(name string, origin, target types.Roots) {
+		paths := map[string]bool{}
+		for path := range origin {
+			paths[path] = true
+		}
+		for path := range target {
+			paths[path] = true
+		}
+		for path := range paths {
+			o, t := origin[path], target[path]
+			switch {
+			case o == nil:
+				diffs = append(diffs, fmt.Sprintf("%s of root %s added: %+v", name, path, *t))
+			case t == nil:
+				diffs = append(diffs, fmt.Sprintf("%s of root %s removed: %+v", name, path, *o))
+			case *o != *t:
+				diffs = append(diffs, fmt.Sprintf("%s of root %s: %+v -> %+v", name, path, *o, *t))
+			}
+		}
+	}
+	diffRoots("capacity", origin.Capacity.Roots, target.Capacity.Roots)
+	diffRoots("usage", origin.Usage.Roots, target.Usage.Roots)
+
+	bindings := map[[2]string]*types.VolumeBinding{}
+	for _, vb := range origin.Bindings {
+		bindings[vb.GetMapKey()] = vb
+	}
+	for _, vb := range target.Bindings {
+		o, ok := bindings[vb.GetMapKey()]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("binding added: %s", vb.ToString()))
+		case !o.Equal(vb):
+			diffs = append(diffs, fmt.Sprintf("binding: %s -> %s", o.ToString(), vb.ToString()))
+		}
+		delete(bindings, vb.GetMapKey())
+	}
+	for _, vb := range bindings {
+		diffs = append(diffs, fmt.Sprintf("binding removed: %s", vb.ToString()))
+	}
+	if !reflect.DeepEqual(origin.Cleanups, target.Cleanups) {
+		diffs = append(diffs, fmt.Sprintf("cleanups: %d -> %d", len(origin.Cleanups), len(target.Cleanups)))
+	}
+	sort.Strings(diffs)
+	return diffs
+}
+
+// diffTenantSizes describes the changes of usage of tenants on a node from origin to target
+func diffTenantSizes(origin, target map[string]int64) []string {
+	diffs := []string{}
+	for tenant, size := range target {
+		if origin[tenant] != size {
+			diffs = append(diffs, fmt.Sprintf("usage of tenant %s: %d -> %d", tenant, origin[tenant], size))
+		}
+	}
+	for tenant, size := range origin {
+		if _, ok := target[tenant]; !ok {
+			diffs = append(diffs, fmt.Sprintf("usage of tenant %s: %d -> 0", tenant, size))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
@@ -0,0 +1,124 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := initHostdir(ctx, t)
+	dst := initHostdir(ctx, t)
+
+	for _, nodename := range []string{"node0", "node1"} {
+		_, err := src.AddNode(ctx, nodename, plugintypes.NodeResourceRequest{"roots": []string{"/data:100GiB"}}, nil)
+		assert.NoError(t, err)
+	}
+	deploy, err := src.CalculateDeploy(ctx, "node0", 1, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:10GiB"}, "tenant": "team-a"})
+	assert.NoError(t, err)
+	_, err = src.SetNodeResourceUsage(ctx, "node0", nil, nil, deploy.WorkloadsResource, true, true)
+	assert.NoError(t, err)
+
+	snapshot, err := src.Export(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, types.SnapshotVersion, snapshot.Version)
+	assert.Equal(t, "/hostdir", snapshot.Prefix)
+	assert.Len(t, snapshot.Nodes, 2)
+	assert.Equal(t, map[string]int64{"node0": 10 * units.GiB}, snapshot.Tenants["team-a"].Nodes)
+	data, err := json.Marshal(snapshot)
+	assert.NoError(t, err)
+	snapshot = &types.Snapshot{}
+	assert.NoError(t, json.Unmarshal(data, snapshot))
+
+	// node2 is only in dst, node0 differs
+	_, err = dst.AddNode(ctx, "node2", nil, nil)
+	assert.NoError(t, err)
+	other, err := dst.CalculateDeploy(ctx, "node2", 1, plugintypes.WorkloadResourceRequest{"volumes": []string{"/eru/img0:/dir0:1GiB"}, "tenant": "team-b"})
+	assert.NoError(t, err)
+	_, err = dst.SetNodeResourceUsage(ctx, "node2", nil, nil, other.WorkloadsResource, true, true)
+	assert.NoError(t, err)
+	_, err = dst.AddNode(ctx, "node0", plugintypes.NodeResourceRequest{"roots": []string{"/data:50GiB"}}, nil)
+	assert.NoError(t, err)
+
+	// dry run doesn't write
+	changes, err := dst.Import(ctx, snapshot, types.ImportReplace, true)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 3)
+	assert.Equal(t, types.NodeUpdated, changes[0].Action)
+	assert.Contains(t, changes[0].Diffs, "binding added: /data/img0:/dir0:10737418240")
+	assert.Contains(t, changes[0].Diffs, "usage of tenant team-a: 0 -> 10737418240")
+	assert.Equal(t, types.NodeAdded, changes[1].Action)
+	assert.Equal(t, types.NodeRemoved, changes[2].Action)
+	_, err = dst.GetNodeResourceInfo(ctx, "node1", nil)
+	assert.ErrorIs(t, err, coretypes.ErrNodeNotExists)
+
+	// merge keeps node2
+	changes, err = dst.Import(ctx, snapshot, types.ImportMerge, false)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 2)
+	nodes, err := dst.ListNodes(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 3)
+	roots, err := dst.GetNodeRoots(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100*units.GiB), roots[0].Size)
+	assert.Equal(t, int64(10*units.GiB), roots[0].Used)
+	bindings, err := dst.GetNodeBindings(ctx, "node0")
+	assert.NoError(t, err)
+	assert.Len(t, bindings, 1)
+	// counters of tenants are restored with the nodes
+	usage, err := dst.getTenantUsage(ctx, "team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"node0": 10 * units.GiB}, usage.Nodes)
+	usage, err = dst.getTenantUsage(ctx, "team-b")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(units.GiB), usage.Size())
+
+	// nothing changes after merge, replace removes node2
+	changes, err = dst.Import(ctx, snapshot, types.ImportReplace, false)
+	assert.NoError(t, err)
+	assert.Equal(t, types.NodeUnchanged, changes[0].Action)
+	assert.Equal(t, types.NodeUnchanged, changes[1].Action)
+	assert.Equal(t, types.NodeRemoved, changes[2].Action)
+	nodes, err = dst.ListNodes(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+	usage, err = dst.getTenantUsage(ctx, "team-b")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), usage.Size())
+
+	// snapshots without tenants leave the counters as they are
+	tenants := snapshot.Tenants
+	snapshot.Tenants = nil
+	assert.NoError(t, dst.updateTenantUsage(ctx, "team-a", "node0", func(int64) int64 { return units.GiB }))
+	changes, err = dst.Import(ctx, snapshot, types.ImportMerge, false)
+	assert.NoError(t, err)
+	assert.Equal(t, types.NodeUnchanged, changes[0].Action)
+	usage, err = dst.getTenantUsage(ctx, "team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(units.GiB), usage.Size())
+	snapshot.Tenants = tenants
+
+	// invalid snapshots
+	_, err = dst.Import(ctx, snapshot, "overwrite", true)
+	assert.ErrorIs(t, err, types.ErrInvalidSnapshot)
+	snapshot.Version = 2
+	_, err = dst.Import(ctx, snapshot, types.ImportMerge, true)
+	assert.ErrorIs(t, err, types.ErrInvalidSnapshot)
+	snapshot.Version = types.SnapshotVersion
+	snapshot.Tenants["team-a"].Nodes["node4"] = units.GiB
+	_, err = dst.Import(ctx, snapshot, types.ImportMerge, true)
+	assert.ErrorIs(t, err, types.ErrInvalidSnapshot)
+	delete(snapshot.Tenants["team-a"].Nodes, "node4")
+	snapshot.Nodes["node3"] = &types.NodeResourceInfo{}
+	_, err = dst.Import(ctx, snapshot, types.ImportMerge, true)
+	assert.ErrorIs(t, err, types.ErrInvalidSnapshot)
+}
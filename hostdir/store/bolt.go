@@ -0,0 +1,177 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const boltFile = "hostdir.db"
+
+var (
+	boltBucket = []byte("hostdir")
+	// revisions of keys are kept in a separate bucket, so the values are stored as they are
+	boltRevisionBucket = []byte("revisions")
+)
+
+// Bolt keeps data in a local bolt file
+type Bolt struct {
+	db *bolt.DB
+}
+
+// NewBolt opens or creates the bolt file under dataDir.
+// The file is locked while opened, so concurrent processes are serialized.
+func NewBolt(dataDir string) (*Bolt, error) {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(filepath.Join(dataDir, boltFile), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		// the file is locked by others or can't be opened
+		return nil, errors.Mark(err, ErrUnavailable)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltBucket, boltRevisionBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Bolt{db: db}, nil
+}
+
+// Get .
+func (b *Bolt) Get(_ context.Context, key string) (value []byte, err error) {
+	return value, b.db.View(func(tx *bolt.Tx) error {
+		value, err = getFromBucket(tx.Bucket(boltBucket), key)
+		return err
+	})
+}
+
+// GetMulti .
+func (b *Bolt) GetMulti(_ context.Context, keys []string) (map[string][]byte, error) {
+	ans := map[string][]byte{}
+	return ans, b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, key := range keys {
+			value, err := getFromBucket(bucket, key)
+			if err != nil {
+				return err
+			}
+			ans[key] = value
+		}
+		return nil
+	})
+}
+
+// List .
+func (b *Bolt) List(_ context.Context, prefix string) (map[string][]byte, error) {
+	ans := map[string][]byte{}
+	return ans, b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			ans[string(k)] = copyBytes(v)
+		}
+		return nil
+	})
+}
+
+// GetRevision .
+func (b *Bolt) GetRevision(_ context.Context, key string) (value []byte, revision int64, err error) {
+	return value, revision, b.db.View(func(tx *bolt.Tx) error {
+		if value, err = getFromBucket(tx.Bucket(boltBucket), key); err != nil {
+			return err
+		}
+		revision = getRevision(tx, key)
+		return nil
+	})
+}
+
+// Put .
+func (b *Bolt) Put(_ context.Context, key string, value []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return put(tx, key, value)
+	})
+}
+
+// CompareAndSwap .
+func (b *Bolt) CompareAndSwap(_ context.Context, key string, value []byte, revision int64) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if getRevision(tx, key) != revision {
+			return errors.Wrapf(ErrConflict, "key: %s", key)
+		}
+		return put(tx, key, value)
+	})
+}
+
+// Delete .
+func (b *Bolt) Delete(_ context.Context, key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(boltRevisionBucket).Delete([]byte(key)); err != nil {
+			return err
+		}
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+}
+
+// CompareAndDelete .
+func (b *Bolt) CompareAndDelete(_ context.Context, key string, revision int64) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if getRevision(tx, key) != revision {
+			return errors.Wrapf(ErrConflict, "key: %s", key)
+		}
+		if err := tx.Bucket(boltRevisionBucket).Delete([]byte(key)); err != nil {
+			return err
+		}
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+}
+
+// Close .
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
+
+// getRevision returns 0 if the key doesn't exist, or it is written before revisions are kept
+func getRevision(tx *bolt.Tx, key string) int64 {
+	if tx.Bucket(boltBucket).Get([]byte(key)) == nil {
+		return 0
+	}
+	if revision := tx.Bucket(boltRevisionBucket).Get([]byte(key)); len(revision) == 8 {
+		return int64(binary.BigEndian.Uint64(revision))
+	}
+	return 0
+}
+
+func put(tx *bolt.Tx, key string, value []byte) error {
+	revisions := tx.Bucket(boltRevisionBucket)
+	seq, err := revisions.NextSequence()
+	if err != nil {
+		return err
+	}
+	revision := make([]byte, 8)
+	binary.BigEndian.PutUint64(revision, seq)
+	if err := revisions.Put([]byte(key), revision); err != nil {
+		return err
+	}
+	return tx.Bucket(boltBucket).Put([]byte(key), value)
+}
+
+func getFromBucket(bucket *bolt.Bucket, key string) ([]byte, error) {
+	value := bucket.Get([]byte(key))
+	if value == nil {
+		return nil, errors.Wrapf(ErrKeyNotFound, "key: %s", key)
+	}
+	// value is only valid in the transaction
+	return copyBytes(value), nil
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/cockroachdb/errors"
+	coretypes "github.com/projecteru2/core/types"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/namespace"
+)
+
+// txnLimit is the default max number of operations in a txn of etcd
+const txnLimit = 128
+
+// ETCD keeps data in etcd, keys are prefixed by EtcdConfig.Prefix
+type ETCD struct {
+	cli *clientv3.Client
+}
+
+// NewETCD connects to the etcd of config
+func NewETCD(_ context.Context, config coretypes.EtcdConfig) (*ETCD, error) {
+	if len(config.Machines) < 1 {
+		return nil, coretypes.ErrConfigInvaild
+	}
+	var tlsConfig *tls.Config
+	if config.Ca != "" && config.Key != "" && config.Cert != "" {
+		tlsInfo := transport.TLSInfo{
+			TrustedCAFile: config.Ca,
+			KeyFile:       config.Key,
+			CertFile:      config.Cert,
+		}
+		var err error
+		if tlsConfig, err = tlsInfo.ClientConfig(); err != nil {
+			return nil, err
+		}
+	}
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints: config.Machines,
+		Username:  config.Auth.Username,
+		Password:  config.Auth.Password,
+		TLS:       tlsConfig,
+	})
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	cli.KV = namespace.NewKV(cli.KV, config.Prefix)
+	cli.Watcher = namespace.NewWatcher(cli.Watcher, config.Prefix)
+	cli.Lease = namespace.NewLease(cli.Lease, config.Prefix)
+	return &ETCD{cli: cli}, nil
+}
+
+// Get .
+func (e *ETCD) Get(ctx context.Context, key string) ([]byte, error) {
+	value, _, err := e.GetRevision(ctx, key)
+	return value, err
+}
+
+// GetMulti .
+func (e *ETCD) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	ans := map[string][]byte{}
+	for start := 0; start < len(keys); start += txnLimit {
+		end := start + txnLimit
+		if end > len(keys) {
+			end = len(keys)
+		}
+		ops := []clientv3.Op{}
+		for _, key := range keys[start:end] {
+			ops = append(ops, clientv3.OpGet(key))
+		}
+		resp, err := e.cli.Txn(ctx).Then(ops...).Commit()
+		if err != nil {
+			return nil, unavailable(err)
+		}
+		for idx, op := range resp.Responses {
+			kvs := op.GetResponseRange().Kvs
+			if len(kvs) != 1 {
+				return nil, errors.Wrapf(ErrKeyNotFound, "key: %s", keys[start+idx])
+			}
+			ans[string(kvs[0].Key)] = kvs[0].Value
+		}
+	}
+	return ans, nil
+}
+
+// List .
+func (e *ETCD) List(ctx context.Context, prefix string) (map[string][]byte, error) {
+	resp, err := e.cli.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	ans := map[string][]byte{}
+	for _, kv := range resp.Kvs {
+		ans[string(kv.Key)] = kv.Value
+	}
+	return ans, nil
+}
+
+// GetRevision returns the mod revision of key
+func (e *ETCD) GetRevision(ctx context.Context, key string) ([]byte, int64, error) {
+	resp, err := e.cli.Get(ctx, key)
+	if err != nil {
+		return nil, 0, unavailable(err)
+	}
+	if resp.Count != 1 {
+		return nil, 0, errors.Wrapf(ErrKeyNotFound, "key: %s", key)
+	}
+	return resp.Kvs[0].Value, resp.Kvs[0].ModRevision, nil
+}
+
+// Put .
+func (e *ETCD) Put(ctx context.Context, key string, value []byte) error {
+	_, err := e.cli.Put(ctx, key, string(value))
+	return unavailable(err)
+}
+
+// CompareAndSwap compares the mod revision of key in a txn
+func (e *ETCD) CompareAndSwap(ctx context.Context, key string, value []byte, revision int64) error {
+	resp, err := e.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", revision)).
+		Then(clientv3.OpPut(key, string(value))).
+		Commit()
+	if err != nil {
+		return unavailable(err)
+	}
+	if !resp.Succeeded {
+		return errors.Wrapf(ErrConflict, "key: %s", key)
+	}
+	return nil
+}
+
+// Delete .
+func (e *ETCD) Delete(ctx context.Context, key string) error {
+	_, err := e.cli.Delete(ctx, key)
+	return unavailable(err)
+}
+
+// CompareAndDelete .
+func (e *ETCD) CompareAndDelete(ctx context.Context, key string, revision int64) error {
+	resp, err := e.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", revision)).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	if err != nil {
+		return unavailable(err)
+	}
+	if !resp.Succeeded {
+		return errors.Wrapf(ErrConflict, "key: %s", key)
+	}
+	return nil
+}
+
+// Close .
+func (e *ETCD) Close() error {
+	return e.cli.Close()
+}
+
+// unavailable marks errors of etcd client with ErrUnavailable, nil is kept
+func unavailable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return errors.Mark(err, ErrUnavailable)
+}
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/projecteru2/core/store/etcdv3/embedded"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestETCD returns the store of an embedded etcd cluster, which is terminated with t.
+// The store has a client of its own, it is closed by the returned closer.
+func newTestETCD(t *testing.T, prefix string) (*ETCD, func()) {
+	cluster := embedded.NewCluster(t, prefix)
+	s, err := NewETCD(context.Background(), coretypes.EtcdConfig{Machines: cluster.RandClient().Endpoints(), Prefix: prefix})
+	assert.NoError(t, err)
+	return s, func() { assert.NoError(t, s.Close()) }
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Memory keeps data in memory, data is lost when the process exits
+type Memory struct {
+	sync.RWMutex
+	data      map[string][]byte
+	revisions map[string]int64
+	revision  int64
+}
+
+// NewMemory .
+func NewMemory() *Memory {
+	return &Memory{data: map[string][]byte{}, revisions: map[string]int64{}}
+}
+
+// Get .
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+	value, ok := m.data[key]
+	if !ok {
+		return nil, errors.Wrapf(ErrKeyNotFound, "key: %s", key)
+	}
+	return copyBytes(value), nil
+}
+
+// GetMulti .
+func (m *Memory) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	ans := map[string][]byte{}
+	for _, key := range keys {
+		value, err := m.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		ans[key] = value
+	}
+	return ans, nil
+}
+
+// List .
+func (m *Memory) List(_ context.Context, prefix string) (map[string][]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+	ans := map[string][]byte{}
+	for key, value := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			ans[key] = copyBytes(value)
+		}
+	}
+	return ans, nil
+}
+
+// GetRevision .
+func (m *Memory) GetRevision(_ context.Context, key string) ([]byte, int64, error) {
+	m.RLock()
+	defer m.RUnlock()
+	value, ok := m.data[key]
+	if !ok {
+		return nil, 0, errors.Wrapf(ErrKeyNotFound, "key: %s", key)
+	}
+	return copyBytes(value), m.revisions[key], nil
+}
+
+// Put .
+func (m *Memory) Put(_ context.Context, key string, value []byte) error {
+	m.Lock()
+	defer m.Unlock()
+	m.put(key, value)
+	return nil
+}
+
+// CompareAndSwap .
+func (m *Memory) CompareAndSwap(_ context.Context, key string, value []byte, revision int64) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.revisions[key] != revision {
+		return errors.Wrapf(ErrConflict, "key: %s", key)
+	}
+	m.put(key, value)
+	return nil
+}
+
+// Delete .
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.data, key)
+	delete(m.revisions, key)
+	return nil
+}
+
+// CompareAndDelete .
+func (m *Memory) CompareAndDelete(_ context.Context, key string, revision int64) error {
+	m.Lock()
+	defer m.Unlock()
+	if m.revisions[key] != revision {
+		return errors.Wrapf(ErrConflict, "key: %s", key)
+	}
+	delete(m.data, key)
+	delete(m.revisions, key)
+	return nil
+}
+
+// Close .
+func (m *Memory) Close() error {
+	return nil
+}
+
+func (m *Memory) put(key string, value []byte) {
+	m.revision++
+	m.data[key] = copyBytes(value)
+	m.revisions[key] = m.revision
+}
+
+func copyBytes(b []byte) []byte {
+	ans := make([]byte, len(b))
+	copy(ans, b)
+	return ans
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+	coretypes "github.com/projecteru2/core/types"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+var (
+	// ErrKeyNotFound is returned when the key doesn't exist
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrConflict is returned by CompareAndSwap when the key has been changed
+	ErrConflict = errors.New("revision conflict")
+	// ErrUnavailable marks failures to reach the store, e.g. etcd is down, rather than of data
+	ErrUnavailable = errors.New("store unavailable")
+)
+
+// Store is the storage of hostdir plugin
+type Store interface {
+	// Get returns ErrKeyNotFound if the key doesn't exist
+	Get(ctx context.Context, key string) ([]byte, error)
+	// GetMulti returns ErrKeyNotFound if any of the keys doesn't exist
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+	// List returns all keys with the prefix
+	List(ctx context.Context, prefix string) (map[string][]byte, error)
+	// GetRevision returns the value with its revision, the revision changes whenever the key is written.
+	// It returns ErrKeyNotFound if the key doesn't exist
+	GetRevision(ctx context.Context, key string) ([]byte, int64, error)
+	Put(ctx context.Context, key string, value []byte) error
+	// CompareAndSwap puts the value only if the key is still of the revision, 0 means the key doesn't exist.
+	// It returns ErrConflict otherwise
+	CompareAndSwap(ctx context.Context, key string, value []byte, revision int64) error
+	Delete(ctx context.Context, key string) error
+	// CompareAndDelete deletes the key only if it is still of the revision, it returns ErrConflict otherwise
+	CompareAndDelete(ctx context.Context, key string, revision int64) error
+	Close() error
+}
+
+// New creates store according to config
+func New(ctx context.Context, config coretypes.Config, storeConfig types.StoreConfig) (Store, error) {
+	switch storeConfig.Type {
+	case "", types.StoreETCD:
+		return NewETCD(ctx, config.Etcd)
+	case types.StoreMemory:
+		return NewMemory(), nil
+	case types.StoreBolt:
+		return NewBolt(storeConfig.DataDir)
+	default:
+		return nil, errors.Wrapf(types.ErrInvalidConfig, "unknown store type: %s", storeConfig.Type)
+	}
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	config := coretypes.Config{
+		Etcd: coretypes.EtcdConfig{
+			Prefix: "/hostdir",
+		},
+	}
+	t.Run(types.StoreETCD, func(t *testing.T) {
+		s, closer := newTestETCD(t, config.Etcd.Prefix)
+		defer closer()
+		testStore(ctx, t, s)
+	})
+	for _, storeConfig := range []types.StoreConfig{
+		{Type: types.StoreMemory},
+		{Type: types.StoreBolt, DataDir: filepath.Join(t.TempDir(), "data")},
+	} {
+		t.Run(storeConfig.Type, func(t *testing.T) {
+			s, err := New(ctx, config, storeConfig)
+			assert.NoError(t, err)
+			defer s.Close()
+			testStore(ctx, t, s)
+		})
+	}
+
+	_, err := New(ctx, config, types.StoreConfig{Type: "redis"})
+	assert.ErrorIs(t, err, types.ErrInvalidConfig)
+	_, err = New(ctx, config, types.StoreConfig{Type: types.StoreETCD})
+	assert.ErrorIs(t, err, coretypes.ErrConfigInvaild)
+}
+
+func TestBoltPersistence(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	s, err := NewBolt(dataDir)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Put(ctx, "/a/1", []byte("1")))
+	assert.NoError(t, s.Close())
+
+	s, err = NewBolt(dataDir)
+	assert.NoError(t, err)
+	defer s.Close()
+	v, err := s.Get(ctx, "/a/1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), v)
+}
+
+func testStore(ctx context.Context, t *testing.T, s Store) {
+	_, err := s.Get(ctx, "/a/1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	assert.NoError(t, s.Put(ctx, "/a/1", []byte("1")))
+	assert.NoError(t, s.Put(ctx, "/a/2", []byte("2")))
+	assert.NoError(t, s.Put(ctx, "/b/1", []byte("3")))
+
+	v, err := s.Get(ctx, "/a/1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	kvs, err := s.GetMulti(ctx, []string{"/a/1", "/b/1"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"/a/1": []byte("1"), "/b/1": []byte("3")}, kvs)
+	_, err = s.GetMulti(ctx, []string{"/a/1", "/c/1"})
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	kvs, err = s.List(ctx, "/a/")
+	assert.NoError(t, err)
+	assert.Len(t, kvs, 2)
+
+	assert.NoError(t, s.Delete(ctx, "/a/1"))
+	_, err = s.Get(ctx, "/a/1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	_, _, err = s.GetRevision(ctx, "/a/1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// revision 0 creates the key only if it doesn't exist
+	assert.ErrorIs(t, s.CompareAndSwap(ctx, "/a/2", []byte("4"), 0), ErrConflict)
+	assert.NoError(t, s.CompareAndSwap(ctx, "/a/1", []byte("1"), 0))
+	v, rev, err := s.GetRevision(ctx, "/a/1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), v)
+	assert.NotZero(t, rev)
+
+	assert.NoError(t, s.CompareAndSwap(ctx, "/a/1", []byte("5"), rev))
+	assert.ErrorIs(t, s.CompareAndSwap(ctx, "/a/1", []byte("6"), rev), ErrConflict)
+	_, rev1, err := s.GetRevision(ctx, "/a/1")
+	assert.NoError(t, err)
+	assert.NotEqual(t, rev, rev1)
+	// put changes the revision as well
+	assert.NoError(t, s.Put(ctx, "/a/1", []byte("7")))
+	assert.ErrorIs(t, s.CompareAndSwap(ctx, "/a/1", []byte("8"), rev1), ErrConflict)
+	v, err = s.Get(ctx, "/a/1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("7"), v)
+
+	assert.ErrorIs(t, s.CompareAndDelete(ctx, "/a/1", rev1), ErrConflict)
+	_, rev, err = s.GetRevision(ctx, "/a/1")
+	assert.NoError(t, err)
+	assert.NoError(t, s.CompareAndDelete(ctx, "/a/1", rev))
+	_, err = s.Get(ctx, "/a/1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
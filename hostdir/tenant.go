@@ -0,0 +1,205 @@
+package hostdir
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+// tenantUsageKey is where the usage of tenant is kept, it is out of the prefix of node records
+const tenantUsageKey = "/resource/hostdir-tenants/%s"
+
+// getTenantUsage returns the usage of tenant with its revision, an empty one of revision 0 if there is none
+func (p Plugin) getTenantUsage(ctx context.Context, tenant string) (*types.TenantUsage, error) {
+	value, revision, err := p.store.GetRevision(ctx, fmt.Sprintf(tenantUsageKey, tenant))
+	if errors.Is(err, store.ErrKeyNotFound) {
+		return &types.TenantUsage{Tenant: tenant, Nodes: map[string]int64{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	usage := &types.TenantUsage{}
+	if err := json.Unmarshal(value, usage); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode usage of tenant %s", tenant)
+	}
+	if usage.Nodes == nil {
+		usage.Nodes = map[string]int64{}
+	}
+	usage.Revision = revision
+	return usage, nil
+}
+
+// listTenantUsages returns the usage of all tenants in store by tenant
+func (p Plugin) listTenantUsages(ctx context.Context) (map[string]*types.TenantUsage, error) {
+	resps, err := p.store.List(ctx, strings.TrimSuffix(tenantUsageKey, "%s"))
+	if err != nil {
+		return nil, err
+	}
+	ans := map[string]*types.TenantUsage{}
+	for key, value := range resps {
+		usage := &types.TenantUsage{}
+		if err := json.Unmarshal(value, usage); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode usage of tenant %s", key)
+		}
+		ans[usage.Tenant] = usage
+	}
+	return ans, nil
+}
+
+// updateTenantUsage updates the size of node in the usage of tenant by f, the write is compare-and-swap and retried on conflict
+func (p Plugin) updateTenantUsage(ctx context.Context, tenant string, nodename string, f func(int64) int64) error {
+	return p.retryRecordOnConflict(ctx, "tenant "+tenant, func() error {
+		usage, err := p.getTenantUsage(ctx, tenant)
+		if err != nil {
+			return err
+		}
+		if size := f(usage.Nodes[nodename]); size > 0 {
+			usage.Nodes[nodename] = size
+		} else {
+			delete(usage.Nodes, nodename)
+		}
+		data, err := json.Marshal(usage)
+		if err != nil {
+			return err
+		}
+		return p.store.CompareAndSwap(ctx, fmt.Sprintf(tenantUsageKey, tenant), data, usage.Revision)
+	})
+}
+
+// setTenantsUsage counts the workloads of SetNodeResourceUsage in the usage of their tenants.
+// Without delta the workloads are all of the node, so tenants not in them are cleared on the node.
+func (p Plugin) setTenantsUsage(ctx context.Context, nodename string, workloadsResource []plugintypes.WorkloadResource, delta bool, incr bool) error {
+	sizes, err := tenantSizesOf(workloadsResource)
+	if err != nil {
+		return err
+	}
+	if !delta {
+		usages, err := p.listTenantUsages(ctx)
+		if err != nil {
+			return err
+		}
+		for tenant, usage := range usages {
+			if _, ok := sizes[tenant]; !ok && usage.Nodes[nodename] != 0 {
+				sizes[tenant] = 0
+			}
+		}
+	}
+
+	tenants := []string{}
+	for tenant := range sizes {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	for _, tenant := range tenants {
+		size := sizes[tenant]
+		err := p.updateTenantUsage(ctx, tenant, nodename, func(origin int64) int64 {
+			switch {
+			case !delta:
+				return size
+			case incr:
+				return origin + size
+			default:
+				return origin - size
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fixTenantsUsage rebuilds the usage of tenants on node from all workloads of node, the changes are returned as diffs
+func (p Plugin) fixTenantsUsage(ctx context.Context, nodename string, workloadsResource []plugintypes.WorkloadResource) ([]string, error) {
+	sizes, err := tenantSizesOf(workloadsResource)
+	if err != nil {
+		return nil, err
+	}
+	usages, err := p.listTenantUsages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diffs := diffTenantSizes(types.TenantSizesOf(usages, nodename), sizes)
+	if len(diffs) == 0 {
+		return diffs, nil
+	}
+	return diffs, p.setTenantsUsage(ctx, nodename, workloadsResource, false, true)
+}
+
+// removeTenantsUsage drops the node from the usage of all tenants
+func (p Plugin) removeTenantsUsage(ctx context.Context, nodename string) error {
+	return p.setTenantsUsage(ctx, nodename, nil, false, true)
+}
+
+// tenantSizesOf returns the total size of workloads by tenant, workloads without tenant are skipped
+func tenantSizesOf(workloadsResource []plugintypes.WorkloadResource) (map[string]int64, error) {
+	sizes := map[string]int64{}
+	for _, resource := range workloadsResource {
+		workloadResource := &types.WorkloadResource{}
+		if err := workloadResource.Parse(resource); err != nil {
+			return nil, err
+		}
+		if tenant := workloadResource.TenantOf(); tenant != "" {
+			sizes[tenant] += workloadResource.Size()
+		}
+	}
+	return sizes, nil
+}
+
+// checkTenantQuota checks whether the growth of tenant fits in its cluster-wide quota
+func (p Plugin) checkTenantQuota(ctx context.Context, tenant string, growth int64) error {
+	quota := p.hostdirConfig.TenantQuota(tenant)
+	if quota == 0 || growth <= 0 {
+		return nil
+	}
+	usage, err := p.getTenantUsage(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	if used := usage.Size(); used+growth > quota {
+		return &types.PolicyError{
+			Rule:   types.RuleTenantQuota,
+			From:   "tenant " + tenant,
+			Limit:  units.BytesSize(float64(quota)),
+			Actual: fmt.Sprintf("%s used and %s more", units.BytesSize(float64(used)), units.BytesSize(float64(growth))),
+		}
+	}
+	return nil
+}
+
+// GetTenantReports returns the consumption of tenants against their quotas, sorted by tenant.
+// Tenants with quota are included even if they have no usage.
+func (p Plugin) GetTenantReports(ctx context.Context) ([]*types.TenantReport, error) {
+	usages, err := p.listTenantUsages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reports := map[string]*types.TenantReport{}
+	for tenant := range p.hostdirConfig.Tenants {
+		reports[tenant] = &types.TenantReport{Tenant: tenant, Quota: p.hostdirConfig.TenantQuota(tenant)}
+	}
+	for tenant, usage := range usages {
+		report, ok := reports[tenant]
+		if !ok {
+			report = &types.TenantReport{Tenant: tenant}
+			reports[tenant] = report
+		}
+		report.Nodes = len(usage.Nodes)
+		report.Used = usage.Size()
+	}
+	ans := []*types.TenantReport{}
+	for _, report := range reports {
+		ans = append(ans, report)
+	}
+	sort.Slice(ans, func(i, j int) bool { return ans[i].Tenant < ans[j].Tenant })
+	return ans, nil
+}
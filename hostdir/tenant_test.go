@@ -0,0 +1,146 @@
+package hostdir
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/docker/go-units"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+)
+
+func TestTenantQuota(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	st.hostdirConfig.Tenants = map[string]types.TenantConfig{"team-a": {Quota: "20GiB"}, "team-b": {Quota: "1TiB"}}
+	for _, nodename := range []string{"node0", "node1"} {
+		_, err := st.AddNode(ctx, nodename, plugintypes.NodeResourceRequest{"roots": []string{"/data:1TiB"}}, nil)
+		assert.NoError(t, err)
+	}
+	tenantQuotaError := func(err error) {
+		assert.ErrorIs(t, err, types.ErrQuotaExceeded)
+		e := &types.PolicyError{}
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, types.RuleTenantQuota, e.Rule)
+	}
+	used := func(tenant string) int64 {
+		usage, err := st.getTenantUsage(ctx, tenant)
+		assert.NoError(t, err)
+		return usage.Size()
+	}
+
+	req := plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:5GiB"}, "tenant": "team-a"}
+	deploy, err := st.CalculateDeploy(ctx, "node0", 2, req)
+	assert.NoError(t, err)
+	workloadResource := &types.WorkloadResource{}
+	assert.NoError(t, workloadResource.Parse(deploy.WorkloadsResource[0]))
+	assert.Equal(t, "team-a", workloadResource.Tenant)
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, deploy.WorkloadsResource, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10*units.GiB), used("team-a"))
+
+	// the quota is across nodes
+	_, err = st.CalculateDeploy(ctx, "node1", 3, req)
+	tenantQuotaError(err)
+	_, err = st.CalculateDeploy(ctx, "node1", 2, req)
+	assert.NoError(t, err)
+
+	// the app is the tenant if not set
+	_, err = st.CalculateDeploy(ctx, "node1", 3, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:5GiB"}, "app": "team-a"})
+	tenantQuotaError(err)
+
+	// realloc counts the growth
+	_, err = st.CalculateRealloc(ctx, "node0", deploy.WorkloadsResource[0], plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:15GiB"}})
+	tenantQuotaError(err)
+	_, err = st.CalculateRealloc(ctx, "node0", deploy.WorkloadsResource[0], plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:10GiB"}})
+	assert.NoError(t, err)
+	// the delta of realloc is of the tenant, so applying it counts the growth
+	realloc, err := st.CalculateRealloc(ctx, "node0", deploy.WorkloadsResource[0], plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:5GiB"}})
+	assert.NoError(t, err)
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, []plugintypes.WorkloadResource{realloc.DeltaResource}, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(15*units.GiB), used("team-a"))
+	_, err = st.CalculateRealloc(ctx, "node0", realloc.WorkloadResource, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:6GiB"}})
+	tenantQuotaError(err)
+	realloc, err = st.CalculateRealloc(ctx, "node0", realloc.WorkloadResource, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:-5GiB"}})
+	assert.NoError(t, err)
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, []plugintypes.WorkloadResource{realloc.DeltaResource}, true, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10*units.GiB), used("team-a"))
+
+	other, err := st.CalculateDeploy(ctx, "node1", 1, plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img1:/dir1:1GiB"}, "tenant": "team-c"})
+	assert.NoError(t, err)
+	_, err = st.SetNodeResourceUsage(ctx, "node1", nil, nil, other.WorkloadsResource, true, true)
+	assert.NoError(t, err)
+
+	reports, err := st.GetTenantReports(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []*types.TenantReport{
+		{Tenant: "team-a", Nodes: 1, Used: 10 * units.GiB, Quota: 20 * units.GiB},
+		{Tenant: "team-b", Quota: units.TiB},
+		{Tenant: "team-c", Nodes: 1, Used: units.GiB},
+	}, reports)
+
+	// counters are out of node records
+	nodes, err := st.ListNodes(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 2)
+
+	// removed workloads are uncounted
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, deploy.WorkloadsResource[:1], true, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5*units.GiB), used("team-a"))
+
+	// rewriting usage of node clears the tenants without workloads on it
+	_, err = st.SetNodeResourceUsage(ctx, "node0", nil, nil, nil, false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), used("team-a"))
+	assert.Equal(t, int64(units.GiB), used("team-c"))
+}
+
+func TestTenantUsageOfNode(t *testing.T) {
+	ctx := context.Background()
+	st := initHostdir(ctx, t)
+	for _, nodename := range []string{"node0", "node1"} {
+		_, err := st.AddNode(ctx, nodename, plugintypes.NodeResourceRequest{"roots": []string{"/data:1TiB"}}, nil)
+		assert.NoError(t, err)
+	}
+	used := func(tenant string) int64 {
+		usage, err := st.getTenantUsage(ctx, tenant)
+		assert.NoError(t, err)
+		return usage.Size()
+	}
+
+	req := plugintypes.WorkloadResourceRequest{"volumes": []string{"/data/img0:/dir0:5GiB"}, "tenant": "team-a"}
+	deploys := map[string][]plugintypes.WorkloadResource{}
+	for _, nodename := range []string{"node0", "node1"} {
+		deploy, err := st.CalculateDeploy(ctx, nodename, 1, req)
+		assert.NoError(t, err)
+		_, err = st.SetNodeResourceUsage(ctx, nodename, nil, nil, deploy.WorkloadsResource, true, true)
+		assert.NoError(t, err)
+		deploys[nodename] = deploy.WorkloadsResource
+	}
+	assert.Equal(t, int64(10*units.GiB), used("team-a"))
+
+	// fixing the node rebuilds the drifted usage of tenants from workloads
+	assert.NoError(t, st.updateTenantUsage(ctx, "team-a", "node0", func(int64) int64 { return 1 }))
+	assert.NoError(t, st.updateTenantUsage(ctx, "team-b", "node0", func(int64) int64 { return 1 }))
+	resp, err := st.FixNodeResource(ctx, "node0", deploys["node0"])
+	assert.NoError(t, err)
+	assert.Len(t, resp.Diffs, 2)
+	assert.Equal(t, int64(10*units.GiB), used("team-a"))
+	assert.Equal(t, int64(0), used("team-b"))
+	resp, err = st.FixNodeResource(ctx, "node0", deploys["node0"])
+	assert.NoError(t, err)
+	assert.Len(t, resp.Diffs, 0)
+
+	// removing the node drops it from the usage of tenants
+	_, err = st.RemoveNode(ctx, "node0")
+	assert.NoError(t, err)
+	usage, err := st.getTenantUsage(ctx, "team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int64{"node1": 5 * units.GiB}, usage.Nodes)
+}
@@ -0,0 +1,42 @@
+package types
+
+// NodeUsage is the summary of hostdir capacity and usage of a node
+type NodeUsage struct {
+	Nodename string `json:"nodename"`
+	Roots    int    `json:"roots"`
+	Bindings int    `json:"bindings"`
+	// Capacity is the sum of the effective capacity of roots
+	Capacity int64 `json:"capacity"`
+	Used     int64 `json:"used"`
+	Free     int64 `json:"free"`
+}
+
+// RootUsage is the capacity and usage of a root of node
+type RootUsage struct {
+	Path       string  `json:"path"`
+	Size       int64   `json:"size"`
+	Overcommit float64 `json:"overcommit,omitempty"`
+	Reserved   string  `json:"reserved,omitempty"`
+	Pool       string  `json:"pool,omitempty"`
+	Domain     string  `json:"domain"`
+	// Capacity is the effective capacity, after overcommit and reserved are applied
+	Capacity int64 `json:"capacity"`
+	Used     int64 `json:"used"`
+	Free     int64 `json:"free"`
+}
+
+// NodeBinding is a volume binding of node with the workloads using it
+type NodeBinding struct {
+	Binding     string   `json:"binding"`
+	Source      string   `json:"source"`
+	Destination string   `json:"destination"`
+	SizeInBytes int64    `json:"size_in_bytes"`
+	Workloads   []string `json:"workloads"`
+}
+
+// NodeMigration is the migration of the record of a node
+type NodeMigration struct {
+	Nodename string `json:"nodename"`
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+}
@@ -0,0 +1,41 @@
+package types
+
+// Checks of `check`, in the order they run, a check is skipped if any before it fails
+const (
+	CheckConfig   = "config"   // the config file loads
+	CheckSettings = "settings" // the hostdir settings validate, e.g. roots and policies
+	CheckStore    = "store"    // the store is reachable, under the prefix for etcd
+	CheckRecords  = "records"  // the stored records decode under the current schema
+
+	CheckOK      = "ok"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// CheckResult is the result of a check, Code is the error code if it failed
+type CheckResult struct {
+	Name    string         `json:"name"`
+	Status  string         `json:"status"`
+	Code    string         `json:"code,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// CheckReport is the result of all checks of plugin, OK is true only if none of them failed
+type CheckReport struct {
+	Name     string         `json:"name"`
+	Version  string         `json:"version"`
+	Revision string         `json:"revision"`
+	OK       bool           `json:"ok"`
+	Checks   []*CheckResult `json:"checks"`
+}
+
+// RecordsCheck is the result of decoding the stored records.
+// Outdated nodes are of old schema versions, they are migrated on read, or all at once by `admin migrate`.
+// Invalid records can't be decoded or validated, they are keyed by the record, e.g. node node0, with the error.
+type RecordsCheck struct {
+	Nodes    int               `json:"nodes"`
+	Tenants  int               `json:"tenants"`
+	Outdated []string          `json:"outdated,omitempty"`
+	Invalid  map[string]string `json:"invalid,omitempty"`
+}
@@ -0,0 +1,342 @@
+package types
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/jinzhu/configor"
+	"github.com/projecteru2/core/utils"
+)
+
+const (
+	// StoreETCD keeps node records in etcd
+	StoreETCD = "etcd"
+	// StoreMemory keeps node records in memory, mostly for testing
+	StoreMemory = "memory"
+	// StoreBolt keeps node records in a bolt file under the local data directory
+	StoreBolt = "bolt"
+)
+
+const (
+	// QuotaNone doesn't limit the size of directories
+	QuotaNone = "none"
+	// QuotaXFS limits directories by xfs project quota
+	QuotaXFS = "xfs"
+	// QuotaExt4 limits directories by ext4 project quota
+	QuotaExt4 = "ext4"
+	// QuotaLoop mounts an ext4 image file of the limited size on each directory
+	QuotaLoop = "loop"
+)
+
+// StoreConfig selects the storage backend of node records
+type StoreConfig struct {
+	Type    string `yaml:"type" json:"type" default:"etcd"` // etcd, memory or bolt
+	DataDir string `yaml:"data_dir" json:"data_dir"`        // data directory of bolt store
+	// Retries is how many times an update of node record is retried when the record is changed by others, 0 means 10
+	Retries int `yaml:"retries" json:"retries" default:"10"`
+}
+
+// Validate .
+func (c *StoreConfig) Validate() error {
+	switch c.Type {
+	case "", StoreETCD, StoreMemory:
+	case StoreBolt:
+		if c.DataDir == "" {
+			return errors.Wrap(ErrInvalidConfig, "data dir of bolt store must be provided")
+		}
+	default:
+		return errors.Wrapf(ErrInvalidConfig, "unknown store type: %s", c.Type)
+	}
+	if c.Retries < 0 {
+		return errors.Wrapf(ErrInvalidConfig, "negative retries of store: %d", c.Retries)
+	}
+	return nil
+}
+
+// GRPCConfig holds the config of grpc server
+type GRPCConfig struct {
+	Bind     string `yaml:"bind" json:"bind" default:":5005"`
+	CertFile string `yaml:"cert_file" json:"cert_file"` // TLS is enabled if cert and key are provided
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+	CAFile   string `yaml:"ca_file" json:"ca_file"` // client certs are required and verified by it (mTLS)
+}
+
+// Validate .
+func (c *GRPCConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.Wrap(ErrInvalidConfig, "cert and key of grpc must be provided together")
+	}
+	if c.CAFile != "" && c.CertFile == "" {
+		return errors.Wrap(ErrInvalidConfig, "ca of grpc requires cert and key")
+	}
+	return nil
+}
+
+// AgentConfig holds the config of node-side agent, which provisions the sources of bindings
+type AgentConfig struct {
+	UID         int           `yaml:"uid" json:"uid"`                                                                // owner of created directories
+	GID         int           `yaml:"gid" json:"gid"`                                                                // group of created directories
+	Mode        string        `yaml:"mode" json:"mode" default:"0755"`                                               // permission bits of created directories, in octal
+	Quota       string        `yaml:"quota" json:"quota" default:"none"`                                             // none, xfs, ext4 or loop
+	ImageDir    string        `yaml:"image_dir" json:"image_dir"`                                                    // image files of loop quota
+	ProjectFile string        `yaml:"project_file" json:"project_file" default:"/var/lib/eru-hostdir/projects.json"` // project ids of quota assigned to directories
+	ArchiveDir  string        `yaml:"archive_dir" json:"archive_dir"`                                                // archives of removed sources, see Retention
+	Interval    time.Duration `yaml:"interval" json:"interval" default:"10s"`                                        // interval of reconciling bindings
+}
+
+// Validate .
+func (c *AgentConfig) Validate() error {
+	if _, err := c.FileMode(); err != nil {
+		return err
+	}
+	switch c.Quota {
+	case "", QuotaNone:
+	case QuotaXFS, QuotaExt4, QuotaLoop:
+		if c.Quota == QuotaLoop && c.ImageDir == "" {
+			return errors.Wrap(ErrInvalidConfig, "image dir of loop quota must be provided")
+		}
+		if c.ProjectFile == "" {
+			return errors.Wrapf(ErrInvalidConfig, "project file of %s quota must be provided", c.Quota)
+		}
+	default:
+		return errors.Wrapf(ErrInvalidConfig, "unknown quota backend: %s", c.Quota)
+	}
+	if c.ArchiveDir != "" && !filepath.IsAbs(c.ArchiveDir) {
+		return errors.Wrapf(ErrInvalidConfig, "archive dir must be absolute: %s", c.ArchiveDir)
+	}
+	if c.Interval < 0 {
+		return errors.Wrapf(ErrInvalidConfig, "invalid interval of agent: %s", c.Interval)
+	}
+	return nil
+}
+
+// FileMode parses Mode, 0755 by default
+func (c *AgentConfig) FileMode() (uint32, error) {
+	if c.Mode == "" {
+		return 0755, nil
+	}
+	mode, err := strconv.ParseUint(c.Mode, 8, 32)
+	if err != nil || mode > 07777 {
+		return 0, errors.Wrapf(ErrInvalidConfig, "invalid mode of agent: %s", c.Mode)
+	}
+	return uint32(mode), nil
+}
+
+// RootConfig holds the default settings of one root directory
+type RootConfig struct {
+	Path       string  `yaml:"path" json:"path"`
+	Size       string  `yaml:"size" json:"size"`             // used by AddNode when the node doesn't report its roots
+	Overcommit float64 `yaml:"overcommit" json:"overcommit"` // overrides Config.Overcommit for this root
+	Reserved   string  `yaml:"reserved" json:"reserved"`     // overrides Config.Reserved for this root
+	Retention  string  `yaml:"retention" json:"retention"`   // overrides Config.Retention for this root
+	Pool       string  `yaml:"pool" json:"pool"`             // storage class of this root, e.g. ssd, hdd or nvme
+	Domain     string  `yaml:"domain" json:"domain"`         // failure domain of this root, e.g. the device it lives on
+}
+
+// Config holds hostdir specific config, it lives under the `hostdir` section of hostdir.yaml
+type Config struct {
+	Overcommit float64                 `yaml:"overcommit" json:"overcommit" default:"1"`       // ratio between effective capacity and raw capacity
+	Reserved   string                  `yaml:"reserved" json:"reserved"`                       // headroom kept free on each root, e.g. 10GiB or 5%
+	Retention  string                  `yaml:"retention" json:"retention" default:"keep"`      // fate of sources of removed bindings: delete, keep or archive-after-Nd
+	Placement  string                  `yaml:"placement" json:"placement" default:"worst-fit"` // strategy to pick the root of pool bindings: best-fit, worst-fit or round-robin
+	Roots      []RootConfig            `yaml:"roots" json:"roots"`
+	Policies   PoliciesConfig          `yaml:"policies" json:"policies"`
+	Tenants    map[string]TenantConfig `yaml:"tenants" json:"tenants"` // cluster-wide quotas by tenant, see WorkloadResourceRequest.TenantOf
+	Store      StoreConfig             `yaml:"store" json:"store"`
+	Journal    JournalConfig           `yaml:"journal" json:"journal"`
+	GRPC       GRPCConfig              `yaml:"grpc" json:"grpc"`
+	Agent      AgentConfig             `yaml:"agent" json:"agent"`
+}
+
+// LoadConfig reads the `hostdir` section from the config file and validates it
+func LoadConfig(configPath string) (Config, error) {
+	c, err := ReadConfig(configPath)
+	if err != nil {
+		return Config{}, err
+	}
+	return c, c.Validate()
+}
+
+// ReadConfig reads the `hostdir` section from the config file without validation
+func ReadConfig(configPath string) (Config, error) {
+	c := struct {
+		Hostdir Config `yaml:"hostdir"`
+	}{}
+	if err := configor.Load(&c, configPath); err != nil {
+		return Config{}, err
+	}
+	return c.Hostdir, nil
+}
+
+// Validate .
+func (c *Config) Validate() error {
+	if err := c.Store.Validate(); err != nil {
+		return err
+	}
+	if err := c.Journal.Validate(); err != nil {
+		return err
+	}
+	if err := c.GRPC.Validate(); err != nil {
+		return err
+	}
+	if err := c.Agent.Validate(); err != nil {
+		return err
+	}
+	if c.Overcommit < 0 {
+		return errors.Wrapf(ErrInvalidConfig, "invalid overcommit: %v", c.Overcommit)
+	}
+	if _, err := ParseReserved(c.Reserved, 0); err != nil {
+		return errors.Wrapf(ErrInvalidConfig, "invalid reserved: %s", c.Reserved)
+	}
+	if c.Retention != "" {
+		if _, err := ParseRetention(c.Retention); err != nil {
+			return errors.Wrapf(ErrInvalidConfig, "%s", err)
+		}
+	}
+	if c.Placement != "" && !ValidPlacement(c.Placement) {
+		return errors.Wrapf(ErrInvalidConfig, "invalid placement: %s", c.Placement)
+	}
+	if err := c.Policies.Validate(); err != nil {
+		return err
+	}
+	// the pod of node is read from the records of eru-core, which are only in etcd
+	if len(c.Policies.Pods) > 0 && c.Store.Type != "" && c.Store.Type != StoreETCD {
+		return errors.Wrapf(ErrInvalidConfig, "policies of pods need the etcd store of eru-core, not %s", c.Store.Type)
+	}
+	for tenant, tc := range c.Tenants {
+		if !ValidName(tenant) {
+			return errors.Wrapf(ErrInvalidConfig, "invalid tenant: %s", tenant)
+		}
+		if err := tc.Validate(); err != nil {
+			return errors.Wrapf(err, "tenant %s", tenant)
+		}
+	}
+	seen := map[string]bool{}
+	for _, rc := range c.Roots {
+		if !filepath.IsAbs(rc.Path) {
+			return errors.Wrapf(ErrInvalidConfig, "root path must be absolute: %s", rc.Path)
+		}
+		path := filepath.Clean(rc.Path)
+		if seen[path] {
+			return errors.Wrapf(ErrInvalidConfig, "duplicated root: %s", path)
+		}
+		seen[path] = true
+		if size, err := utils.ParseRAMInHuman(rc.Size); err != nil || size < 0 {
+			return errors.Wrapf(ErrInvalidConfig, "invalid size of root %s: %s", path, rc.Size)
+		}
+		if rc.Overcommit < 0 {
+			return errors.Wrapf(ErrInvalidConfig, "invalid overcommit of root %s: %v", path, rc.Overcommit)
+		}
+		if _, err := ParseReserved(rc.Reserved, 0); err != nil {
+			return errors.Wrapf(ErrInvalidConfig, "invalid reserved of root %s: %s", path, rc.Reserved)
+		}
+		if rc.Retention != "" {
+			if _, err := ParseRetention(rc.Retention); err != nil {
+				return errors.Wrapf(ErrInvalidConfig, "invalid retention of root %s: %s", path, rc.Retention)
+			}
+		}
+		if rc.Pool != "" && !ValidName(rc.Pool) {
+			return errors.Wrapf(ErrInvalidConfig, "invalid pool of root %s: %s", path, rc.Pool)
+		}
+		if rc.Domain != "" && !ValidName(rc.Domain) {
+			return errors.Wrapf(ErrInvalidConfig, "invalid domain of root %s: %s", path, rc.Domain)
+		}
+	}
+	return nil
+}
+
+// GetRootConfig returns the config of the root, nil if the root isn't configured
+func (c *Config) GetRootConfig(path string) *RootConfig {
+	path = filepath.Clean(path)
+	for i := range c.Roots {
+		if filepath.Clean(c.Roots[i].Path) == path {
+			return &c.Roots[i]
+		}
+	}
+	return nil
+}
+
+// RootPolicy returns the overcommit ratio and reserved headroom for the root.
+// Priority: node root > root config > global config.
+func (c *Config) RootPolicy(path string, root *Root) (overcommit float64, reserved string) {
+	overcommit, reserved = c.Overcommit, c.Reserved
+	if rc := c.GetRootConfig(path); rc != nil {
+		if rc.Overcommit > 0 {
+			overcommit = rc.Overcommit
+		}
+		if rc.Reserved != "" {
+			reserved = rc.Reserved
+		}
+	}
+	if root != nil {
+		if root.Overcommit > 0 {
+			overcommit = root.Overcommit
+		}
+		if root.Reserved != "" {
+			reserved = root.Reserved
+		}
+	}
+	if overcommit <= 0 {
+		overcommit = 1
+	}
+	return overcommit, reserved
+}
+
+// RetentionOf returns the retention of the source of vb under root.
+// Priority: binding > root config > global config, keep by default.
+func (c *Config) RetentionOf(root string, vb *VolumeBinding) string {
+	if vb.Retention != "" {
+		return vb.Retention
+	}
+	if rc := c.GetRootConfig(root); rc != nil && rc.Retention != "" {
+		return rc.Retention
+	}
+	if c.Retention != "" {
+		return c.Retention
+	}
+	return RetentionKeep
+}
+
+// RootPool returns the pool of the root, empty if it isn't in any pool.
+// Priority: node root > root config.
+func (c *Config) RootPool(path string, root *Root) string {
+	if root != nil && root.Pool != "" {
+		return root.Pool
+	}
+	if rc := c.GetRootConfig(path); rc != nil {
+		return rc.Pool
+	}
+	return ""
+}
+
+// RootDomain returns the failure domain of the root, roots without one are domains of their own.
+// Priority: node root > root config > path.
+func (c *Config) RootDomain(path string, root *Root) string {
+	if root != nil && root.Domain != "" {
+		return root.Domain
+	}
+	if rc := c.GetRootConfig(path); rc != nil && rc.Domain != "" {
+		return rc.Domain
+	}
+	return path
+}
+
+// PlacementOf returns the strategy to pick the root of pool bindings, worst-fit by default
+func (c *Config) PlacementOf() string {
+	if c.Placement != "" {
+		return c.Placement
+	}
+	return PlacementWorstFit
+}
+
+// DefaultRoots returns the roots defined in config, it is used when a node doesn't report its roots
+func (c *Config) DefaultRoots() Roots {
+	roots := Roots{}
+	for _, rc := range c.Roots {
+		size, _ := utils.ParseRAMInHuman(rc.Size)
+		roots[filepath.Clean(rc.Path)] = &Root{Size: size, Pool: rc.Pool, Domain: rc.Domain}
+	}
+	return roots
+}
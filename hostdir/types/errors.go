@@ -1,11 +1,69 @@
 package types
 
-import "github.com/cockroachdb/errors"
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+)
 
 var (
-	ErrInvalidCapacity = errors.New("invalid capacity")
-	ErrInvalidVolume   = errors.New("invalid volume")
-	ErrInvalidStorage  = errors.New("invalid storage")
-	ErrInvalidVolumes  = errors.New("invalid volumes")
-	ErrInvalidParams   = errors.New("invalid io parameters")
+	ErrInvalidCapacity  = errors.New("invalid capacity")
+	ErrInvalidVolume    = errors.New("invalid volume")
+	ErrInvalidStorage   = errors.New("invalid storage")
+	ErrInvalidVolumes   = errors.New("invalid volumes")
+	ErrInvalidParams    = errors.New("invalid io parameters")
+	ErrInvalidConfig    = errors.New("invalid hostdir config")
+	ErrInvalidRoot      = errors.New("invalid root")
+	ErrRootNotFound     = errors.New("no root for volume")
+	ErrInvalidRetention = errors.New("invalid retention")
+	ErrInvalidSnapshot  = errors.New("invalid snapshot")
+	ErrInvalidSchema    = errors.New("invalid schema version")
+
+	ErrInsufficientCapacity = errors.New("insufficient hostdir capacity")
+	ErrConflict             = errors.New("conflicting update of record")
+	ErrQuotaExceeded        = errors.New("quota policy violated")
+	ErrJournalNotFound      = errors.New("journal entry not found")
 )
+
+// InsufficientCapacityError tells which binding can't fit in its root, it matches ErrInsufficientCapacity
+type InsufficientCapacityError struct {
+	Node      string
+	Root      string
+	Binding   string
+	Need      int64
+	Available int64
+}
+
+// Error .
+func (e *InsufficientCapacityError) Error() string {
+	return fmt.Sprintf("%s: binding %s needs %d bytes more on root %s of node %s, only %d bytes available",
+		ErrInsufficientCapacity, e.Binding, e.Need, e.Root, e.Node, e.Available)
+}
+
+// Unwrap .
+func (e *InsufficientCapacityError) Unwrap() error {
+	return ErrInsufficientCapacity
+}
+
+// PolicyError tells which rule of quota policy is violated, it matches ErrQuotaExceeded
+type PolicyError struct {
+	Rule    string
+	From    string
+	Binding string
+	Limit   string
+	Actual  string
+}
+
+// Error .
+func (e *PolicyError) Error() string {
+	msg := fmt.Sprintf("%s: %s of %s is %s, got %s", ErrQuotaExceeded, e.Rule, e.From, e.Limit, e.Actual)
+	if e.Binding != "" {
+		msg += " by binding " + e.Binding
+	}
+	return msg
+}
+
+// Unwrap .
+func (e *PolicyError) Unwrap() error {
+	return ErrQuotaExceeded
+}
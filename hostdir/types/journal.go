@@ -0,0 +1,161 @@
+package types
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/docker/go-units"
+)
+
+const (
+	// JournalStore keeps the journal in the store of node records, bounded by JournalConfig.MaxEntries
+	JournalStore = "store"
+	// JournalFile appends the journal to a local file in JSON lines
+	JournalFile = "file"
+	// JournalNone disables the journal, it is the default
+	JournalNone = "none"
+
+	// DefaultJournalEntries is the number of entries kept by store journal if not configured
+	DefaultJournalEntries = 10000
+)
+
+// JournalConfig selects where the audit journal of mutating calls is kept, it is disabled by default.
+// Store journal takes a global sequence for each entry, which serializes the writes of all nodes,
+// so file journal suits clusters of frequent writes better.
+type JournalConfig struct {
+	Type       string `yaml:"type" json:"type" default:"none"` // store, file or none
+	Path       string `yaml:"path" json:"path"`                // file of file journal
+	MaxEntries int    `yaml:"max_entries" json:"max_entries"`  // entries kept by store journal, older ones are overwritten, 0 means 10000
+}
+
+// Validate .
+func (c *JournalConfig) Validate() error {
+	switch c.Type {
+	case "", JournalStore, JournalNone:
+	case JournalFile:
+		if c.Path == "" {
+			return errors.Wrap(ErrInvalidConfig, "path of file journal must be provided")
+		}
+	default:
+		return errors.Wrapf(ErrInvalidConfig, "unknown journal type: %s", c.Type)
+	}
+	if c.MaxEntries < 0 {
+		return errors.Wrapf(ErrInvalidConfig, "negative max entries of journal: %d", c.MaxEntries)
+	}
+	return nil
+}
+
+// JournalEntry is a change of node made by a mutating call.
+// Before and After are the node records around the change, null if the node doesn't exist,
+// they are kept as written, so use DecodeNodeResourceInfo to read them.
+type JournalEntry struct {
+	ID      int64           `json:"id"`
+	Time    time.Time       `json:"time"`
+	Command string          `json:"command"`
+	Node    string          `json:"node"`
+	Input   json.RawMessage `json:"input,omitempty"`
+	Before  json.RawMessage `json:"before"`
+	After   json.RawMessage `json:"after"`
+}
+
+// JournalFilter selects entries of journal, zero values match all.
+// Since is inclusive and Until is exclusive, Limit keeps the latest entries.
+type JournalFilter struct {
+	Node  string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// Match .
+func (f *JournalFilter) Match(entry *JournalEntry) bool {
+	switch {
+	case f.Node != "" && entry.Node != f.Node:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !entry.Time.Before(f.Until):
+		return false
+	default:
+		return true
+	}
+}
+
+// RecordChange is a change of an item of node record, e.g. capacity /eru, usage /eru or binding /eru/img0:/data.
+// From or To is empty if the item is absent on that side.
+type RecordChange struct {
+	Item string `json:"item"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// JournalRevert restores the node of a journal entry to its record before the entry,
+// Changes are made to the current record, which must be the record after the entry.
+type JournalRevert struct {
+	Entry   *JournalEntry   `json:"entry"`
+	Changes []*RecordChange `json:"changes"`
+	Applied bool            `json:"applied"`
+}
+
+// DiffNodeRecords returns the changes from one node record to another, null or empty records are of absent nodes.
+// Records of old schema versions are migrated before compared.
+func DiffNodeRecords(from, to []byte) ([]*RecordChange, error) {
+	fromItems, err := nodeRecordItems(from)
+	if err != nil {
+		return nil, err
+	}
+	toItems, err := nodeRecordItems(to)
+	if err != nil {
+		return nil, err
+	}
+	changes := []*RecordChange{}
+	for item, value := range fromItems {
+		if value != toItems[item] {
+			changes = append(changes, &RecordChange{Item: item, From: value, To: toItems[item]})
+		}
+	}
+	for item, value := range toItems {
+		if _, ok := fromItems[item]; !ok {
+			changes = append(changes, &RecordChange{Item: item, To: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Item < changes[j].Item })
+	return changes, nil
+}
+
+// nodeRecordItems flattens node record into items with human readable values
+func nodeRecordItems(data []byte) (map[string]string, error) {
+	items := map[string]string{}
+	if len(data) == 0 || string(data) == "null" {
+		return items, nil
+	}
+	info, _, err := DecodeNodeResourceInfo(data)
+	if err != nil {
+		return nil, err
+	}
+	items["node"] = "exists"
+	size := func(n int64) string { return units.BytesSize(float64(n)) }
+	if info.Capacity != nil {
+		for path, root := range info.Capacity.Roots {
+			items["capacity "+path] = size(root.Size)
+		}
+	}
+	if info.Usage != nil {
+		for path, root := range info.Usage.Roots {
+			items["usage "+path] = size(root.Size)
+		}
+	}
+	bindings := map[string]int64{}
+	for _, vb := range info.Bindings {
+		bindings[vb.Source+":"+vb.Destination] += vb.SizeInBytes
+	}
+	for binding, n := range bindings {
+		items["binding "+binding] = size(n)
+	}
+	for _, task := range info.Cleanups {
+		items["cleanup "+task.Source] = task.Retention
+	}
+	return items, nil
+}
@@ -0,0 +1,27 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournalConfig(t *testing.T) {
+	for _, c := range []JournalConfig{{}, {Type: JournalStore, MaxEntries: 10}, {Type: JournalNone}, {Type: JournalFile, Path: "/tmp/journal.log"}} {
+		assert.NoError(t, c.Validate())
+	}
+	for _, c := range []JournalConfig{{Type: "etcd"}, {Type: JournalFile}, {MaxEntries: -1}} {
+		assert.ErrorIs(t, c.Validate(), ErrInvalidConfig)
+	}
+}
+
+func TestJournalFilter(t *testing.T) {
+	now := time.Now()
+	entry := &JournalEntry{Time: now, Node: "node0"}
+	assert.True(t, (&JournalFilter{}).Match(entry))
+	assert.True(t, (&JournalFilter{Node: "node0", Since: now, Until: now.Add(time.Second)}).Match(entry))
+	assert.False(t, (&JournalFilter{Node: "node1"}).Match(entry))
+	assert.False(t, (&JournalFilter{Since: now.Add(time.Second)}).Match(entry))
+	assert.False(t, (&JournalFilter{Until: now}).Match(entry))
+}
@@ -1,42 +1,367 @@
 package types
 
 import (
-	"github.com/mitchellh/mapstructure"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
 	resourcetypes "github.com/projecteru2/core/resource/types"
+	"github.com/projecteru2/core/utils"
 )
 
-// NodeResource indicate node cpumem resource
-type NodeResource struct{}
+// Root is a host directory which holds the sources of volume bindings.
+// Overcommit and Reserved are optional, see Config.RootPolicy.
+// Pool is the storage class of root, e.g. ssd, bindings requested from a pool are placed on its roots, see Config.RootPool.
+// Domain is the failure domain of root, e.g. the device it lives on, see Config.RootDomain.
+type Root struct {
+	Size       int64   `json:"size" mapstructure:"size"`
+	Overcommit float64 `json:"overcommit,omitempty" mapstructure:"overcommit"`
+	Reserved   string  `json:"reserved,omitempty" mapstructure:"reserved"`
+	Pool       string  `json:"pool,omitempty" mapstructure:"pool"`
+	Domain     string  `json:"domain,omitempty" mapstructure:"domain"`
+}
+
+// NewRoot parses root string, format => path:size[:overcommit[:reserved]][:options], options => pool=name[,domain=name]
+func NewRoot(root string) (string, *Root, error) {
+	parts := strings.Split(root, ":")
+	r := &Root{}
+	if last := parts[len(parts)-1]; len(parts) > 2 && strings.Contains(last, "=") {
+		if err := r.parseOptions(last); err != nil {
+			return "", nil, errors.Wrapf(ErrInvalidRoot, "%s: %s", root, err)
+		}
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) < 2 || len(parts) > 4 {
+		return "", nil, errors.Wrap(ErrInvalidRoot, root)
+	}
+	var err error
+	if r.Size, err = utils.ParseRAMInHuman(parts[1]); err != nil {
+		return "", nil, errors.Wrap(ErrInvalidRoot, root)
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		if r.Overcommit, err = strconv.ParseFloat(parts[2], 64); err != nil {
+			return "", nil, errors.Wrap(ErrInvalidRoot, root)
+		}
+	}
+	if len(parts) > 3 {
+		r.Reserved = parts[3]
+	}
+	return filepath.Clean(parts[0]), r, ValidateRoot(parts[0], r)
+}
+
+func (r *Root) parseOptions(options string) error {
+	for _, option := range strings.Split(options, ",") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "pool":
+			r.Pool = value
+		case "domain":
+			r.Domain = value
+		default:
+			return errors.Errorf("unknown option: %s", key)
+		}
+	}
+	return nil
+}
+
+// ValidateRoot .
+func ValidateRoot(path string, r *Root) error {
+	if !filepath.IsAbs(path) {
+		return errors.Wrapf(ErrInvalidRoot, "root must be absolute: %s", path)
+	}
+	if r.Size < 0 {
+		return errors.Wrapf(ErrInvalidRoot, "negative size of root %s: %d", path, r.Size)
+	}
+	if r.Overcommit < 0 {
+		return errors.Wrapf(ErrInvalidRoot, "negative overcommit of root %s: %v", path, r.Overcommit)
+	}
+	if _, err := ParseReserved(r.Reserved, r.Size); err != nil {
+		return errors.Wrapf(ErrInvalidRoot, "invalid reserved of root %s: %s", path, r.Reserved)
+	}
+	if r.Pool != "" && !ValidName(r.Pool) {
+		return errors.Wrapf(ErrInvalidRoot, "invalid pool of root %s: %s", path, r.Pool)
+	}
+	if r.Domain != "" && !ValidName(r.Domain) {
+		return errors.Wrapf(ErrInvalidRoot, "invalid domain of root %s: %s", path, r.Domain)
+	}
+	return nil
+}
+
+// ValidName checks the name of pool, failure domain or anti-affinity group, it is made of letters, digits, dots, dashes and underscores
+func ValidName(pool string) bool {
+	if pool == "" {
+		return false
+	}
+	for _, c := range pool {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseReserved parses the reserved headroom, an absolute amount like 10GiB or a percentage of size like 5%
+func ParseReserved(reserved string, size int64) (int64, error) {
+	if strings.HasSuffix(reserved, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(reserved, "%"), 64)
+		if err != nil || percent < 0 || percent > 100 {
+			return 0, errors.Wrapf(ErrInvalidRoot, "invalid reserved percentage: %s", reserved)
+		}
+		return int64(float64(size) * percent / 100), nil
+	}
+	ans, err := utils.ParseRAMInHuman(reserved)
+	if err != nil || ans < 0 {
+		return 0, errors.Wrapf(ErrInvalidRoot, "invalid reserved: %s", reserved)
+	}
+	return ans, nil
+}
+
+// EffectiveSize returns the capacity of root after applying overcommit ratio and reserved headroom
+func EffectiveSize(cfg *Config, path string, r *Root) (int64, error) {
+	overcommit, reserved := cfg.RootPolicy(path, r)
+	reservedSize, err := ParseReserved(reserved, r.Size)
+	if err != nil {
+		return 0, err
+	}
+	ans := int64(float64(r.Size)*overcommit) - reservedSize
+	if ans < 0 {
+		ans = 0
+	}
+	return ans, nil
+}
+
+// Roots maps root path to root
+type Roots map[string]*Root
+
+// DeepCopy .
+func (rs Roots) DeepCopy() Roots {
+	ans := Roots{}
+	for path, r := range rs {
+		r1 := *r
+		ans[path] = &r1
+	}
+	return ans
+}
+
+// Find returns the longest root which contains the source
+func (rs Roots) Find(source string) (string, bool) {
+	source = filepath.Clean(source)
+	ans := ""
+	for path := range rs {
+		if (source == path || strings.HasPrefix(source, path+"/") || path == "/") && len(path) > len(ans) {
+			ans = path
+		}
+	}
+	return ans, ans != ""
+}
+
+// Bucket sums the size of volume bindings by root
+func (rs Roots) Bucket(vbs VolumeBindings) (map[string]int64, error) {
+	ans := map[string]int64{}
+	for _, vb := range vbs {
+		path, ok := rs.Find(vb.Source)
+		if !ok {
+			return nil, errors.Wrapf(ErrRootNotFound, "%s", vb.Source)
+		}
+		ans[path] += vb.SizeInBytes
+	}
+	return ans, nil
+}
+
+// InPool returns the paths of roots in the pool, sorted
+func (rs Roots) InPool(cfg *Config, pool string) []string {
+	ans := []string{}
+	for path, r := range rs {
+		if cfg.RootPool(path, r) == pool {
+			ans = append(ans, path)
+		}
+	}
+	sort.Strings(ans)
+	return ans
+}
+
+// Size returns the total raw size of roots
+func (rs Roots) Size() int64 {
+	ans := int64(0)
+	for _, r := range rs {
+		ans += r.Size
+	}
+	return ans
+}
+
+// NodeResource indicate node hostdir resource
+type NodeResource struct {
+	Roots Roots `json:"roots" mapstructure:"roots"`
+}
+
+// NewNodeResource .
+func NewNodeResource() *NodeResource {
+	return &NodeResource{Roots: Roots{}}
+}
 
 func (r *NodeResource) AsRawParams() resourcetypes.RawParams {
-	return resourcetypes.RawParams{}
+	return resourcetypes.RawParams{
+		"roots": r.Roots,
+	}
 }
 
 // Parse .
 func (r *NodeResource) Parse(rawParams resourcetypes.RawParams) error {
-	return mapstructure.Decode(rawParams, r)
+	// use json because roots may be decoded from json already
+	body, err := json.Marshal(rawParams)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, r); err != nil {
+		return err
+	}
+	if r.Roots == nil {
+		r.Roots = Roots{}
+	}
+	return nil
+}
+
+// DeepCopy .
+func (r *NodeResource) DeepCopy() *NodeResource {
+	return &NodeResource{Roots: r.Roots.DeepCopy()}
+}
+
+// Add adds sizes of roots
+func (r *NodeResource) Add(r1 *NodeResource) {
+	for path, root := range r1.Roots {
+		if _, ok := r.Roots[path]; !ok {
+			r.Roots[path] = &Root{}
+		}
+		r.Roots[path].Size += root.Size
+	}
+}
+
+// Sub subtracts sizes of roots
+func (r *NodeResource) Sub(r1 *NodeResource) {
+	for path, root := range r1.Roots {
+		if _, ok := r.Roots[path]; !ok {
+			r.Roots[path] = &Root{}
+		}
+		r.Roots[path].Size -= root.Size
+	}
 }
 
 func (r *NodeResource) Validate() error {
+	for path, root := range r.Roots {
+		if err := ValidateRoot(path, root); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// NodeResourceInfo indicate cpumem capacity and usage
+// NodeResourceInfo indicate hostdir capacity and usage.
+// Bindings are the volumes of workloads on the node, they are provisioned by the agent.
+// Cleanups are the sources of removed bindings waiting for their retention.
+// SchemaVersion is the version of stored record, see MigrateNodeRecord.
+// Revision is the revision of stored record it is read at, it is not stored.
 type NodeResourceInfo struct {
-	Capacity *NodeResource `json:"capacity"`
-	Usage    *NodeResource `json:"usage"`
+	Revision      int64          `json:"-"`
+	SchemaVersion int            `json:"schema_version"`
+	Capacity      *NodeResource  `json:"capacity"`
+	Usage         *NodeResource  `json:"usage"`
+	Bindings      VolumeBindings `json:"bindings,omitempty"`
+	Cleanups      []*CleanupTask `json:"cleanups,omitempty"`
+}
+
+// DeepCopy .
+func (n *NodeResourceInfo) DeepCopy() *NodeResourceInfo {
+	ans := &NodeResourceInfo{
+		Revision:      n.Revision,
+		SchemaVersion: n.SchemaVersion,
+		Capacity:      n.Capacity.DeepCopy(),
+		Usage:         n.Usage.DeepCopy(),
+	}
+	for _, vb := range n.Bindings {
+		ans.Bindings = append(ans.Bindings, vb.DeepCopy())
+	}
+	for _, task := range n.Cleanups {
+		task1 := *task
+		ans.Cleanups = append(ans.Cleanups, &task1)
+	}
+	return ans
 }
 
 func (n *NodeResourceInfo) Validate() error {
+	if n.Capacity == nil {
+		return ErrInvalidCapacity
+	}
+	if n.Capacity.Roots == nil {
+		n.Capacity.Roots = Roots{}
+	}
+	if n.Usage == nil {
+		n.Usage = NewNodeResource()
+	}
+	if n.Usage.Roots == nil {
+		n.Usage.Roots = Roots{}
+	}
+	for path := range n.Capacity.Roots {
+		if _, ok := n.Usage.Roots[path]; !ok {
+			n.Usage.Roots[path] = &Root{}
+		}
+	}
+	for path, root := range n.Usage.Roots {
+		if _, ok := n.Capacity.Roots[path]; !ok && root.Size != 0 {
+			return errors.Wrapf(ErrInvalidCapacity, "root %s is used but not in capacity", path)
+		}
+	}
 	if err := n.Capacity.Validate(); err != nil {
 		return err
 	}
 	return n.Usage.Validate()
 }
 
+// GetEffectiveCapacity returns the effective capacity of each root
+func (n *NodeResourceInfo) GetEffectiveCapacity(cfg *Config) (map[string]int64, error) {
+	ans := map[string]int64{}
+	for path, root := range n.Capacity.Roots {
+		size, err := EffectiveSize(cfg, path, root)
+		if err != nil {
+			return nil, err
+		}
+		ans[path] = size
+	}
+	return ans, nil
+}
+
+// GetAvailableResource returns the free space of each root, based on effective capacity
+func (n *NodeResourceInfo) GetAvailableResource(cfg *Config) (map[string]int64, error) {
+	ans, err := n.GetEffectiveCapacity(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for path := range ans {
+		if used, ok := n.Usage.Roots[path]; ok {
+			ans[path] -= used.Size
+		}
+	}
+	return ans, nil
+}
+
 // NodeResourceRequest includes all possible fields passed by eru-core for editing node, it not parsed!
-type NodeResourceRequest struct{}
+type NodeResourceRequest struct {
+	Roots Roots `json:"roots" mapstructure:"roots"`
+}
 
 func (n *NodeResourceRequest) Parse(rawParams resourcetypes.RawParams) error {
-	return mapstructure.Decode(rawParams, n)
+	n.Roots = Roots{}
+	for _, s := range rawParams.OneOfStringSlice("roots", "hostdir-roots") {
+		path, root, err := NewRoot(s)
+		if err != nil {
+			return err
+		}
+		if _, ok := n.Roots[path]; ok {
+			return errors.Wrapf(ErrInvalidRoot, "duplicated root: %s", path)
+		}
+		n.Roots[path] = root
+	}
+	return nil
 }
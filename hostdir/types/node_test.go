@@ -0,0 +1,88 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/docker/go-units"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRoot(t *testing.T) {
+	path, root, err := NewRoot("/data/:1TiB:1.5:10%")
+	assert.NoError(t, err)
+	assert.Equal(t, "/data", path)
+	assert.Equal(t, int64(units.TiB), root.Size)
+	assert.Equal(t, 1.5, root.Overcommit)
+	assert.Equal(t, "10%", root.Reserved)
+
+	// pool option
+	path, root, err = NewRoot("/ssd:1TiB:1.5:pool=ssd")
+	assert.NoError(t, err)
+	assert.Equal(t, "/ssd", path)
+	assert.Equal(t, 1.5, root.Overcommit)
+	assert.Equal(t, "ssd", root.Pool)
+	_, root, err = NewRoot("/ssd:1TiB:pool=ssd,domain=sda")
+	assert.NoError(t, err)
+	assert.Equal(t, "sda", root.Domain)
+	_, _, err = NewRoot("/ssd:1TiB:domain=8:16")
+	assert.ErrorIs(t, err, ErrInvalidRoot)
+	_, _, err = NewRoot("/ssd:1TiB:pool=s/d")
+	assert.ErrorIs(t, err, ErrInvalidRoot)
+	_, _, err = NewRoot("/ssd:1TiB:class=ssd")
+	assert.ErrorIs(t, err, ErrInvalidRoot)
+
+	_, _, err = NewRoot("/data")
+	assert.ErrorIs(t, err, ErrInvalidRoot)
+	_, _, err = NewRoot("/data:1TiB:abc")
+	assert.ErrorIs(t, err, ErrInvalidRoot)
+	_, _, err = NewRoot("/data:1TiB:1:120%")
+	assert.ErrorIs(t, err, ErrInvalidRoot)
+}
+
+func TestEffectiveSize(t *testing.T) {
+	cfg := &Config{
+		Overcommit: 2,
+		Reserved:   "10GiB",
+		Roots: []RootConfig{
+			{Path: "/data", Reserved: "50%"},
+		},
+	}
+	root := &Root{Size: 100 * units.GiB}
+
+	// global config
+	size, err := EffectiveSize(cfg, "/eru", root)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(190*units.GiB), size)
+
+	// root config overrides global config
+	size, err = EffectiveSize(cfg, "/data", root)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(150*units.GiB), size)
+
+	// node root overrides config
+	root.Overcommit = 1
+	root.Reserved = "0"
+	size, err = EffectiveSize(cfg, "/data", root)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100*units.GiB), size)
+
+	// never negative
+	size, err = EffectiveSize(&Config{Reserved: "1TiB"}, "/data", &Root{Size: units.GiB})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+}
+
+func TestRootsFind(t *testing.T) {
+	roots := Roots{
+		"/eru":      &Root{},
+		"/eru/fast": &Root{},
+	}
+	path, ok := roots.Find("/eru/img0")
+	assert.True(t, ok)
+	assert.Equal(t, "/eru", path)
+	path, ok = roots.Find("/eru/fast/img0")
+	assert.True(t, ok)
+	assert.Equal(t, "/eru/fast", path)
+	_, ok = roots.Find("/eru-other/img0")
+	assert.False(t, ok)
+}
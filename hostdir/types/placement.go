@@ -0,0 +1,20 @@
+package types
+
+const (
+	// PlacementBestFit picks the root with the least space left that fits, large free space is kept for large bindings
+	PlacementBestFit = "best-fit"
+	// PlacementWorstFit picks the root with the most space left, usage is balanced across roots
+	PlacementWorstFit = "worst-fit"
+	// PlacementRoundRobin picks the roots in turn, skipping those that don't fit
+	PlacementRoundRobin = "round-robin"
+)
+
+// ValidPlacement checks the placement strategy
+func ValidPlacement(s string) bool {
+	switch s {
+	case PlacementBestFit, PlacementWorstFit, PlacementRoundRobin:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,21 @@
+package types
+
+// DeployPlan is the placement of a deploy across nodes, it is calculated without touching the stored state
+type DeployPlan struct {
+	Count    int         `json:"count"`
+	Placed   int         `json:"placed"`
+	Nodes    []*NodePlan `json:"nodes"`
+	Unplaced int         `json:"unplaced"`
+}
+
+// NodePlan is the placement of a deploy on one node
+type NodePlan struct {
+	Nodename string `json:"nodename"`
+	// Capacity is how many workloads the node can take before the deploy
+	Capacity int `json:"capacity"`
+	Deploy   int `json:"deploy"`
+	// Remaining is how many more workloads the node can take after the deploy
+	Remaining int `json:"remaining"`
+	// Free is the available space of each root after the deploy
+	Free map[string]int64 `json:"free"`
+}
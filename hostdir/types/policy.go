@@ -0,0 +1,160 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+	"github.com/docker/go-units"
+	"github.com/projecteru2/core/utils"
+)
+
+// DefaultCorePrefix is the default etcd prefix of eru-core
+const DefaultCorePrefix = "/eru"
+
+// rules of quota policy, they name the violated rule in PolicyError
+const (
+	RuleMaxBindingSize = "max_binding_size"
+	RuleMaxBindings    = "max_bindings"
+	RuleMaxTotal       = "max_total"
+	RuleGranularity    = "granularity"
+)
+
+// PolicyConfig is a quota policy of workloads, empty rules are unlimited.
+// Sizes are human readable, e.g. 100GiB, sizes of bindings are rounded up to a multiple of granularity.
+type PolicyConfig struct {
+	MaxBindingSize string `yaml:"max_binding_size" json:"max_binding_size"`
+	MaxBindings    int    `yaml:"max_bindings" json:"max_bindings"`
+	MaxTotal       string `yaml:"max_total" json:"max_total"`
+	Granularity    string `yaml:"granularity" json:"granularity"`
+}
+
+// Validate .
+func (pc PolicyConfig) Validate() error {
+	for rule, size := range map[string]string{RuleMaxBindingSize: pc.MaxBindingSize, RuleMaxTotal: pc.MaxTotal, RuleGranularity: pc.Granularity} {
+		if size == "" {
+			continue
+		}
+		if n, err := utils.ParseRAMInHuman(size); err != nil || n <= 0 {
+			return errors.Wrapf(ErrInvalidConfig, "invalid %s: %s", rule, size)
+		}
+	}
+	if pc.MaxBindings < 0 {
+		return errors.Wrapf(ErrInvalidConfig, "invalid %s: %d", RuleMaxBindings, pc.MaxBindings)
+	}
+	return nil
+}
+
+// PoliciesConfig holds quota policies of workloads by pod of node and by app of request, see Config.PolicyOf.
+// The pods of nodes are read from the records of eru-core in etcd, under CorePrefix.
+type PoliciesConfig struct {
+	Default    PolicyConfig            `yaml:"default" json:"default"`
+	Pods       map[string]PolicyConfig `yaml:"pods" json:"pods"`
+	Apps       map[string]PolicyConfig `yaml:"apps" json:"apps"`
+	CorePrefix string                  `yaml:"core_prefix" json:"core_prefix"`
+}
+
+// CorePrefixOf returns the etcd prefix of eru-core, the default one of eru-core if not set
+func (pc PoliciesConfig) CorePrefixOf() string {
+	if pc.CorePrefix != "" {
+		return pc.CorePrefix
+	}
+	return DefaultCorePrefix
+}
+
+// Validate .
+func (pc PoliciesConfig) Validate() error {
+	if err := pc.Default.Validate(); err != nil {
+		return errors.Wrap(err, "default policy")
+	}
+	for pod, policy := range pc.Pods {
+		if err := policy.Validate(); err != nil {
+			return errors.Wrapf(err, "policy of pod %s", pod)
+		}
+	}
+	for app, policy := range pc.Apps {
+		if err := policy.Validate(); err != nil {
+			return errors.Wrapf(err, "policy of app %s", app)
+		}
+	}
+	return nil
+}
+
+// Policy is the quota policy of a workload, zero values are unlimited.
+// From tells where each rule comes from, e.g. app mysql.
+type Policy struct {
+	MaxBindingSize int64
+	MaxBindings    int
+	MaxTotal       int64
+	Granularity    int64
+	From           map[string]string
+}
+
+// PolicyOf returns the quota policy of workloads of app on nodes of pod, each rule is taken by priority: app > pod > default
+func (c *Config) PolicyOf(pod, app string) *Policy {
+	policy := &Policy{From: map[string]string{}}
+	apply := func(pc PolicyConfig, from string) {
+		if pc.MaxBindingSize != "" {
+			policy.MaxBindingSize, _ = utils.ParseRAMInHuman(pc.MaxBindingSize)
+			policy.From[RuleMaxBindingSize] = from
+		}
+		if pc.MaxBindings > 0 {
+			policy.MaxBindings = pc.MaxBindings
+			policy.From[RuleMaxBindings] = from
+		}
+		if pc.MaxTotal != "" {
+			policy.MaxTotal, _ = utils.ParseRAMInHuman(pc.MaxTotal)
+			policy.From[RuleMaxTotal] = from
+		}
+		if pc.Granularity != "" {
+			policy.Granularity, _ = utils.ParseRAMInHuman(pc.Granularity)
+			policy.From[RuleGranularity] = from
+		}
+	}
+	apply(c.Policies.Default, "default policy")
+	if pc, ok := c.Policies.Pods[pod]; ok && pod != "" {
+		apply(pc, "policy of pod "+pod)
+	}
+	if pc, ok := c.Policies.Apps[app]; ok && app != "" {
+		apply(pc, "policy of app "+app)
+	}
+	return policy
+}
+
+// Round rounds the positive sizes of bindings up to a multiple of granularity
+func (p *Policy) Round(vbs VolumeBindings) {
+	if p.Granularity <= 0 {
+		return
+	}
+	for _, vb := range vbs {
+		if vb.SizeInBytes > 0 && vb.SizeInBytes%p.Granularity != 0 {
+			vb.SizeInBytes = (vb.SizeInBytes/p.Granularity + 1) * p.Granularity
+		}
+	}
+}
+
+// Check checks the bindings of a workload against the policy
+func (p *Policy) Check(vbs VolumeBindings) error {
+	if p.MaxBindings > 0 && len(vbs) > p.MaxBindings {
+		return &PolicyError{Rule: RuleMaxBindings, From: p.From[RuleMaxBindings], Limit: fmt.Sprint(p.MaxBindings), Actual: fmt.Sprint(len(vbs))}
+	}
+	total := int64(0)
+	for _, vb := range vbs {
+		if p.MaxBindingSize > 0 && vb.SizeInBytes > p.MaxBindingSize {
+			return &PolicyError{
+				Rule: RuleMaxBindingSize, From: p.From[RuleMaxBindingSize], Binding: vb.ToString(),
+				Limit: units.BytesSize(float64(p.MaxBindingSize)), Actual: units.BytesSize(float64(vb.SizeInBytes)),
+			}
+		}
+		if p.Granularity > 0 && vb.SizeInBytes%p.Granularity != 0 {
+			return &PolicyError{
+				Rule: RuleGranularity, From: p.From[RuleGranularity], Binding: vb.ToString(),
+				Limit: units.BytesSize(float64(p.Granularity)), Actual: units.BytesSize(float64(vb.SizeInBytes)),
+			}
+		}
+		total += vb.SizeInBytes
+	}
+	if p.MaxTotal > 0 && total > p.MaxTotal {
+		return &PolicyError{Rule: RuleMaxTotal, From: p.From[RuleMaxTotal], Limit: units.BytesSize(float64(p.MaxTotal)), Actual: units.BytesSize(float64(total))}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/docker/go-units"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyOf(t *testing.T) {
+	cfg := &Config{
+		Policies: PoliciesConfig{
+			Default: PolicyConfig{MaxBindingSize: "100GiB", MaxBindings: 4, Granularity: "1GiB"},
+			Pods:    map[string]PolicyConfig{"db": {MaxBindingSize: "1TiB", MaxTotal: "2TiB"}},
+			Apps:    map[string]PolicyConfig{"mysql": {MaxBindings: 2}},
+		},
+	}
+	assert.NoError(t, cfg.Validate())
+
+	// app > pod > default
+	policy := cfg.PolicyOf("db", "mysql")
+	assert.Equal(t, int64(units.TiB), policy.MaxBindingSize)
+	assert.Equal(t, 2, policy.MaxBindings)
+	assert.Equal(t, int64(2*units.TiB), policy.MaxTotal)
+	assert.Equal(t, int64(units.GiB), policy.Granularity)
+	assert.Equal(t, "policy of pod db", policy.From[RuleMaxBindingSize])
+	assert.Equal(t, "policy of app mysql", policy.From[RuleMaxBindings])
+	assert.Equal(t, "default policy", policy.From[RuleGranularity])
+
+	policy = cfg.PolicyOf("web", "")
+	assert.Equal(t, int64(100*units.GiB), policy.MaxBindingSize)
+	assert.Equal(t, int64(0), policy.MaxTotal)
+
+	// pods of nodes are only known by the etcd of eru-core
+	cfg.Store = StoreConfig{Type: StoreBolt, DataDir: "/tmp/hostdir"}
+	assert.ErrorIs(t, cfg.Validate(), ErrInvalidConfig)
+	cfg.Store = StoreConfig{Type: StoreETCD}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Policies.Apps["redis"] = PolicyConfig{MaxTotal: "lots"}
+	assert.ErrorIs(t, cfg.Validate(), ErrInvalidConfig)
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy := (&Config{Policies: PoliciesConfig{
+		Default: PolicyConfig{MaxBindingSize: "10GiB", MaxBindings: 2, MaxTotal: "15GiB", Granularity: "1GiB"},
+	}}).PolicyOf("", "")
+
+	// sizes are rounded up by granularity
+	vbs, err := NewVolumeBindings([]string{"/eru/img0:/dir0:1500MiB", "/eru/img1:/dir1:0"})
+	assert.NoError(t, err)
+	policy.Round(vbs)
+	assert.Equal(t, int64(2*units.GiB), vbs[0].SizeInBytes)
+	assert.Equal(t, int64(0), vbs[1].SizeInBytes)
+	assert.NoError(t, policy.Check(vbs))
+
+	check := func(rule string, volumes ...string) {
+		vbs, err := NewVolumeBindings(volumes)
+		assert.NoError(t, err)
+		err = policy.Check(vbs)
+		assert.ErrorIs(t, err, ErrQuotaExceeded)
+		e := &PolicyError{}
+		assert.True(t, errors.As(err, &e))
+		assert.Equal(t, rule, e.Rule)
+		assert.Contains(t, err.Error(), rule)
+	}
+	check(RuleMaxBindingSize, "/eru/img0:/dir0:11GiB")
+	check(RuleMaxBindings, "/eru/img0:/dir0:1GiB", "/eru/img1:/dir1:1GiB", "/eru/img2:/dir2:1GiB")
+	check(RuleMaxTotal, "/eru/img0:/dir0:10GiB", "/eru/img1:/dir1:10GiB")
+	check(RuleGranularity, "/eru/img0:/dir0:1500MiB")
+}
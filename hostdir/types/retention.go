@@ -0,0 +1,72 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	// RetentionDelete deletes the source as soon as its binding is removed
+	RetentionDelete = "delete"
+	// RetentionKeep never touches the source, it stays as an orphan until removed by hand
+	RetentionKeep = "keep"
+	// RetentionArchiveAfter archives and deletes the source N days after its binding is removed, format => archive-after-Nd
+	RetentionArchiveAfter = "archive-after-"
+)
+
+// Retention decides the fate of the source of a removed binding
+type Retention struct {
+	Mode string
+	Days int
+}
+
+// ParseRetention parses delete, keep or archive-after-Nd
+func ParseRetention(s string) (*Retention, error) {
+	switch {
+	case s == RetentionDelete, s == RetentionKeep:
+		return &Retention{Mode: s}, nil
+	case strings.HasPrefix(s, RetentionArchiveAfter):
+		days, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(s, RetentionArchiveAfter), "d"))
+		if err != nil || days < 0 {
+			return nil, errors.Wrapf(ErrInvalidRetention, "%s", s)
+		}
+		return &Retention{Mode: RetentionArchiveAfter, Days: days}, nil
+	default:
+		return nil, errors.Wrapf(ErrInvalidRetention, "%s", s)
+	}
+}
+
+// String .
+func (r Retention) String() string {
+	if r.Mode == RetentionArchiveAfter {
+		return RetentionArchiveAfter + strconv.Itoa(r.Days) + "d"
+	}
+	return r.Mode
+}
+
+// CleanupTask is the pending cleanup of the source of a removed binding
+type CleanupTask struct {
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	SizeInBytes int64     `json:"size_in_bytes"`
+	Retention   string    `json:"retention"`
+	RemovedAt   time.Time `json:"removed_at"`
+}
+
+// DueAt returns when the task should be executed, false if the source is kept
+func (t *CleanupTask) DueAt() (time.Time, bool) {
+	r, err := ParseRetention(t.Retention)
+	if err != nil || r.Mode == RetentionKeep {
+		return time.Time{}, false
+	}
+	return t.RemovedAt.AddDate(0, 0, r.Days), true
+}
+
+// IsDue .
+func (t *CleanupTask) IsDue(now time.Time) bool {
+	dueAt, ok := t.DueAt()
+	return ok && !now.Before(dueAt)
+}
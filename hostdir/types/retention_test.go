@@ -0,0 +1,39 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetention(t *testing.T) {
+	for _, s := range []string{"delete", "keep", "archive-after-7d", "archive-after-0d"} {
+		r, err := ParseRetention(s)
+		assert.NoError(t, err)
+		assert.Equal(t, s, r.String())
+	}
+	r, err := ParseRetention("archive-after-3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, r.Days)
+
+	for _, s := range []string{"", "remove", "archive-after-", "archive-after--1d"} {
+		_, err := ParseRetention(s)
+		assert.ErrorIs(t, err, ErrInvalidRetention)
+	}
+}
+
+func TestCleanupTask(t *testing.T) {
+	now := time.Now()
+	task := &CleanupTask{Source: "/eru/img0", Retention: "delete", RemovedAt: now}
+	assert.True(t, task.IsDue(now))
+
+	task.Retention = "archive-after-7d"
+	assert.False(t, task.IsDue(now.AddDate(0, 0, 6)))
+	assert.True(t, task.IsDue(now.AddDate(0, 0, 7)))
+
+	task.Retention = "keep"
+	_, ok := task.DueAt()
+	assert.False(t, ok)
+	assert.False(t, task.IsDue(now.AddDate(10, 0, 0)))
+}
@@ -0,0 +1,145 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+)
+
+// SchemaVersion is the version of node records written by this plugin.
+// Bump it together with a migration from the previous version when the record format changes.
+const SchemaVersion = 1
+
+// Migration upgrades a node record from version From to From+1.
+// The record is the decoded json object, since old formats may not decode into NodeResourceInfo.
+type Migration struct {
+	From        int
+	Description string
+	Migrate     func(record map[string]any) error
+}
+
+var migrations = map[int]*Migration{}
+
+// RegisterMigration registers the migration from m.From, it panics if there is one already
+func RegisterMigration(m *Migration) {
+	if _, ok := migrations[m.From]; ok {
+		panic(fmt.Sprintf("duplicated migration from schema version %d", m.From))
+	}
+	migrations[m.From] = m
+}
+
+// GetMigrations returns the migrations from version to SchemaVersion in order
+func GetMigrations(version int) ([]*Migration, error) {
+	if version > SchemaVersion {
+		return nil, errors.Wrapf(ErrInvalidSchema, "version %d is newer than %d, upgrade the plugin first", version, SchemaVersion)
+	}
+	ans := []*Migration{}
+	for v := version; v < SchemaVersion; v++ {
+		m, ok := migrations[v]
+		if !ok {
+			return nil, errors.Wrapf(ErrInvalidSchema, "no migration from version %d", v)
+		}
+		ans = append(ans, m)
+	}
+	return ans, nil
+}
+
+// MigrateNodeRecord upgrades a stored node record to SchemaVersion, the version of data is returned as well.
+// Records without version are of version 0.
+func MigrateNodeRecord(data []byte) ([]byte, int, error) {
+	record := map[string]any{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, 0, err
+	}
+	version := 0
+	if v, ok := record["schema_version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f != float64(int(f)) || f < 0 {
+			return nil, 0, errors.Wrapf(ErrInvalidSchema, "%v", v)
+		}
+		version = int(f)
+	}
+	ms, err := GetMigrations(version)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(ms) == 0 {
+		return data, version, nil
+	}
+	for _, m := range ms {
+		if err := m.Migrate(record); err != nil {
+			return nil, 0, errors.Wrapf(err, "failed to migrate from version %d", m.From)
+		}
+		record["schema_version"] = m.From + 1
+	}
+	data, err = json.Marshal(record)
+	return data, version, err
+}
+
+// DecodeNodeResourceInfo decodes a stored node record, it is migrated to SchemaVersion first.
+// The version of data is returned as well.
+func DecodeNodeResourceInfo(data []byte) (*NodeResourceInfo, int, error) {
+	data, version, err := MigrateNodeRecord(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	n := &NodeResourceInfo{}
+	if err := json.Unmarshal(data, n); err != nil {
+		return nil, 0, err
+	}
+	return n, version, nil
+}
+
+func init() {
+	RegisterMigration(&Migration{
+		From:        0,
+		Description: "add usage of every root in capacity, remove empty usage of roots not in capacity",
+		Migrate:     migrateV0,
+	})
+}
+
+// migrateV0 normalizes records written before versioning, they could be written without usage of roots
+func migrateV0(record map[string]any) error {
+	roots := func(key string) (map[string]any, error) {
+		resource, ok := record[key].(map[string]any)
+		if !ok {
+			if record[key] != nil {
+				return nil, errors.Wrapf(ErrInvalidSchema, "invalid %s: %v", key, record[key])
+			}
+			resource = map[string]any{}
+			record[key] = resource
+		}
+		rs, ok := resource["roots"].(map[string]any)
+		if !ok {
+			if resource["roots"] != nil {
+				return nil, errors.Wrapf(ErrInvalidSchema, "invalid roots of %s: %v", key, resource["roots"])
+			}
+			rs = map[string]any{}
+			resource["roots"] = rs
+		}
+		return rs, nil
+	}
+	capacity, err := roots("capacity")
+	if err != nil {
+		return err
+	}
+	usage, err := roots("usage")
+	if err != nil {
+		return err
+	}
+	for path := range capacity {
+		if _, ok := usage[path]; !ok {
+			usage[path] = map[string]any{"size": 0}
+		}
+	}
+	for path, root := range usage {
+		if _, ok := capacity[path]; ok {
+			continue
+		}
+		if r, ok := root.(map[string]any); ok && (r["size"] == nil || r["size"] == float64(0)) {
+			delete(usage, path)
+		}
+	}
+	return nil
+}
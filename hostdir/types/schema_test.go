@@ -0,0 +1,68 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSchemaFixtures migrates the fixture of every schema version, they hold the same node
+// and must end up as the fixture of the current version
+func TestSchemaFixtures(t *testing.T) {
+	golden, err := os.ReadFile(filepath.Join("testdata", "schema", fmt.Sprintf("v%d.json", SchemaVersion)))
+	assert.NoError(t, err)
+	expected := &NodeResourceInfo{}
+	assert.NoError(t, json.Unmarshal(golden, expected))
+	assert.NoError(t, expected.Validate())
+	expectedJSON, err := json.Marshal(expected)
+	assert.NoError(t, err)
+
+	for version := 0; version <= SchemaVersion; version++ {
+		data, err := os.ReadFile(filepath.Join("testdata", "schema", fmt.Sprintf("v%d.json", version)))
+		assert.NoError(t, err, "no fixture of version %d", version)
+
+		n, from, err := DecodeNodeResourceInfo(data)
+		assert.NoError(t, err)
+		assert.Equal(t, version, from)
+		assert.Equal(t, SchemaVersion, n.SchemaVersion)
+		assert.NoError(t, n.Validate())
+		actualJSON, err := json.Marshal(n)
+		assert.NoError(t, err)
+		assert.JSONEq(t, string(expectedJSON), string(actualJSON), "version %d", version)
+
+		// migrating again changes nothing
+		migrated, err := json.Marshal(n)
+		assert.NoError(t, err)
+		again, from, err := MigrateNodeRecord(migrated)
+		assert.NoError(t, err)
+		assert.Equal(t, SchemaVersion, from)
+		assert.JSONEq(t, string(migrated), string(again))
+	}
+}
+
+func TestMigrateNodeRecord(t *testing.T) {
+	// newer records are refused, they may have fields this version would drop
+	_, _, err := MigrateNodeRecord([]byte(fmt.Sprintf(`{"schema_version": %d}`, SchemaVersion+1)))
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+
+	_, _, err = MigrateNodeRecord([]byte(`{"schema_version": "1"}`))
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+	_, _, err = MigrateNodeRecord([]byte(`{"schema_version": 0.5}`))
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+	_, _, err = MigrateNodeRecord([]byte(`{"capacity": []}`))
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+	_, _, err = MigrateNodeRecord([]byte(`[]`))
+	assert.Error(t, err)
+
+	// every version up to the current one has a migration
+	for version := 0; version < SchemaVersion; version++ {
+		ms, err := GetMigrations(version)
+		assert.NoError(t, err)
+		assert.Len(t, ms, SchemaVersion-version)
+	}
+	assert.Panics(t, func() { RegisterMigration(&Migration{From: 0}) })
+}
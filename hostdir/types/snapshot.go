@@ -0,0 +1,95 @@
+package types
+
+import (
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// SnapshotVersion is the version of snapshot written by this plugin
+const SnapshotVersion = 1
+
+const (
+	// ImportMerge keeps the nodes in store which aren't in the snapshot
+	ImportMerge = "merge"
+	// ImportReplace removes the nodes in store which aren't in the snapshot
+	ImportReplace = "replace"
+)
+
+const (
+	NodeAdded     = "add"
+	NodeUpdated   = "update"
+	NodeRemoved   = "remove"
+	NodeUnchanged = "unchanged"
+)
+
+// Snapshot is the state of all nodes, bindings and cleanups included, it is used for backup and migration
+type Snapshot struct {
+	Version int `json:"version"`
+	// Prefix is the etcd prefix it was exported from, only for reference
+	Prefix     string                       `json:"prefix"`
+	ExportedAt time.Time                    `json:"exported_at"`
+	Nodes      map[string]*NodeResourceInfo `json:"nodes"`
+	// Tenants are the usage counters of tenants by tenant, nil for snapshots exported before tenants,
+	// the counters in store are left as they are by import then
+	Tenants map[string]*TenantUsage `json:"tenants"`
+}
+
+// Validate .
+func (s *Snapshot) Validate() error {
+	if s.Version != SnapshotVersion {
+		return errors.Wrapf(ErrInvalidSnapshot, "unsupported version %d, expect %d", s.Version, SnapshotVersion)
+	}
+	for nodename, nodeResourceInfo := range s.Nodes {
+		if nodename == "" {
+			return errors.Wrap(ErrInvalidSnapshot, "empty nodename")
+		}
+		if nodeResourceInfo == nil {
+			return errors.Wrapf(ErrInvalidSnapshot, "no resource of node %s", nodename)
+		}
+		if nodeResourceInfo.SchemaVersion > SchemaVersion {
+			return errors.Wrapf(ErrInvalidSnapshot, "node %s: schema version %d is newer than %d", nodename, nodeResourceInfo.SchemaVersion, SchemaVersion)
+		}
+		if err := nodeResourceInfo.Validate(); err != nil {
+			return errors.Wrapf(ErrInvalidSnapshot, "node %s: %s", nodename, err)
+		}
+		// bindings of different workloads may share a source, so they are validated one by one
+		for _, vb := range nodeResourceInfo.Bindings {
+			if err := vb.Validate(); err != nil {
+				return errors.Wrapf(ErrInvalidSnapshot, "node %s: %s", nodename, err)
+			}
+		}
+	}
+	for tenant, usage := range s.Tenants {
+		if usage == nil || usage.Tenant != tenant {
+			return errors.Wrapf(ErrInvalidSnapshot, "mismatched usage of tenant %s", tenant)
+		}
+		for nodename, size := range usage.Nodes {
+			if _, ok := s.Nodes[nodename]; !ok {
+				return errors.Wrapf(ErrInvalidSnapshot, "tenant %s: usage of unknown node %s", tenant, nodename)
+			}
+			if size < 0 {
+				return errors.Wrapf(ErrInvalidSnapshot, "tenant %s: negative usage on node %s", tenant, nodename)
+			}
+		}
+	}
+	return nil
+}
+
+// TenantSizesOf returns the usage of tenants on node by tenant out of the usages of all tenants
+func TenantSizesOf(usages map[string]*TenantUsage, nodename string) map[string]int64 {
+	ans := map[string]int64{}
+	for tenant, usage := range usages {
+		if size := usage.Nodes[nodename]; size != 0 {
+			ans[tenant] = size
+		}
+	}
+	return ans
+}
+
+// NodeChange is the change of a node made by import
+type NodeChange struct {
+	Nodename string   `json:"nodename"`
+	Action   string   `json:"action"`
+	Diffs    []string `json:"diffs,omitempty"`
+}
@@ -0,0 +1,56 @@
+package types
+
+import (
+	"github.com/cockroachdb/errors"
+	"github.com/projecteru2/core/utils"
+)
+
+// RuleTenantQuota is the rule of cluster-wide quota of tenant, it names the violated rule in PolicyError
+const RuleTenantQuota = "tenant_quota"
+
+// TenantConfig is the cluster-wide limit of a tenant, quota is the total size of its bindings across nodes, e.g. 10TiB
+type TenantConfig struct {
+	Quota string `yaml:"quota" json:"quota"`
+}
+
+// Validate .
+func (tc TenantConfig) Validate() error {
+	if n, err := utils.ParseRAMInHuman(tc.Quota); err != nil || n <= 0 {
+		return errors.Wrapf(ErrInvalidConfig, "invalid quota: %s", tc.Quota)
+	}
+	return nil
+}
+
+// TenantQuota returns the quota of tenant in bytes, 0 means unlimited
+func (c *Config) TenantQuota(tenant string) int64 {
+	tc, ok := c.Tenants[tenant]
+	if !ok || tenant == "" {
+		return 0
+	}
+	quota, _ := utils.ParseRAMInHuman(tc.Quota)
+	return quota
+}
+
+// TenantUsage is the total size of bindings of a tenant by node, it is kept in store and updated with usage of nodes
+type TenantUsage struct {
+	Revision int64            `json:"-"`
+	Tenant   string           `json:"tenant"`
+	Nodes    map[string]int64 `json:"nodes"`
+}
+
+// Size returns the total size of bindings of tenant across nodes
+func (t *TenantUsage) Size() int64 {
+	ans := int64(0)
+	for _, size := range t.Nodes {
+		ans += size
+	}
+	return ans
+}
+
+// TenantReport is the consumption of a tenant against its quota, quota 0 means unlimited
+type TenantReport struct {
+	Tenant string `json:"tenant"`
+	Nodes  int    `json:"nodes"`
+	Used   int64  `json:"used"`
+	Quota  int64  `json:"quota"`
+}
@@ -4,17 +4,57 @@ import (
 	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/cockroachdb/errors"
 	"github.com/projecteru2/core/utils"
 )
 
-// VolumeBinding format =>  pool/image:dst[:flags][:size][:read_IOPS:write_IOPS:read_bytes:write_bytes]
+// poolPrefix is the prefix of source requesting a pool
+const poolPrefix = "pool="
+
+// VolumeBinding format => src:dst[:size[:options]], options => key=value[,key=value]
+// The source can be pool=name instead of a path, a directory on a root of the pool is picked by deploy, see Root.Pool.
+// Supported options:
+//   - uid, gid and mode: owner, group and permission bits (in octal) of source, they are passed to engine
+//   - retention: see ParseRetention
+//   - anti_affinity: the anti-affinity group of the workload, set by deploy on pool bindings, see WorkloadResourceRequest.AntiAffinity
+//   - from_source and from_destination: only used by realloc, the binding replaces the existing one
+//     with the given source or destination, the allocation is kept and size is added to it
+//
+// It can also be written as an object, see volumeBindingObject.
 type VolumeBinding struct {
 	Source      string
+	Pool        string `json:"pool,omitempty" mapstructure:"pool"`
 	Destination string `json:"destination" mapstructure:"destination"`
 	SizeInBytes int64  `json:"size_in_bytes" mapstructure:"size_in_bytes"`
+	Retention   string `json:"retention,omitempty" mapstructure:"retention"`
+	UID         *int   `json:"uid,omitempty" mapstructure:"uid"`
+	GID         *int   `json:"gid,omitempty" mapstructure:"gid"`
+	Mode        string `json:"mode,omitempty" mapstructure:"mode"`
+	// AntiAffinity is the anti-affinity group the source of pool binding is picked for
+	AntiAffinity string `json:"anti_affinity,omitempty" mapstructure:"anti_affinity"`
+
+	FromSource      string `json:"from_source,omitempty" mapstructure:"from_source"`
+	FromDestination string `json:"from_destination,omitempty" mapstructure:"from_destination"`
+}
+
+// volumeBindingObject is the object form of VolumeBinding, size is human readable, e.g. 10GiB
+type volumeBindingObject struct {
+	Source       string `json:"source"`
+	Pool         string `json:"pool"`
+	Destination  string `json:"destination"`
+	Size         string `json:"size"`
+	SizeInBytes  int64  `json:"size_in_bytes"`
+	Retention    string `json:"retention"`
+	UID          *int   `json:"uid"`
+	GID          *int   `json:"gid"`
+	Mode         string `json:"mode"`
+	AntiAffinity string `json:"anti_affinity"`
+
+	FromSource      string `json:"from_source"`
+	FromDestination string `json:"from_destination"`
 }
 
 func (vb *VolumeBinding) GetSource() string {
@@ -26,41 +66,216 @@ func (vb *VolumeBinding) GetMapKey() [2]string {
 }
 
 func (vb *VolumeBinding) DeepCopy() *VolumeBinding {
-	return &VolumeBinding{
-		Source:      vb.Source,
-		Destination: vb.Destination,
-		SizeInBytes: vb.SizeInBytes,
+	vb1 := *vb
+	if vb.UID != nil {
+		uid := *vb.UID
+		vb1.UID = &uid
+	}
+	if vb.GID != nil {
+		gid := *vb.GID
+		vb1.GID = &gid
 	}
+	return &vb1
+}
+
+// Equal .
+func (vb *VolumeBinding) Equal(vb1 *VolumeBinding) bool {
+	return vb.Source == vb1.Source &&
+		vb.Pool == vb1.Pool &&
+		vb.Destination == vb1.Destination &&
+		vb.SizeInBytes == vb1.SizeInBytes &&
+		vb.Retention == vb1.Retention &&
+		vb.AntiAffinity == vb1.AntiAffinity &&
+		vb.FromSource == vb1.FromSource &&
+		vb.FromDestination == vb1.FromDestination &&
+		vb.SameAttrs(vb1)
+}
+
+// IsMove returns whether the binding replaces an existing one in realloc
+func (vb *VolumeBinding) IsMove() bool {
+	return vb.FromSource != "" || vb.FromDestination != ""
+}
+
+// MovedFrom returns the map key of the binding replaced by vb
+func (vb *VolumeBinding) MovedFrom() [2]string {
+	ans := vb.GetMapKey()
+	if vb.FromSource != "" {
+		ans[0] = vb.FromSource
+	}
+	if vb.FromDestination != "" {
+		ans[1] = vb.FromDestination
+	}
+	return ans
+}
+
+// SameAttrs returns whether the owner, group and mode are the same
+func (vb *VolumeBinding) SameAttrs(vb1 *VolumeBinding) bool {
+	return equalID(vb.UID, vb1.UID) && equalID(vb.GID, vb1.GID) && vb.Mode == vb1.Mode
+}
+
+// MergeAttrs overrides the options of vb by the ones set in vb1
+func (vb *VolumeBinding) MergeAttrs(vb1 *VolumeBinding) {
+	if vb1.Retention != "" {
+		vb.Retention = vb1.Retention
+	}
+	if vb1.UID != nil {
+		uid := *vb1.UID
+		vb.UID = &uid
+	}
+	if vb1.GID != nil {
+		gid := *vb1.GID
+		vb.GID = &gid
+	}
+	if vb1.Mode != "" {
+		vb.Mode = vb1.Mode
+	}
+	if vb1.AntiAffinity != "" {
+		vb.AntiAffinity = vb1.AntiAffinity
+	}
+}
+
+// FileMode returns the parsed mode, false if mode isn't set
+func (vb *VolumeBinding) FileMode() (uint32, bool) {
+	if vb.Mode == "" {
+		return 0, false
+	}
+	mode, err := strconv.ParseUint(vb.Mode, 8, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(mode), true
+}
+
+func equalID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 // NewVolumeBinding returns pointer of VolumeBinding
 func NewVolumeBinding(volume string) (_ *VolumeBinding, err error) {
-	var (
-		src, dst string
-		size     int64
-	)
-
-	switch parts := strings.Split(volume, ":"); len(parts) {
-	case 2:
-		src, dst = parts[0], parts[1]
-	case 3:
-		src, dst = parts[0], parts[1]
-		if size, err = utils.ParseRAMInHuman(parts[2]); err != nil {
+	parts := strings.Split(volume, ":")
+	if len(parts) < 2 || len(parts) > 4 {
+		return nil, errors.Wrap(ErrInvalidVolume, volume)
+	}
+	vb := &VolumeBinding{
+		Source:      parts[0],
+		Destination: parts[1],
+	}
+	vb.parsePool()
+	if len(parts) > 2 && parts[2] != "" {
+		if vb.SizeInBytes, err = utils.ParseRAMInHuman(parts[2]); err != nil {
 			return nil, errors.Wrapf(ErrInvalidVolume, volume)
 		}
-	default:
-		return nil, errors.Wrap(ErrInvalidVolume, volume)
 	}
+	if len(parts) > 3 {
+		if err = vb.parseOptions(parts[3]); err != nil {
+			return nil, errors.Wrapf(ErrInvalidVolume, "%s: %s", volume, err)
+		}
+	}
+
+	return vb, vb.Validate()
+}
 
+// newVolumeBindingFromObject returns pointer of VolumeBinding written in object form
+func newVolumeBindingFromObject(o *volumeBindingObject) (_ *VolumeBinding, err error) {
 	vb := &VolumeBinding{
-		Source:      src,
-		Destination: dst,
-		SizeInBytes: size,
-	}
+		Source:       o.Source,
+		Pool:         o.Pool,
+		Destination:  o.Destination,
+		SizeInBytes:  o.SizeInBytes,
+		Retention:    o.Retention,
+		UID:          o.UID,
+		GID:          o.GID,
+		Mode:         o.Mode,
+		AntiAffinity: o.AntiAffinity,
 
+		FromSource:      o.FromSource,
+		FromDestination: o.FromDestination,
+	}
+	if o.Size != "" {
+		if vb.SizeInBytes, err = utils.ParseRAMInHuman(o.Size); err != nil {
+			return nil, errors.Wrapf(ErrInvalidVolume, "invalid size: %s", o.Size)
+		}
+	}
+	vb.parsePool()
 	return vb, vb.Validate()
 }
 
+// parsePool moves the pool in source form to Pool
+func (vb *VolumeBinding) parsePool() {
+	if pool, ok := strings.CutPrefix(vb.Source, poolPrefix); ok && vb.Pool == "" {
+		vb.Source, vb.Pool = "", pool
+	}
+}
+
+// IsPool returns true if the source is to be picked from a pool
+func (vb *VolumeBinding) IsPool() bool {
+	return vb.Pool != "" && vb.Source == ""
+}
+
+func (vb *VolumeBinding) parseOptions(options string) error {
+	for _, option := range strings.Split(options, ",") {
+		if option == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "retention":
+			vb.Retention = value
+		case "uid", "gid":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.Errorf("invalid %s: %s", key, value)
+			}
+			if key == "uid" {
+				vb.UID = &id
+			} else {
+				vb.GID = &id
+			}
+		case "mode":
+			vb.Mode = value
+		case "anti_affinity":
+			vb.AntiAffinity = value
+		case "from_source":
+			vb.FromSource = value
+		case "from_destination":
+			vb.FromDestination = value
+		default:
+			return errors.Errorf("unknown option: %s", key)
+		}
+	}
+	return nil
+}
+
+// options returns the options in string form, withPlugin includes the ones only used by plugin
+func (vb VolumeBinding) options(withPlugin bool) string {
+	options := []string{}
+	if vb.UID != nil {
+		options = append(options, "uid="+strconv.Itoa(*vb.UID))
+	}
+	if vb.GID != nil {
+		options = append(options, "gid="+strconv.Itoa(*vb.GID))
+	}
+	if vb.Mode != "" {
+		options = append(options, "mode="+vb.Mode)
+	}
+	if withPlugin && vb.Retention != "" {
+		options = append(options, "retention="+vb.Retention)
+	}
+	if withPlugin && vb.AntiAffinity != "" {
+		options = append(options, "anti_affinity="+vb.AntiAffinity)
+	}
+	if withPlugin && vb.FromSource != "" {
+		options = append(options, "from_source="+vb.FromSource)
+	}
+	if withPlugin && vb.FromDestination != "" {
+		options = append(options, "from_destination="+vb.FromDestination)
+	}
+	return strings.Join(options, ",")
+}
+
 // Validate return error if invalid
 // Please note: we allow negative value for SizeInBytes,
 // because Realloc uses negative value to descrease the size of volume.
@@ -71,18 +286,71 @@ func (vb VolumeBinding) Validate() error {
 	if !filepath.IsAbs(vb.Destination) {
 		return errors.Wrapf(ErrInvalidVolume, "dest must be absolute: %+v", vb)
 	}
-	if vb.Source == "" {
+	switch {
+	case vb.Pool != "" && vb.Source != "":
+		return errors.Wrapf(ErrInvalidVolume, "source and pool can't be both provided: %+v", vb)
+	case vb.Pool != "":
+		if !ValidName(vb.Pool) {
+			return errors.Wrapf(ErrInvalidVolume, "invalid pool: %s", vb.Pool)
+		}
+		if vb.IsMove() {
+			return errors.Wrapf(ErrInvalidVolume, "binding of pool can't be moved: %+v", vb)
+		}
+	case vb.Source == "":
 		return errors.Wrapf(ErrInvalidVolume, "source must be provided: %+v", vb)
-	}
-	if !filepath.IsAbs(vb.Source) {
+	case !filepath.IsAbs(vb.Source):
 		return errors.Wrapf(ErrInvalidVolume, "source must be absolute: %+v", vb)
 	}
+	if vb.Retention != "" {
+		if _, err := ParseRetention(vb.Retention); err != nil {
+			return errors.Wrapf(ErrInvalidVolume, "%s: %+v", err, vb)
+		}
+	}
+	if vb.UID != nil && *vb.UID < 0 {
+		return errors.Wrapf(ErrInvalidVolume, "negative uid: %d", *vb.UID)
+	}
+	if vb.GID != nil && *vb.GID < 0 {
+		return errors.Wrapf(ErrInvalidVolume, "negative gid: %d", *vb.GID)
+	}
+	if vb.Mode != "" {
+		if mode, err := strconv.ParseUint(vb.Mode, 8, 32); err != nil || mode > 07777 {
+			return errors.Wrapf(ErrInvalidVolume, "invalid mode: %s", vb.Mode)
+		}
+	}
+	if vb.AntiAffinity != "" && !ValidName(vb.AntiAffinity) {
+		return errors.Wrapf(ErrInvalidVolume, "invalid anti-affinity group: %s", vb.AntiAffinity)
+	}
+	if vb.FromSource != "" && !filepath.IsAbs(vb.FromSource) {
+		return errors.Wrapf(ErrInvalidVolume, "from_source must be absolute: %+v", vb)
+	}
+	if vb.FromDestination != "" && !filepath.IsAbs(vb.FromDestination) {
+		return errors.Wrapf(ErrInvalidVolume, "from_destination must be absolute: %+v", vb)
+	}
+	if vb.IsMove() && vb.MovedFrom() == vb.GetMapKey() {
+		return errors.Wrapf(ErrInvalidVolume, "binding is moved to itself: %+v", vb)
+	}
 	return nil
 }
 
-// ToString returns volume string
+// ToString returns volume string, options are included
 func (vb VolumeBinding) ToString() (volume string) {
+	source := vb.Source
+	if vb.IsPool() {
+		source = poolPrefix + vb.Pool
+	}
+	volume = fmt.Sprintf("%s:%s:%d", source, vb.Destination, vb.SizeInBytes)
+	if options := vb.options(true); options != "" {
+		volume += ":" + options
+	}
+	return volume
+}
+
+// ToEngineString returns volume string passed to engine, options only used by plugin are excluded
+func (vb VolumeBinding) ToEngineString() (volume string) {
 	volume = fmt.Sprintf("%s:%s:%d", vb.Source, vb.Destination, vb.SizeInBytes)
+	if options := vb.options(false); options != "" {
+		volume += ":" + options
+	}
 	return volume
 }
 
@@ -101,7 +369,7 @@ func (vbs VolumeBindings) Equal(vbs1 VolumeBindings) bool {
 		if !ok {
 			return false
 		}
-		if *vb != *vb1 {
+		if !vb.Equal(vb1) {
 			return false
 		}
 	}
@@ -116,13 +384,31 @@ func (vbs VolumeBindings) TotalSize() int64 {
 	return ans
 }
 
+// UnmarshalJSON accepts volumes in both string and object form
 func (vbs *VolumeBindings) UnmarshalJSON(b []byte) (err error) {
-	volumes := []string{}
+	volumes := []json.RawMessage{}
 	if err = json.Unmarshal(b, &volumes); err != nil {
 		return err
 	}
-	*vbs, err = NewVolumeBindings(volumes)
-	return
+	ans := VolumeBindings{}
+	for _, volume := range volumes {
+		var vb *VolumeBinding
+		if s := ""; json.Unmarshal(volume, &s) == nil {
+			vb, err = NewVolumeBinding(s)
+		} else {
+			o := &volumeBindingObject{}
+			if err = json.Unmarshal(volume, o); err != nil {
+				return errors.Wrapf(ErrInvalidVolume, "%s", volume)
+			}
+			vb, err = newVolumeBindingFromObject(o)
+		}
+		if err != nil {
+			return err
+		}
+		ans = append(ans, vb)
+	}
+	*vbs = ans
+	return nil
 }
 
 // MarshalJSON is used for encoding/json.Marshal
@@ -161,6 +447,10 @@ func (vbs VolumeBindings) Validate() error {
 		}
 		seenDest[vb.Destination] = true
 
+		if vb.IsPool() {
+			// the sources are picked by deploy
+			continue
+		}
 		src := vb.GetSource()
 		if v := seenSrc[src]; v {
 			return errors.Wrapf(ErrInvalidVolumes, "duplicated source: %s", src)
@@ -177,12 +467,9 @@ func MergeVolumeBindings(vbs1 VolumeBindings, vbs2 ...VolumeBindings) (ans Volum
 		for _, vb := range vbs {
 			if binding, ok := vbMap[vb.GetMapKey()]; ok {
 				binding.SizeInBytes += vb.SizeInBytes
+				binding.MergeAttrs(vb)
 			} else {
-				vbMap[vb.GetMapKey()] = &VolumeBinding{
-					Source:      vb.Source,
-					Destination: vb.Destination,
-					SizeInBytes: vb.SizeInBytes,
-				}
+				vbMap[vb.GetMapKey()] = vb.DeepCopy()
 			}
 		}
 	}
@@ -204,3 +491,48 @@ func RemoveEmptyVolumeBinding(vbs VolumeBindings) VolumeBindings {
 	}
 	return ans
 }
+
+// ApplyVolumeBindings applies the volumes of workloads to the bindings of a node.
+// With incr, sizes are added and new bindings are appended, a binding is dropped if a negative delta empties it,
+// this is how realloc removes a binding. Without incr, sizes are subtracted and emptied bindings are dropped.
+func ApplyVolumeBindings(vbs VolumeBindings, delta VolumeBindings, incr bool) VolumeBindings {
+	ans := VolumeBindings{}
+	index := map[[2]string]*VolumeBinding{}
+	for _, vb := range vbs {
+		vb1 := vb.DeepCopy()
+		index[vb1.GetMapKey()] = vb1
+		ans = append(ans, vb1)
+	}
+	removed := map[[2]string]bool{}
+	for _, vb := range delta {
+		key := vb.GetMapKey()
+		binding, ok := index[key]
+		switch {
+		case !ok && (!incr || vb.SizeInBytes < 0):
+			continue
+		case !ok:
+			binding = vb.DeepCopy()
+			index[key] = binding
+			ans = append(ans, binding)
+			continue
+		case incr:
+			binding.SizeInBytes += vb.SizeInBytes
+			binding.MergeAttrs(vb)
+		default:
+			binding.SizeInBytes -= vb.SizeInBytes
+		}
+		if binding.SizeInBytes <= 0 && (!incr || vb.SizeInBytes < 0) {
+			removed[key] = true
+		}
+	}
+	if len(removed) == 0 {
+		return ans
+	}
+	kept := VolumeBindings{}
+	for _, vb := range ans {
+		if !removed[vb.GetMapKey()] {
+			kept = append(kept, vb)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,121 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyVolumeBindings(t *testing.T) {
+	w0, err := NewVolumeBindings([]string{"/eru/img0:/dir0:100", "/eru/img1:/dir1"})
+	assert.NoError(t, err)
+	w1, err := NewVolumeBindings([]string{"/eru/img2:/dir0:200"})
+	assert.NoError(t, err)
+
+	vbs := ApplyVolumeBindings(nil, w0, true)
+	vbs = ApplyVolumeBindings(vbs, w1, true)
+	assert.Len(t, vbs, 3)
+
+	// realloc: grow img0 and remove img1, bindings without size are kept
+	delta, err := NewVolumeBindings([]string{"/eru/img0:/dir0:50", "/eru/img1:/dir1:0"})
+	assert.NoError(t, err)
+	vbs = ApplyVolumeBindings(vbs, delta, true)
+	assert.Len(t, vbs, 3)
+	assert.Equal(t, int64(150), vbs[0].SizeInBytes)
+	delta, err = NewVolumeBindings([]string{"/eru/img0:/dir0:-150"})
+	assert.NoError(t, err)
+	vbs = ApplyVolumeBindings(vbs, delta, true)
+	assert.Len(t, vbs, 2)
+
+	// remove workloads
+	vbs = ApplyVolumeBindings(vbs, w0, false)
+	vbs = ApplyVolumeBindings(vbs, w1, false)
+	assert.Len(t, vbs, 0)
+}
+
+func TestVolumeBindingOptions(t *testing.T) {
+	vb, err := NewVolumeBinding("/eru/img0:/dir0:1GiB:retention=archive-after-7d")
+	assert.NoError(t, err)
+	assert.Equal(t, "archive-after-7d", vb.Retention)
+	assert.Equal(t, "/eru/img0:/dir0:1073741824:retention=archive-after-7d", vb.ToString())
+	assert.Equal(t, "/eru/img0:/dir0:1073741824", vb.ToEngineString())
+
+	// size can be omitted
+	vb, err = NewVolumeBinding("/eru/img0:/dir0::retention=delete")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), vb.SizeInBytes)
+
+	_, err = NewVolumeBinding("/eru/img0:/dir0:1GiB:retention=forever")
+	assert.ErrorIs(t, err, ErrInvalidVolume)
+	_, err = NewVolumeBinding("/eru/img0:/dir0:1GiB:owner=root")
+	assert.ErrorIs(t, err, ErrInvalidVolume)
+}
+
+func TestVolumeBindingPool(t *testing.T) {
+	vb, err := NewVolumeBinding("pool=ssd:/dir0:50GiB")
+	assert.NoError(t, err)
+	assert.True(t, vb.IsPool())
+	assert.Equal(t, "ssd", vb.Pool)
+	assert.Equal(t, "", vb.Source)
+	assert.Equal(t, "pool=ssd:/dir0:53687091200", vb.ToString())
+
+	// sources of pool bindings aren't duplicated
+	vbs, err := NewVolumeBindings([]string{"pool=ssd:/dir0:1GiB", "pool=ssd:/dir1:1GiB"})
+	assert.NoError(t, err)
+	assert.NoError(t, vbs.Validate())
+
+	_, err = NewVolumeBinding("pool=:/dir0:1GiB")
+	assert.ErrorIs(t, err, ErrInvalidVolume)
+	_, err = NewVolumeBinding("pool=ssd:/dir0:1GiB:from_destination=/dir1")
+	assert.ErrorIs(t, err, ErrInvalidVolume)
+}
+
+func TestVolumeBindingAttrs(t *testing.T) {
+	vb, err := NewVolumeBinding("/eru/img0:/dir0:1GiB:uid=1000,gid=0,mode=0750,retention=delete")
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, *vb.UID)
+	assert.Equal(t, 0, *vb.GID)
+	mode, ok := vb.FileMode()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(0750), mode)
+	assert.Equal(t, "/eru/img0:/dir0:1073741824:uid=1000,gid=0,mode=0750,retention=delete", vb.ToString())
+	assert.Equal(t, "/eru/img0:/dir0:1073741824:uid=1000,gid=0,mode=0750", vb.ToEngineString())
+
+	vb1 := vb.DeepCopy()
+	assert.True(t, vb.Equal(vb1))
+	*vb1.UID = 0
+	assert.False(t, vb.SameAttrs(vb1))
+
+	// the anti-affinity group is only kept by plugin
+	vb, err = NewVolumeBinding("/eru/hostdir-abc:/dir0:1GiB:mode=0750,anti_affinity=db")
+	assert.NoError(t, err)
+	assert.Equal(t, "db", vb.AntiAffinity)
+	assert.Equal(t, "/eru/hostdir-abc:/dir0:1073741824:mode=0750,anti_affinity=db", vb.ToString())
+	assert.Equal(t, "/eru/hostdir-abc:/dir0:1073741824:mode=0750", vb.ToEngineString())
+
+	for _, volume := range []string{
+		"/eru/img0:/dir0:1GiB:anti_affinity=a/b",
+		"/eru/img0:/dir0:1GiB:uid=-1",
+		"/eru/img0:/dir0:1GiB:gid=root",
+		"/eru/img0:/dir0:1GiB:mode=0999",
+		"/eru/img0:/dir0:1GiB:mode=17777",
+	} {
+		_, err := NewVolumeBinding(volume)
+		assert.ErrorIs(t, err, ErrInvalidVolume, volume)
+	}
+
+	// object form, mixed with string form
+	vbs := VolumeBindings{}
+	assert.NoError(t, vbs.UnmarshalJSON([]byte(`[
+		{"source": "/eru/img0", "destination": "/dir0", "size": "1GiB", "uid": 1000, "mode": "0700"},
+		{"source": "/eru/img1", "destination": "/dir1", "size_in_bytes": 1024},
+		"/eru/img2:/dir2:1GiB:gid=1000"
+	]`)))
+	assert.Len(t, vbs, 3)
+	assert.Equal(t, int64(1<<30), vbs[0].SizeInBytes)
+	assert.Equal(t, 1000, *vbs[0].UID)
+	assert.Nil(t, vbs[0].GID)
+	assert.Equal(t, int64(1024), vbs[1].SizeInBytes)
+	assert.Equal(t, 1000, *vbs[2].GID)
+	assert.ErrorIs(t, vbs.UnmarshalJSON([]byte(`[{"source": "/eru/img0", "destination": "/dir0", "uid": -1}]`)), ErrInvalidVolume)
+}
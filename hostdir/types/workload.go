@@ -8,8 +8,14 @@ import (
 )
 
 // WorkloadResource indicate hostdir workload resource
+// Roots maps the destination of each binding to the root holding its source, it is recorded by deploy and realloc.
+// App is the app of request, it picks the quota policy on realloc, see Config.PolicyOf.
+// Tenant is the tenant of request, the size of workload is counted in its quota, see TenantOf.
 type WorkloadResource struct {
-	Volumes VolumeBindings `json:"volumes" mapstructure:"volumes"`
+	Volumes VolumeBindings    `json:"volumes" mapstructure:"volumes"`
+	Roots   map[string]string `json:"roots,omitempty" mapstructure:"roots"`
+	App     string            `json:"app,omitempty" mapstructure:"app"`
+	Tenant  string            `json:"tenant,omitempty" mapstructure:"tenant"`
 }
 
 func NewWorkloadResoure() *WorkloadResource {
@@ -19,22 +25,48 @@ func NewWorkloadResoure() *WorkloadResource {
 }
 
 func (w *WorkloadResource) AsRawParams() resourcetypes.RawParams {
-	return resourcetypes.RawParams{
+	ans := resourcetypes.RawParams{
 		"volumes": w.Volumes,
 	}
+	if len(w.Roots) > 0 {
+		ans["roots"] = w.Roots
+	}
+	if w.App != "" {
+		ans["app"] = w.App
+	}
+	if w.Tenant != "" {
+		ans["tenant"] = w.Tenant
+	}
+	return ans
 }
 
 func (w *WorkloadResource) Size() int64 {
 	return w.Volumes.TotalSize()
 }
 
+// TenantOf returns the tenant of workload, the app is the tenant if not set
+func (w *WorkloadResource) TenantOf() string {
+	if w.Tenant != "" {
+		return w.Tenant
+	}
+	return w.App
+}
+
 func (w *WorkloadResource) DeepCopy() *WorkloadResource {
 	ans := &WorkloadResource{
 		Volumes: VolumeBindings{},
+		App:     w.App,
+		Tenant:  w.Tenant,
 	}
 	for _, vb := range w.Volumes {
 		ans.Volumes = append(ans.Volumes, vb.DeepCopy())
 	}
+	if w.Roots != nil {
+		ans.Roots = map[string]string{}
+		for dest, root := range w.Roots {
+			ans.Roots[dest] = root
+		}
+	}
 	return ans
 }
 
@@ -50,28 +82,94 @@ func (w *WorkloadResource) Parse(rawParams resourcetypes.RawParams) (err error)
 
 // WorkloadResourceRaw includes all possible fields passed by eru-core for editing workload
 // for request calculation
+// AntiAffinity is the group of replicas whose pool bindings are placed in distinct failure domains, see Config.RootDomain.
+// App picks the quota policy, the policy is applied by ApplyPolicy and checked by Validate.
+// Tenant is whose cluster-wide quota the workload is counted in, see TenantOf.
 type WorkloadResourceRequest struct {
-	Volumes VolumeBindings `json:"volumes" mapstructure:"volumes"`
+	Volumes      VolumeBindings `json:"volumes" mapstructure:"volumes"`
+	AntiAffinity string         `json:"anti_affinity,omitempty" mapstructure:"anti_affinity"`
+	App          string         `json:"app,omitempty" mapstructure:"app"`
+	Tenant       string         `json:"tenant,omitempty" mapstructure:"tenant"`
+	Policy       *Policy        `json:"-" mapstructure:"-"`
 }
 
 func (w *WorkloadResourceRequest) DeepCopy() *WorkloadResourceRequest {
-	ans := &WorkloadResourceRequest{}
+	ans := &WorkloadResourceRequest{AntiAffinity: w.AntiAffinity, App: w.App, Tenant: w.Tenant, Policy: w.Policy}
 	for _, vb := range w.Volumes {
-		newVB := *vb
-		ans.Volumes = append(ans.Volumes, &newVB)
+		ans.Volumes = append(ans.Volumes, vb.DeepCopy())
 	}
 	return ans
 }
 
 // Validate .
 func (w *WorkloadResourceRequest) Validate() error {
-	return w.Volumes.Validate()
+	if w.AntiAffinity != "" && !ValidName(w.AntiAffinity) {
+		return errors.Wrapf(ErrInvalidVolumes, "invalid anti-affinity group: %s", w.AntiAffinity)
+	}
+	if w.App != "" && !ValidName(w.App) {
+		return errors.Wrapf(ErrInvalidVolumes, "invalid app: %s", w.App)
+	}
+	if w.Tenant != "" && !ValidName(w.Tenant) {
+		return errors.Wrapf(ErrInvalidVolumes, "invalid tenant: %s", w.Tenant)
+	}
+	if err := w.Volumes.Validate(); err != nil {
+		return err
+	}
+	if w.Policy != nil {
+		return w.Policy.Check(w.Volumes)
+	}
+	return nil
 }
 
-// Parse .
+// TenantOf returns the tenant of request, the app is the tenant if not set
+func (w *WorkloadResourceRequest) TenantOf() string {
+	if w.Tenant != "" {
+		return w.Tenant
+	}
+	return w.App
+}
+
+// ApplyPolicy rounds the sizes of bindings by granularity of policy, the policy is checked by Validate
+func (w *WorkloadResourceRequest) ApplyPolicy(policy *Policy) {
+	policy.Round(w.Volumes)
+	w.Policy = policy
+}
+
+// ValidateDeploy validates the request of deploy, moving bindings is only allowed in realloc
+func (w *WorkloadResourceRequest) ValidateDeploy() error {
+	for _, vb := range w.Volumes {
+		if vb.IsMove() {
+			return errors.Wrapf(ErrInvalidVolumes, "only realloc can move binding: %s", vb.ToString())
+		}
+	}
+	return w.Validate()
+}
+
+// Parse accepts volumes in both string and object form
 func (w *WorkloadResourceRequest) Parse(rawParams resourcetypes.RawParams) (err error) {
-	if w.Volumes, err = NewVolumeBindings(rawParams.OneOfStringSlice("volumes", "volume-request", "volumes-request")); err != nil {
-		return errors.Wrap(err, "failed to parse workload resource request")
+	w.Volumes = nil
+	w.AntiAffinity = ""
+	for _, key := range []string{"anti-affinity", "anti_affinity"} {
+		if rawParams.IsSet(key) {
+			w.AntiAffinity = rawParams.String(key)
+			break
+		}
+	}
+	w.App = rawParams.String("app")
+	w.Tenant = rawParams.String("tenant")
+	for _, key := range []string{"volumes", "volume-request", "volumes-request"} {
+		if !rawParams.IsSet(key) {
+			continue
+		}
+		// use json because volumes may be strings or objects
+		body, err := json.Marshal(rawParams[key])
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(body, &w.Volumes); err != nil {
+			return errors.Wrap(err, "failed to parse workload resource request")
+		}
+		return nil
 	}
 	return nil
 }
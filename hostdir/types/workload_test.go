@@ -53,4 +53,23 @@ func TestWorkloadResourceRequest(t *testing.T) {
 	req = &WorkloadResourceRequest{}
 	err = req.Parse(params)
 	assert.Error(t, req.Validate())
+
+	// anti-affinity group
+	req = &WorkloadResourceRequest{}
+	assert.NoError(t, req.Parse(resourcetypes.RawParams{
+		"volumes":       []string{"pool=ssd:/data:1GiB"},
+		"anti-affinity": "db",
+	}))
+	assert.Equal(t, "db", req.AntiAffinity)
+	assert.NoError(t, req.Validate())
+	req.AntiAffinity = "d/b"
+	assert.ErrorIs(t, req.Validate(), ErrInvalidVolumes)
+
+	// the copy doesn't share owners of bindings
+	req = &WorkloadResourceRequest{}
+	assert.NoError(t, req.Parse(resourcetypes.RawParams{"volumes": []string{"/eru/img0:/dir0:1GiB:uid=1000,gid=1000"}}))
+	cp := req.DeepCopy()
+	*cp.Volumes[0].UID, *cp.Volumes[0].GID = 0, 0
+	assert.Equal(t, 1000, *req.Volumes[0].UID)
+	assert.Equal(t, 1000, *req.Volumes[0].GID)
 }
@@ -0,0 +1,4 @@
+package pb
+
+// The code of hostdir.proto is generated by protoc with protoc-gen-go v1.30.0 and protoc-gen-go-grpc v1.3.0, see `make grpc`
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative hostdir.proto
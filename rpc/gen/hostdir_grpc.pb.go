@@ -0,0 +1,627 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: hostdir.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	HostdirPlugin_Name_FullMethodName                    = "/pb.HostdirPlugin/Name"
+	HostdirPlugin_GetMetricsDescription_FullMethodName   = "/pb.HostdirPlugin/GetMetricsDescription"
+	HostdirPlugin_GetMetrics_FullMethodName              = "/pb.HostdirPlugin/GetMetrics"
+	HostdirPlugin_AddNode_FullMethodName                 = "/pb.HostdirPlugin/AddNode"
+	HostdirPlugin_RemoveNode_FullMethodName              = "/pb.HostdirPlugin/RemoveNode"
+	HostdirPlugin_GetNodesDeployCapacity_FullMethodName  = "/pb.HostdirPlugin/GetNodesDeployCapacity"
+	HostdirPlugin_SetNodeResourceCapacity_FullMethodName = "/pb.HostdirPlugin/SetNodeResourceCapacity"
+	HostdirPlugin_GetNodeResourceInfo_FullMethodName     = "/pb.HostdirPlugin/GetNodeResourceInfo"
+	HostdirPlugin_SetNodeResourceInfo_FullMethodName     = "/pb.HostdirPlugin/SetNodeResourceInfo"
+	HostdirPlugin_SetNodeResourceUsage_FullMethodName    = "/pb.HostdirPlugin/SetNodeResourceUsage"
+	HostdirPlugin_GetMostIdleNode_FullMethodName         = "/pb.HostdirPlugin/GetMostIdleNode"
+	HostdirPlugin_FixNodeResource_FullMethodName         = "/pb.HostdirPlugin/FixNodeResource"
+	HostdirPlugin_CalculateDeploy_FullMethodName         = "/pb.HostdirPlugin/CalculateDeploy"
+	HostdirPlugin_CalculateRealloc_FullMethodName        = "/pb.HostdirPlugin/CalculateRealloc"
+	HostdirPlugin_CalculateRemap_FullMethodName          = "/pb.HostdirPlugin/CalculateRemap"
+)
+
+// HostdirPluginClient is the client API for HostdirPlugin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type HostdirPluginClient interface {
+	Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error)
+	GetMetricsDescription(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetMetricsDescriptionResponse, error)
+	GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error)
+	AddNode(ctx context.Context, in *AddNodeRequest, opts ...grpc.CallOption) (*AddNodeResponse, error)
+	RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*Empty, error)
+	GetNodesDeployCapacity(ctx context.Context, in *GetNodesDeployCapacityRequest, opts ...grpc.CallOption) (*GetNodesDeployCapacityResponse, error)
+	SetNodeResourceCapacity(ctx context.Context, in *SetNodeResourceCapacityRequest, opts ...grpc.CallOption) (*SetNodeResourceResponse, error)
+	GetNodeResourceInfo(ctx context.Context, in *GetNodeResourceInfoRequest, opts ...grpc.CallOption) (*GetNodeResourceInfoResponse, error)
+	SetNodeResourceInfo(ctx context.Context, in *SetNodeResourceInfoRequest, opts ...grpc.CallOption) (*Empty, error)
+	SetNodeResourceUsage(ctx context.Context, in *SetNodeResourceUsageRequest, opts ...grpc.CallOption) (*SetNodeResourceResponse, error)
+	GetMostIdleNode(ctx context.Context, in *GetMostIdleNodeRequest, opts ...grpc.CallOption) (*GetMostIdleNodeResponse, error)
+	FixNodeResource(ctx context.Context, in *GetNodeResourceInfoRequest, opts ...grpc.CallOption) (*GetNodeResourceInfoResponse, error)
+	CalculateDeploy(ctx context.Context, in *CalculateDeployRequest, opts ...grpc.CallOption) (*CalculateDeployResponse, error)
+	CalculateRealloc(ctx context.Context, in *CalculateReallocRequest, opts ...grpc.CallOption) (*CalculateReallocResponse, error)
+	CalculateRemap(ctx context.Context, in *CalculateRemapRequest, opts ...grpc.CallOption) (*CalculateRemapResponse, error)
+}
+
+type hostdirPluginClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHostdirPluginClient(cc grpc.ClientConnInterface) HostdirPluginClient {
+	return &hostdirPluginClient{cc}
+}
+
+func (c *hostdirPluginClient) Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error) {
+	out := new(NameResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_Name_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) GetMetricsDescription(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetMetricsDescriptionResponse, error) {
+	out := new(GetMetricsDescriptionResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_GetMetricsDescription_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*GetMetricsResponse, error) {
+	out := new(GetMetricsResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_GetMetrics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) AddNode(ctx context.Context, in *AddNodeRequest, opts ...grpc.CallOption) (*AddNodeResponse, error) {
+	out := new(AddNodeResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_AddNode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) RemoveNode(ctx context.Context, in *RemoveNodeRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, HostdirPlugin_RemoveNode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) GetNodesDeployCapacity(ctx context.Context, in *GetNodesDeployCapacityRequest, opts ...grpc.CallOption) (*GetNodesDeployCapacityResponse, error) {
+	out := new(GetNodesDeployCapacityResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_GetNodesDeployCapacity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) SetNodeResourceCapacity(ctx context.Context, in *SetNodeResourceCapacityRequest, opts ...grpc.CallOption) (*SetNodeResourceResponse, error) {
+	out := new(SetNodeResourceResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_SetNodeResourceCapacity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) GetNodeResourceInfo(ctx context.Context, in *GetNodeResourceInfoRequest, opts ...grpc.CallOption) (*GetNodeResourceInfoResponse, error) {
+	out := new(GetNodeResourceInfoResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_GetNodeResourceInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) SetNodeResourceInfo(ctx context.Context, in *SetNodeResourceInfoRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, HostdirPlugin_SetNodeResourceInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) SetNodeResourceUsage(ctx context.Context, in *SetNodeResourceUsageRequest, opts ...grpc.CallOption) (*SetNodeResourceResponse, error) {
+	out := new(SetNodeResourceResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_SetNodeResourceUsage_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) GetMostIdleNode(ctx context.Context, in *GetMostIdleNodeRequest, opts ...grpc.CallOption) (*GetMostIdleNodeResponse, error) {
+	out := new(GetMostIdleNodeResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_GetMostIdleNode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) FixNodeResource(ctx context.Context, in *GetNodeResourceInfoRequest, opts ...grpc.CallOption) (*GetNodeResourceInfoResponse, error) {
+	out := new(GetNodeResourceInfoResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_FixNodeResource_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) CalculateDeploy(ctx context.Context, in *CalculateDeployRequest, opts ...grpc.CallOption) (*CalculateDeployResponse, error) {
+	out := new(CalculateDeployResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_CalculateDeploy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) CalculateRealloc(ctx context.Context, in *CalculateReallocRequest, opts ...grpc.CallOption) (*CalculateReallocResponse, error) {
+	out := new(CalculateReallocResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_CalculateRealloc_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostdirPluginClient) CalculateRemap(ctx context.Context, in *CalculateRemapRequest, opts ...grpc.CallOption) (*CalculateRemapResponse, error) {
+	out := new(CalculateRemapResponse)
+	err := c.cc.Invoke(ctx, HostdirPlugin_CalculateRemap_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HostdirPluginServer is the server API for HostdirPlugin service.
+// All implementations must embed UnimplementedHostdirPluginServer
+// for forward compatibility
+type HostdirPluginServer interface {
+	Name(context.Context, *Empty) (*NameResponse, error)
+	GetMetricsDescription(context.Context, *Empty) (*GetMetricsDescriptionResponse, error)
+	GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error)
+	AddNode(context.Context, *AddNodeRequest) (*AddNodeResponse, error)
+	RemoveNode(context.Context, *RemoveNodeRequest) (*Empty, error)
+	GetNodesDeployCapacity(context.Context, *GetNodesDeployCapacityRequest) (*GetNodesDeployCapacityResponse, error)
+	SetNodeResourceCapacity(context.Context, *SetNodeResourceCapacityRequest) (*SetNodeResourceResponse, error)
+	GetNodeResourceInfo(context.Context, *GetNodeResourceInfoRequest) (*GetNodeResourceInfoResponse, error)
+	SetNodeResourceInfo(context.Context, *SetNodeResourceInfoRequest) (*Empty, error)
+	SetNodeResourceUsage(context.Context, *SetNodeResourceUsageRequest) (*SetNodeResourceResponse, error)
+	GetMostIdleNode(context.Context, *GetMostIdleNodeRequest) (*GetMostIdleNodeResponse, error)
+	FixNodeResource(context.Context, *GetNodeResourceInfoRequest) (*GetNodeResourceInfoResponse, error)
+	CalculateDeploy(context.Context, *CalculateDeployRequest) (*CalculateDeployResponse, error)
+	CalculateRealloc(context.Context, *CalculateReallocRequest) (*CalculateReallocResponse, error)
+	CalculateRemap(context.Context, *CalculateRemapRequest) (*CalculateRemapResponse, error)
+	mustEmbedUnimplementedHostdirPluginServer()
+}
+
+// UnimplementedHostdirPluginServer must be embedded to have forward compatible implementations.
+type UnimplementedHostdirPluginServer struct {
+}
+
+func (UnimplementedHostdirPluginServer) Name(context.Context, *Empty) (*NameResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Name not implemented")
+}
+func (UnimplementedHostdirPluginServer) GetMetricsDescription(context.Context, *Empty) (*GetMetricsDescriptionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetricsDescription not implemented")
+}
+func (UnimplementedHostdirPluginServer) GetMetrics(context.Context, *GetMetricsRequest) (*GetMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetrics not implemented")
+}
+func (UnimplementedHostdirPluginServer) AddNode(context.Context, *AddNodeRequest) (*AddNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddNode not implemented")
+}
+func (UnimplementedHostdirPluginServer) RemoveNode(context.Context, *RemoveNodeRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveNode not implemented")
+}
+func (UnimplementedHostdirPluginServer) GetNodesDeployCapacity(context.Context, *GetNodesDeployCapacityRequest) (*GetNodesDeployCapacityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodesDeployCapacity not implemented")
+}
+func (UnimplementedHostdirPluginServer) SetNodeResourceCapacity(context.Context, *SetNodeResourceCapacityRequest) (*SetNodeResourceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetNodeResourceCapacity not implemented")
+}
+func (UnimplementedHostdirPluginServer) GetNodeResourceInfo(context.Context, *GetNodeResourceInfoRequest) (*GetNodeResourceInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNodeResourceInfo not implemented")
+}
+func (UnimplementedHostdirPluginServer) SetNodeResourceInfo(context.Context, *SetNodeResourceInfoRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetNodeResourceInfo not implemented")
+}
+func (UnimplementedHostdirPluginServer) SetNodeResourceUsage(context.Context, *SetNodeResourceUsageRequest) (*SetNodeResourceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetNodeResourceUsage not implemented")
+}
+func (UnimplementedHostdirPluginServer) GetMostIdleNode(context.Context, *GetMostIdleNodeRequest) (*GetMostIdleNodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMostIdleNode not implemented")
+}
+func (UnimplementedHostdirPluginServer) FixNodeResource(context.Context, *GetNodeResourceInfoRequest) (*GetNodeResourceInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FixNodeResource not implemented")
+}
+func (UnimplementedHostdirPluginServer) CalculateDeploy(context.Context, *CalculateDeployRequest) (*CalculateDeployResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CalculateDeploy not implemented")
+}
+func (UnimplementedHostdirPluginServer) CalculateRealloc(context.Context, *CalculateReallocRequest) (*CalculateReallocResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CalculateRealloc not implemented")
+}
+func (UnimplementedHostdirPluginServer) CalculateRemap(context.Context, *CalculateRemapRequest) (*CalculateRemapResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CalculateRemap not implemented")
+}
+func (UnimplementedHostdirPluginServer) mustEmbedUnimplementedHostdirPluginServer() {}
+
+// UnsafeHostdirPluginServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HostdirPluginServer will
+// result in compilation errors.
+type UnsafeHostdirPluginServer interface {
+	mustEmbedUnimplementedHostdirPluginServer()
+}
+
+func RegisterHostdirPluginServer(s grpc.ServiceRegistrar, srv HostdirPluginServer) {
+	s.RegisterService(&HostdirPlugin_ServiceDesc, srv)
+}
+
+func _HostdirPlugin_Name_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).Name(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_Name_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).Name(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_GetMetricsDescription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).GetMetricsDescription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_GetMetricsDescription_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).GetMetricsDescription(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_GetMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).GetMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_GetMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).GetMetrics(ctx, req.(*GetMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_AddNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).AddNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_AddNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).AddNode(ctx, req.(*AddNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_RemoveNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).RemoveNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_RemoveNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).RemoveNode(ctx, req.(*RemoveNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_GetNodesDeployCapacity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNodesDeployCapacityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).GetNodesDeployCapacity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_GetNodesDeployCapacity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).GetNodesDeployCapacity(ctx, req.(*GetNodesDeployCapacityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_SetNodeResourceCapacity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetNodeResourceCapacityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).SetNodeResourceCapacity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_SetNodeResourceCapacity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).SetNodeResourceCapacity(ctx, req.(*SetNodeResourceCapacityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_GetNodeResourceInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNodeResourceInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).GetNodeResourceInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_GetNodeResourceInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).GetNodeResourceInfo(ctx, req.(*GetNodeResourceInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_SetNodeResourceInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetNodeResourceInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).SetNodeResourceInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_SetNodeResourceInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).SetNodeResourceInfo(ctx, req.(*SetNodeResourceInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_SetNodeResourceUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetNodeResourceUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).SetNodeResourceUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_SetNodeResourceUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).SetNodeResourceUsage(ctx, req.(*SetNodeResourceUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_GetMostIdleNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMostIdleNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).GetMostIdleNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_GetMostIdleNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).GetMostIdleNode(ctx, req.(*GetMostIdleNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_FixNodeResource_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNodeResourceInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).FixNodeResource(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_FixNodeResource_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).FixNodeResource(ctx, req.(*GetNodeResourceInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_CalculateDeploy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculateDeployRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).CalculateDeploy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_CalculateDeploy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).CalculateDeploy(ctx, req.(*CalculateDeployRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_CalculateRealloc_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculateReallocRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).CalculateRealloc(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_CalculateRealloc_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).CalculateRealloc(ctx, req.(*CalculateReallocRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostdirPlugin_CalculateRemap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculateRemapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostdirPluginServer).CalculateRemap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostdirPlugin_CalculateRemap_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostdirPluginServer).CalculateRemap(ctx, req.(*CalculateRemapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// HostdirPlugin_ServiceDesc is the grpc.ServiceDesc for HostdirPlugin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HostdirPlugin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.HostdirPlugin",
+	HandlerType: (*HostdirPluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Name",
+			Handler:    _HostdirPlugin_Name_Handler,
+		},
+		{
+			MethodName: "GetMetricsDescription",
+			Handler:    _HostdirPlugin_GetMetricsDescription_Handler,
+		},
+		{
+			MethodName: "GetMetrics",
+			Handler:    _HostdirPlugin_GetMetrics_Handler,
+		},
+		{
+			MethodName: "AddNode",
+			Handler:    _HostdirPlugin_AddNode_Handler,
+		},
+		{
+			MethodName: "RemoveNode",
+			Handler:    _HostdirPlugin_RemoveNode_Handler,
+		},
+		{
+			MethodName: "GetNodesDeployCapacity",
+			Handler:    _HostdirPlugin_GetNodesDeployCapacity_Handler,
+		},
+		{
+			MethodName: "SetNodeResourceCapacity",
+			Handler:    _HostdirPlugin_SetNodeResourceCapacity_Handler,
+		},
+		{
+			MethodName: "GetNodeResourceInfo",
+			Handler:    _HostdirPlugin_GetNodeResourceInfo_Handler,
+		},
+		{
+			MethodName: "SetNodeResourceInfo",
+			Handler:    _HostdirPlugin_SetNodeResourceInfo_Handler,
+		},
+		{
+			MethodName: "SetNodeResourceUsage",
+			Handler:    _HostdirPlugin_SetNodeResourceUsage_Handler,
+		},
+		{
+			MethodName: "GetMostIdleNode",
+			Handler:    _HostdirPlugin_GetMostIdleNode_Handler,
+		},
+		{
+			MethodName: "FixNodeResource",
+			Handler:    _HostdirPlugin_FixNodeResource_Handler,
+		},
+		{
+			MethodName: "CalculateDeploy",
+			Handler:    _HostdirPlugin_CalculateDeploy_Handler,
+		},
+		{
+			MethodName: "CalculateRealloc",
+			Handler:    _HostdirPlugin_CalculateRealloc_Handler,
+		},
+		{
+			MethodName: "CalculateRemap",
+			Handler:    _HostdirPlugin_CalculateRemap_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "hostdir.proto",
+}
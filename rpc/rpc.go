@@ -0,0 +1,359 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+
+	enginetypes "github.com/projecteru2/core/engine/types"
+	plugintypes "github.com/projecteru2/core/resource/plugins/types"
+	resourcetypes "github.com/projecteru2/core/resource/types"
+	coretypes "github.com/projecteru2/core/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/yuyang0/resource-hostdir/cmd"
+	"github.com/yuyang0/resource-hostdir/hostdir"
+	pb "github.com/yuyang0/resource-hostdir/rpc/gen"
+)
+
+// Server exposes hostdir.Plugin over grpc
+type Server struct {
+	pb.UnimplementedHostdirPluginServer
+	plugin *hostdir.Plugin
+}
+
+// New .
+func New(p *hostdir.Plugin) *Server {
+	return &Server{plugin: p}
+}
+
+// NewGRPCServer returns a grpc server with hostdir and health services registered
+func NewGRPCServer(p *hostdir.Plugin, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	pb.RegisterHostdirPluginServer(s, New(p))
+	hs := health.NewServer()
+	hs.SetServingStatus(pb.HostdirPlugin_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, hs)
+	return s
+}
+
+// Name .
+func (s *Server) Name(context.Context, *pb.Empty) (*pb.NameResponse, error) {
+	return &pb.NameResponse{Name: s.plugin.Name()}, nil
+}
+
+// GetMetricsDescription .
+func (s *Server) GetMetricsDescription(ctx context.Context, _ *pb.Empty) (*pb.GetMetricsDescriptionResponse, error) {
+	r, err := s.plugin.GetMetricsDescription(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.GetMetricsDescriptionResponse{}
+	for _, d := range *r {
+		resp.Descriptions = append(resp.Descriptions, &pb.MetricsDescription{Name: d.Name, Help: d.Help, Type: d.Type, Labels: d.Labels})
+	}
+	return resp, nil
+}
+
+// GetMetrics .
+func (s *Server) GetMetrics(ctx context.Context, req *pb.GetMetricsRequest) (*pb.GetMetricsResponse, error) {
+	r, err := s.plugin.GetMetrics(ctx, req.Podname, req.Nodename)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.GetMetricsResponse{}
+	for _, m := range *r {
+		resp.Metrics = append(resp.Metrics, &pb.Metrics{Name: m.Name, Labels: m.Labels, Key: m.Key, Value: m.Value})
+	}
+	return resp, nil
+}
+
+// AddNode .
+func (s *Server) AddNode(ctx context.Context, req *pb.AddNodeRequest) (*pb.AddNodeResponse, error) {
+	if req.Nodename == "" {
+		return nil, toStatus(coretypes.ErrEmptyNodeName)
+	}
+	resource, err := decodeParams(req.Resource)
+	if err != nil {
+		return nil, err
+	}
+	info := &enginetypes.Info{}
+	if len(req.Info) > 0 {
+		if err := json.Unmarshal(req.Info, info); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid info: %s", err)
+		}
+	}
+	r, err := s.plugin.AddNode(ctx, req.Nodename, resource, info)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.AddNodeResponse{Capacity: encodeParams(r.Capacity), Usage: encodeParams(r.Usage)}, nil
+}
+
+// RemoveNode .
+func (s *Server) RemoveNode(ctx context.Context, req *pb.RemoveNodeRequest) (*pb.Empty, error) {
+	if req.Nodename == "" {
+		return nil, toStatus(coretypes.ErrEmptyNodeName)
+	}
+	if _, err := s.plugin.RemoveNode(ctx, req.Nodename); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Empty{}, nil
+}
+
+// GetNodesDeployCapacity .
+func (s *Server) GetNodesDeployCapacity(ctx context.Context, req *pb.GetNodesDeployCapacityRequest) (*pb.GetNodesDeployCapacityResponse, error) {
+	request, err := decodeParams(req.WorkloadResourceRequest)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.plugin.GetNodesDeployCapacity(ctx, req.Nodenames, request)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.GetNodesDeployCapacityResponse{
+		NodesDeployCapacity: map[string]*pb.NodeDeployCapacity{},
+		Total:               int64(r.Total),
+	}
+	for nodename, c := range r.NodeDeployCapacityMap {
+		resp.NodesDeployCapacity[nodename] = &pb.NodeDeployCapacity{
+			Capacity: int64(c.Capacity),
+			Usage:    c.Usage,
+			Rate:     c.Rate,
+			Weight:   c.Weight,
+		}
+	}
+	return resp, nil
+}
+
+// SetNodeResourceCapacity .
+func (s *Server) SetNodeResourceCapacity(ctx context.Context, req *pb.SetNodeResourceCapacityRequest) (*pb.SetNodeResourceResponse, error) {
+	if req.Nodename == "" {
+		return nil, toStatus(coretypes.ErrEmptyNodeName)
+	}
+	resource, err := decodeParams(req.Resource)
+	if err != nil {
+		return nil, err
+	}
+	resourceRequest, err := decodeParams(req.ResourceRequest)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.plugin.SetNodeResourceCapacity(ctx, req.Nodename, resource, resourceRequest, req.Delta, req.Incr)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.SetNodeResourceResponse{Before: encodeParams(r.Before), After: encodeParams(r.After)}, nil
+}
+
+// GetNodeResourceInfo .
+func (s *Server) GetNodeResourceInfo(ctx context.Context, req *pb.GetNodeResourceInfoRequest) (*pb.GetNodeResourceInfoResponse, error) {
+	return s.getNodeResourceInfo(ctx, req, s.plugin.GetNodeResourceInfo)
+}
+
+// FixNodeResource .
+func (s *Server) FixNodeResource(ctx context.Context, req *pb.GetNodeResourceInfoRequest) (*pb.GetNodeResourceInfoResponse, error) {
+	return s.getNodeResourceInfo(ctx, req, s.plugin.FixNodeResource)
+}
+
+func (s *Server) getNodeResourceInfo(
+	ctx context.Context,
+	req *pb.GetNodeResourceInfoRequest,
+	f func(context.Context, string, []plugintypes.WorkloadResource) (*plugintypes.GetNodeResourceInfoResponse, error),
+) (*pb.GetNodeResourceInfoResponse, error) {
+	if req.Nodename == "" {
+		return nil, toStatus(coretypes.ErrEmptyNodeName)
+	}
+	workloadsResource, err := decodeParamsSlice(req.WorkloadsResource)
+	if err != nil {
+		return nil, err
+	}
+	r, err := f(ctx, req.Nodename, workloadsResource)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.GetNodeResourceInfoResponse{
+		Capacity: encodeParams(r.Capacity),
+		Usage:    encodeParams(r.Usage),
+		Diffs:    r.Diffs,
+	}, nil
+}
+
+// SetNodeResourceInfo .
+func (s *Server) SetNodeResourceInfo(ctx context.Context, req *pb.SetNodeResourceInfoRequest) (*pb.Empty, error) {
+	if req.Nodename == "" {
+		return nil, toStatus(coretypes.ErrEmptyNodeName)
+	}
+	capacity, err := decodeParams(req.Capacity)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := decodeParams(req.Usage)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.plugin.SetNodeResourceInfo(ctx, req.Nodename, capacity, usage); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.Empty{}, nil
+}
+
+// SetNodeResourceUsage .
+func (s *Server) SetNodeResourceUsage(ctx context.Context, req *pb.SetNodeResourceUsageRequest) (*pb.SetNodeResourceResponse, error) {
+	if req.Nodename == "" {
+		return nil, toStatus(coretypes.ErrEmptyNodeName)
+	}
+	resource, err := decodeParams(req.Resource)
+	if err != nil {
+		return nil, err
+	}
+	resourceRequest, err := decodeParams(req.ResourceRequest)
+	if err != nil {
+		return nil, err
+	}
+	workloadsResource, err := decodeParamsSlice(req.WorkloadsResource)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.plugin.SetNodeResourceUsage(ctx, req.Nodename, resource, resourceRequest, workloadsResource, req.Delta, req.Incr)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.SetNodeResourceResponse{Before: encodeParams(r.Before), After: encodeParams(r.After)}, nil
+}
+
+// GetMostIdleNode .
+func (s *Server) GetMostIdleNode(ctx context.Context, req *pb.GetMostIdleNodeRequest) (*pb.GetMostIdleNodeResponse, error) {
+	r, err := s.plugin.GetMostIdleNode(ctx, req.Nodenames)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.GetMostIdleNodeResponse{Nodename: r.Nodename, Priority: int64(r.Priority)}, nil
+}
+
+// CalculateDeploy .
+func (s *Server) CalculateDeploy(ctx context.Context, req *pb.CalculateDeployRequest) (*pb.CalculateDeployResponse, error) {
+	if req.Nodename == "" {
+		return nil, toStatus(coretypes.ErrEmptyNodeName)
+	}
+	request, err := decodeParams(req.WorkloadResourceRequest)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.plugin.CalculateDeploy(ctx, req.Nodename, int(req.DeployCount), request)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.CalculateDeployResponse{}
+	for _, p := range r.EnginesParams {
+		resp.EnginesParams = append(resp.EnginesParams, encodeParams(p))
+	}
+	for _, p := range r.WorkloadsResource {
+		resp.WorkloadsResource = append(resp.WorkloadsResource, encodeParams(p))
+	}
+	return resp, nil
+}
+
+// CalculateRealloc .
+func (s *Server) CalculateRealloc(ctx context.Context, req *pb.CalculateReallocRequest) (*pb.CalculateReallocResponse, error) {
+	if req.Nodename == "" {
+		return nil, toStatus(coretypes.ErrEmptyNodeName)
+	}
+	resource, err := decodeParams(req.WorkloadResource)
+	if err != nil {
+		return nil, err
+	}
+	request, err := decodeParams(req.WorkloadResourceRequest)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.plugin.CalculateRealloc(ctx, req.Nodename, resource, request)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.CalculateReallocResponse{
+		EngineParams:     encodeParams(r.EngineParams),
+		DeltaResource:    encodeParams(r.DeltaResource),
+		WorkloadResource: encodeParams(r.WorkloadResource),
+	}, nil
+}
+
+// CalculateRemap .
+func (s *Server) CalculateRemap(ctx context.Context, req *pb.CalculateRemapRequest) (*pb.CalculateRemapResponse, error) {
+	if req.Nodename == "" {
+		return nil, toStatus(coretypes.ErrEmptyNodeName)
+	}
+	workloadsResource := map[string]plugintypes.WorkloadResource{}
+	for id, body := range req.WorkloadsResource {
+		p, err := decodeParams(body)
+		if err != nil {
+			return nil, err
+		}
+		workloadsResource[id] = p
+	}
+	r, err := s.plugin.CalculateRemap(ctx, req.Nodename, workloadsResource)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &pb.CalculateRemapResponse{EngineParamsMap: map[string][]byte{}}
+	for id, p := range r.EngineParamsMap {
+		resp.EngineParamsMap[id] = encodeParams(p)
+	}
+	return resp, nil
+}
+
+// decodeParams decodes json encoded raw params, empty body means nil
+func decodeParams(body []byte) (resourcetypes.RawParams, error) {
+	if len(body) == 0 {
+		return nil, nil
+	}
+	ans := resourcetypes.RawParams{}
+	if err := json.Unmarshal(body, &ans); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid params: %s", err)
+	}
+	return ans, nil
+}
+
+func decodeParamsSlice(bodies [][]byte) ([]resourcetypes.RawParams, error) {
+	ans := make([]resourcetypes.RawParams, 0, len(bodies))
+	for _, body := range bodies {
+		p, err := decodeParams(body)
+		if err != nil {
+			return nil, err
+		}
+		ans = append(ans, p)
+	}
+	return ans, nil
+}
+
+func encodeParams(p resourcetypes.RawParams) []byte {
+	if p == nil {
+		return nil
+	}
+	// raw params are always json serializable, they come from json or plain structs
+	body, _ := json.Marshal(p)
+	return body
+}
+
+// grpcCodes maps categories of errors to grpc status codes, errors are categorized by cmd.NewError
+var grpcCodes = map[string]codes.Code{
+	cmd.CategoryInvalid:     codes.InvalidArgument,
+	cmd.CategoryExhausted:   codes.ResourceExhausted,
+	cmd.CategoryNotFound:    codes.NotFound,
+	cmd.CategoryConflict:    codes.Aborted,
+	cmd.CategoryUnavailable: codes.Unavailable,
+	cmd.CategoryInternal:    codes.Internal,
+}
+
+// toStatus maps plugin errors to grpc status codes by their categories, existing nodes are AlreadyExists
+func toStatus(err error) error {
+	e := cmd.NewError(err)
+	code := grpcCodes[e.Category()]
+	if e.Code == cmd.CodeNodeExists {
+		code = codes.AlreadyExists
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,142 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	coretypes "github.com/projecteru2/core/types"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/yuyang0/resource-hostdir/hostdir"
+	"github.com/yuyang0/resource-hostdir/hostdir/store"
+	"github.com/yuyang0/resource-hostdir/hostdir/types"
+	pb "github.com/yuyang0/resource-hostdir/rpc/gen"
+)
+
+func newClient(ctx context.Context, t *testing.T) (pb.HostdirPluginClient, *grpc.ClientConn) {
+	p, err := hostdir.NewPlugin(ctx, coretypes.Config{}, types.Config{
+		Roots: []types.RootConfig{{Path: "/eru", Size: "100GiB"}},
+		Store: types.StoreConfig{Type: types.StoreMemory},
+	})
+	assert.NoError(t, err)
+
+	l := bufconn.Listen(1 << 20)
+	s := NewGRPCServer(p)
+	go func() { _ = s.Serve(l) }()
+	t.Cleanup(func() {
+		s.Stop()
+		_ = p.Close()
+	})
+
+	conn, err := grpc.DialContext(ctx, "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewHostdirPluginClient(conn), conn
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	client, conn := newClient(ctx, t)
+
+	h, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: pb.HostdirPlugin_ServiceDesc.ServiceName})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, h.Status)
+
+	name, err := client.Name(ctx, &pb.Empty{})
+	assert.NoError(t, err)
+	assert.Equal(t, "hostdir", name.Name)
+
+	_, err = client.AddNode(ctx, &pb.AddNodeRequest{Nodename: "node0"})
+	assert.NoError(t, err)
+	_, err = client.AddNode(ctx, &pb.AddNodeRequest{Nodename: "node0"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	request, _ := json.Marshal(map[string]any{"volumes": []string{"/eru/img0:/dir0:10GiB"}})
+	capacity, err := client.GetNodesDeployCapacity(ctx, &pb.GetNodesDeployCapacityRequest{
+		Nodenames:               []string{"node0"},
+		WorkloadResourceRequest: request,
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, capacity.Total)
+	assert.EqualValues(t, 10, capacity.NodesDeployCapacity["node0"].Capacity)
+
+	deploy, err := client.CalculateDeploy(ctx, &pb.CalculateDeployRequest{
+		Nodename:                "node0",
+		DeployCount:             2,
+		WorkloadResourceRequest: request,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, deploy.WorkloadsResource, 2)
+
+	usage, err := client.SetNodeResourceUsage(ctx, &pb.SetNodeResourceUsageRequest{
+		Nodename:          "node0",
+		WorkloadsResource: deploy.WorkloadsResource,
+		Delta:             true,
+		Incr:              true,
+	})
+	assert.NoError(t, err)
+	after := types.NewNodeResource()
+	assert.NoError(t, json.Unmarshal(usage.After, after))
+	assert.EqualValues(t, 20<<30, after.Roots["/eru"].Size)
+
+	info, err := client.GetNodeResourceInfo(ctx, &pb.GetNodeResourceInfoRequest{
+		Nodename:          "node0",
+		WorkloadsResource: deploy.WorkloadsResource,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, info.Diffs, 0)
+
+	metrics, err := client.GetMetrics(ctx, &pb.GetMetricsRequest{Podname: "pod", Nodename: "node0"})
+	assert.NoError(t, err)
+	assert.Len(t, metrics.Metrics, 3)
+
+	// errors are mapped to status codes
+	_, err = client.CalculateDeploy(ctx, &pb.CalculateDeployRequest{
+		Nodename:                "node0",
+		DeployCount:             10,
+		WorkloadResourceRequest: request,
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = client.GetNodeResourceInfo(ctx, &pb.GetNodeResourceInfoRequest{Nodename: "node1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.CalculateDeploy(ctx, &pb.CalculateDeployRequest{
+		Nodename:                "node0",
+		DeployCount:             1,
+		WorkloadResourceRequest: []byte("{"),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.RemoveNode(ctx, &pb.RemoveNodeRequest{Nodename: "node0"})
+	assert.NoError(t, err)
+}
+
+func TestToStatus(t *testing.T) {
+	for err, code := range map[error]codes.Code{
+		types.ErrInvalidConfig:    codes.InvalidArgument,
+		types.ErrInvalidRetention: codes.InvalidArgument,
+		types.ErrInvalidSnapshot:  codes.InvalidArgument,
+		types.ErrInvalidSchema:    codes.InvalidArgument,
+		types.ErrQuotaExceeded:    codes.ResourceExhausted,
+		types.ErrJournalNotFound:  codes.NotFound,
+		coretypes.ErrNodeExists:   codes.AlreadyExists,
+		store.ErrConflict:         codes.Aborted,
+		errors.Mark(errors.New("connection refused"), store.ErrUnavailable): codes.Unavailable,
+		errors.New("boom"): codes.Internal,
+	} {
+		assert.Equal(t, code, status.Code(toStatus(err)), err.Error())
+	}
+}